@@ -0,0 +1,38 @@
+package admission
+
+import (
+	"errors"
+)
+
+// errors returned by Admit
+var (
+	// ErrInvalidTicket - ticket is malformed or does not prove admission
+	ErrInvalidTicket = errors.New("invalid admission ticket")
+
+	// ErrExpiredTicket - ticket is not fresh
+	ErrExpiredTicket = errors.New("expired admission ticket")
+
+	// ErrSpentTicket - ticket has already been used
+	ErrSpentTicket = errors.New("admission ticket already used")
+)
+
+// Admission - The main interface for admitting peers into pools.
+// makes it expensive for a single adversary to occupy many slots of a run
+// resource is pool id sent by peer in C_JOIN_REQUEST
+type Admission interface {
+	Admit(resource string, ticket []byte) error
+}
+
+type open struct {
+	Admission
+}
+
+// NewOpen creates a new Admission instance which admits every peer
+func NewOpen() Admission {
+	return &open{}
+}
+
+// Admit - admits peer without ticket
+func (a *open) Admit(resource string, ticket []byte) error {
+	return nil
+}
@@ -0,0 +1,163 @@
+package admission
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dev-appmonsters/dicemix-light-server/clock"
+)
+
+func newTestProofOfWork(now time.Time, difficulty int) Admission {
+	return NewProofOfWork(difficulty, time.Minute, clock.NewFakeClock(now))
+}
+
+type powTestPair struct {
+	name     string
+	resource string
+	elapsed  time.Duration
+	err      error
+}
+
+var powTests = []powTestPair{
+	{"valid", "1000000-p2wpkh", 0, nil},
+	{"other pool", "10000000-p2wpkh", 0, ErrInvalidTicket},
+	{"expired", "1000000-p2wpkh", 2 * time.Minute, ErrExpiredTicket},
+	{"from future", "1000000-p2wpkh", -2 * time.Minute, ErrExpiredTicket},
+}
+
+func TestProofOfWork(t *testing.T) {
+	for _, pair := range powTests {
+		solved := time.Unix(1000000, 0)
+		ticket, err := SolveProofOfWork("1000000-p2wpkh", 12, solved)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		p := newTestProofOfWork(solved.Add(pair.elapsed), 12)
+		if err := p.Admit(pair.resource, ticket); err != pair.err {
+			t.Error("For", pair.name, "expected", pair.err, "got", err)
+		}
+
+		// tickets are accepted only once
+		if pair.err == nil {
+			if err := p.Admit(pair.resource, ticket); err != ErrSpentTicket {
+				t.Error("For", pair.name, "expected", ErrSpentTicket, "got", err)
+			}
+		}
+	}
+}
+
+func TestProofOfWorkDifficulty(t *testing.T) {
+	now := time.Unix(1000000, 0)
+	p := newTestProofOfWork(now, 20)
+
+	// ticket with 8 leading zero bits has 2^-12 chance of 20 bits
+	ticket, _ := SolveProofOfWork("pool", 8, now)
+	for leadingZeros(powHash("pool", ticket)) >= 20 {
+		ticket, _ = SolveProofOfWork("pool", 8, now)
+	}
+
+	for _, invalid := range [][]byte{nil, ticket[:31], ticket} {
+		if err := p.Admit("pool", invalid); err != ErrInvalidTicket {
+			t.Error("For", len(invalid), "expected", ErrInvalidTicket, "got", err)
+		}
+	}
+}
+
+func TestBlindToken(t *testing.T) {
+	issuer, err := NewMemoryIssuer(1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Unix(1000000, 0)
+	fake := clock.NewFakeClock(now)
+	a := NewBlindToken(issuer.PublicKey(), time.Hour, fake).(*blindToken)
+	epoch := TokenEpoch(now, time.Hour)
+
+	token, err := NewBlindedToken(issuer.PublicKey(), epoch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	blindSignature, err := issuer.Sign(token.Blinded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ticket, err := token.Unblind(blindSignature)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := a.Admit("pool", ticket); err != nil {
+		t.Error("For", "valid", "expected", nil, "got", err)
+	}
+	if err := a.Admit("pool", ticket); err != ErrSpentTicket {
+		t.Error("For", "spent", "expected", ErrSpentTicket, "got", err)
+	}
+
+	// issuer never sees serial or its signature
+	if string(blindSignature) == string(ticket[serialSize:]) {
+		t.Error("For", "blinding", "expected", "different signatures", "got", "same")
+	}
+
+	// signature of other issuer or for other serial is refused
+	other, _ := NewMemoryIssuer(1024)
+	forged := append([]byte{}, ticket...)
+	forged[0] ^= 1
+	otherToken, _ := NewBlindedToken(other.PublicKey(), epoch)
+	otherSignature, _ := other.Sign(otherToken.Blinded)
+	otherTicket, _ := otherToken.Unblind(otherSignature)
+
+	for name, invalid := range map[string][]byte{"forged": forged, "other issuer": otherTicket, "short": ticket[:serialSize]} {
+		if err := a.Admit("pool", invalid); err != ErrInvalidTicket {
+			t.Error("For", name, "expected", ErrInvalidTicket, "got", err)
+		}
+	}
+
+	// signature of blinded serial is not signature of serial
+	if _, err := token.Unblind(otherSignature); err == nil {
+		t.Error("For", "wrong signature", "expected", "error", "got", err)
+	}
+
+	// tokens of future epochs are refused
+	if err := a.Admit("pool", blindTicket(t, issuer, epoch+1)); err != ErrExpiredTicket {
+		t.Error("For", "future epoch", "expected", ErrExpiredTicket, "got", err)
+	}
+
+	// tokens are valid in next epoch as well
+	fake.Advance(time.Hour)
+	if err := a.Admit("pool", blindTicket(t, issuer, epoch)); err != nil {
+		t.Error("For", "next epoch", "expected", nil, "got", err)
+	}
+
+	// serials are forgotten once their tokens have expired
+	fake.Advance(time.Hour)
+	if err := a.Admit("pool", ticket); err != ErrExpiredTicket {
+		t.Error("For", "expired", "expected", ErrExpiredTicket, "got", err)
+	}
+	if err := a.Admit("pool", blindTicket(t, issuer, epoch+2)); err != nil || len(a.spent) != 1 {
+		t.Error("For", "prune", "expected", 1, "got", len(a.spent), err)
+	}
+}
+
+// returns ticket of epoch signed by issuer
+func blindTicket(t *testing.T, issuer Issuer, epoch uint64) []byte {
+	token, err := NewBlindedToken(issuer.PublicKey(), epoch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signature, err := issuer.Sign(token.Blinded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ticket, err := token.Unblind(signature)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ticket
+}
+
+func TestOpen(t *testing.T) {
+	if err := NewOpen().Admit("pool", nil); err != nil {
+		t.Error("For", "open", "expected", nil, "got", err)
+	}
+}
@@ -0,0 +1,199 @@
+package admission
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/dev-appmonsters/dicemix-light-server/clock"
+)
+
+// size of serial of a blind token
+// epoch (8 bytes) || random (24 bytes)
+const serialSize = 32
+
+// Issuer - The main interface for services issuing blind tokens.
+// issuer signs blinded serials and can not link tokens to peers
+type Issuer interface {
+	PublicKey() *rsa.PublicKey
+	Sign(blinded []byte) ([]byte, error)
+}
+
+type memoryIssuer struct {
+	key *rsa.PrivateKey
+	Issuer
+}
+
+// NewMemoryIssuer creates a new Issuer instance with a fresh RSA key
+// of bits size, used for tests and single process deployments
+func NewMemoryIssuer(bits int) (Issuer, error) {
+	key, err := rsa.GenerateKey(rand.Reader, bits)
+	if err != nil {
+		return nil, err
+	}
+	return &memoryIssuer{key: key}, nil
+}
+
+// PublicKey - key with which tokens are verified
+func (i *memoryIssuer) PublicKey() *rsa.PublicKey {
+	return &i.key.PublicKey
+}
+
+// Sign - signs blinded serial with raw RSA
+func (i *memoryIssuer) Sign(blinded []byte) ([]byte, error) {
+	m := new(big.Int).SetBytes(blinded)
+	if m.Sign() == 0 || m.Cmp(i.key.N) >= 0 {
+		return nil, errors.New("invalid blinded serial")
+	}
+	return new(big.Int).Exp(m, i.key.D, i.key.N).Bytes(), nil
+}
+
+type blindToken struct {
+	issuer *rsa.PublicKey
+
+	// length of epoch to which tokens are bound
+	epoch time.Duration
+
+	// serials of tokens which have been used and their expiry
+	spent map[string]time.Time
+
+	clock clock.Clock
+	sync.Mutex
+	Admission
+}
+
+// NewBlindToken creates a new Admission instance which requires
+// tokens blindly signed by issuer, every token can be used only once
+// tokens are valid in epoch stamped in their serial and in next one
+// ticket is serial || signature
+func NewBlindToken(issuer *rsa.PublicKey, epoch time.Duration, clock clock.Clock) Admission {
+	return &blindToken{
+		issuer: issuer,
+		epoch:  epoch,
+		spent:  make(map[string]time.Time),
+		clock:  clock,
+	}
+}
+
+// Admit - verifies signature of issuer, epoch and single use of token
+func (b *blindToken) Admit(resource string, ticket []byte) error {
+	if len(ticket) <= serialSize {
+		return ErrInvalidTicket
+	}
+
+	serial, signature := ticket[:serialSize], ticket[serialSize:]
+	if !verifyBlindSignature(b.issuer, serial, signature) {
+		return ErrInvalidTicket
+	}
+
+	now := b.clock.Now()
+	epoch, current := binary.BigEndian.Uint64(serial), TokenEpoch(now, b.epoch)
+	if epoch > current || epoch+1 < current {
+		return ErrExpiredTicket
+	}
+
+	b.Lock()
+	defer b.Unlock()
+
+	// forget tokens which would be refused as expired anyway
+	for serial, expiry := range b.spent {
+		if !now.Before(expiry) {
+			delete(b.spent, serial)
+		}
+	}
+
+	if _, ok := b.spent[string(serial)]; ok {
+		return ErrSpentTicket
+	}
+	b.spent[string(serial)] = time.Unix(int64(epoch+2)*int64(b.epoch/time.Second), 0)
+	return nil
+}
+
+// TokenEpoch - returns epoch of length at time now
+// stamped in serials of blind tokens
+func TokenEpoch(now time.Time, length time.Duration) uint64 {
+	return uint64(now.Unix() / int64(length/time.Second))
+}
+
+// BlindedToken - random serial blinded for issuer
+// Blinded is sent to issuer, its signature is passed to Unblind
+type BlindedToken struct {
+	Serial  []byte
+	Blinded []byte
+
+	issuer *rsa.PublicKey
+	factor *big.Int
+}
+
+// NewBlindedToken - generates random serial of epoch and blinds it for issuer
+func NewBlindedToken(issuer *rsa.PublicKey, epoch uint64) (*BlindedToken, error) {
+	serial := make([]byte, serialSize)
+	binary.BigEndian.PutUint64(serial, epoch)
+	if _, err := rand.Read(serial[8:]); err != nil {
+		return nil, err
+	}
+
+	// factor should be invertible modulo N
+	var factor *big.Int
+	for factor == nil || new(big.Int).GCD(nil, nil, factor, issuer.N).Cmp(big.NewInt(1)) != 0 {
+		var err error
+		if factor, err = rand.Int(rand.Reader, issuer.N); err != nil {
+			return nil, err
+		}
+	}
+
+	// blinded := H(serial) * factor^e mod N
+	e := big.NewInt(int64(issuer.E))
+	blinded := new(big.Int).Exp(factor, e, issuer.N)
+	blinded.Mul(blinded, fullDomainHash(issuer, serial))
+	blinded.Mod(blinded, issuer.N)
+
+	return &BlindedToken{Serial: serial, Blinded: blinded.Bytes(), issuer: issuer, factor: factor}, nil
+}
+
+// Unblind - removes blinding factor from signature of issuer
+// returns ticket to be sent in C_JOIN_REQUEST
+func (t *BlindedToken) Unblind(blindSignature []byte) ([]byte, error) {
+	signature := new(big.Int).SetBytes(blindSignature)
+	signature.Mul(signature, new(big.Int).ModInverse(t.factor, t.issuer.N))
+	signature.Mod(signature, t.issuer.N)
+
+	if !verifyBlindSignature(t.issuer, t.Serial, signature.Bytes()) {
+		return nil, errors.New("invalid signature of issuer")
+	}
+	return append(append([]byte{}, t.Serial...), signature.Bytes()...), nil
+}
+
+// checks signature^e == H(serial) mod N
+func verifyBlindSignature(issuer *rsa.PublicKey, serial, signature []byte) bool {
+	s := new(big.Int).SetBytes(signature)
+	if s.Sign() == 0 || s.Cmp(issuer.N) >= 0 {
+		return false
+	}
+
+	s.Exp(s, big.NewInt(int64(issuer.E)), issuer.N)
+	return s.Cmp(fullDomainHash(issuer, serial)) == 0
+}
+
+// hashes serial to an integer modulo N
+// by concatenating SHA256(counter || serial) till size of N
+func fullDomainHash(issuer *rsa.PublicKey, serial []byte) *big.Int {
+	size := (issuer.N.BitLen() + 7) / 8
+	digest := make([]byte, 0, size+sha256.Size)
+
+	counter := make([]byte, 4)
+	for i := uint32(0); len(digest) < size; i++ {
+		binary.BigEndian.PutUint32(counter, i)
+		h := sha256.New()
+		h.Write(counter)
+		h.Write(serial)
+		digest = h.Sum(digest)
+	}
+
+	return new(big.Int).Mod(new(big.Int).SetBytes(digest[:size]), issuer.N)
+}
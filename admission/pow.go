@@ -0,0 +1,110 @@
+package admission
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"math/bits"
+	"sync"
+	"time"
+
+	"github.com/dev-appmonsters/dicemix-light-server/clock"
+)
+
+// size of proof of work ticket
+// timestamp (8 bytes) || random (16 bytes) || counter (8 bytes)
+const powTicketSize = 32
+
+type proofOfWork struct {
+	// required number of leading zero bits of hash
+	difficulty int
+
+	// tickets older or newer than window are refused
+	window time.Duration
+
+	// hashes of tickets seen within window and their expiry
+	spent map[[sha256.Size]byte]time.Time
+
+	clock clock.Clock
+	sync.Mutex
+	Admission
+}
+
+// NewProofOfWork creates a new Admission instance which requires
+// hashcash like tickets with difficulty leading zero bits
+// tickets are valid for window around their timestamp and only once
+func NewProofOfWork(difficulty int, window time.Duration, clock clock.Clock) Admission {
+	return &proofOfWork{
+		difficulty: difficulty,
+		window:     window,
+		spent:      make(map[[sha256.Size]byte]time.Time),
+		clock:      clock,
+	}
+}
+
+// Admit - verifies work, freshness and single use of ticket
+func (p *proofOfWork) Admit(resource string, ticket []byte) error {
+	if len(ticket) != powTicketSize || leadingZeros(powHash(resource, ticket)) < p.difficulty {
+		return ErrInvalidTicket
+	}
+
+	now := p.clock.Now()
+	timestamp := time.Unix(int64(binary.BigEndian.Uint64(ticket)), 0)
+	if timestamp.Before(now.Add(-p.window)) || timestamp.After(now.Add(p.window)) {
+		return ErrExpiredTicket
+	}
+
+	p.Lock()
+	defer p.Unlock()
+
+	// forget tickets which would be refused as expired anyway
+	for hash, expiry := range p.spent {
+		if now.After(expiry) {
+			delete(p.spent, hash)
+		}
+	}
+
+	hash := sha256.Sum256(ticket)
+	if _, ok := p.spent[hash]; ok {
+		return ErrSpentTicket
+	}
+	p.spent[hash] = timestamp.Add(p.window)
+	return nil
+}
+
+// SolveProofOfWork - returns ticket for resource with difficulty
+// leading zero bits, expected work is 2^difficulty hashes
+func SolveProofOfWork(resource string, difficulty int, now time.Time) ([]byte, error) {
+	ticket := make([]byte, powTicketSize)
+	binary.BigEndian.PutUint64(ticket, uint64(now.Unix()))
+	if _, err := rand.Read(ticket[8:24]); err != nil {
+		return nil, err
+	}
+
+	for counter := uint64(0); ; counter++ {
+		binary.BigEndian.PutUint64(ticket[24:], counter)
+		if leadingZeros(powHash(resource, ticket)) >= difficulty {
+			return ticket, nil
+		}
+	}
+}
+
+// hash of ticket bound to resource
+func powHash(resource string, ticket []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte(resource))
+	h.Write([]byte{0})
+	h.Write(ticket)
+	return h.Sum(nil)
+}
+
+// number of leading zero bits of hash
+func leadingZeros(hash []byte) (count int) {
+	for _, b := range hash {
+		if b != 0 {
+			return count + bits.LeadingZeros8(b)
+		}
+		count += 8
+	}
+	return
+}
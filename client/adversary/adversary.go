@@ -0,0 +1,99 @@
+// Package adversary contains strategies of misbehaving peers
+// used to exercise BLAME stage of server
+package adversary
+
+import (
+	"crypto/rand"
+	"reflect"
+
+	"github.com/dev-appmonsters/dicemix-light-server/client"
+	"github.com/dev-appmonsters/dicemix-light-server/messages"
+
+	"github.com/golang/protobuf/proto"
+)
+
+// RefuseConfirmation - refuses to confirm transaction
+// even though our messages are included
+func RefuseConfirmation() client.Strategy {
+	return func(request proto.Message) proto.Message {
+		if r, ok := request.(*messages.ConfirmationRequest); ok {
+			r.Confirmation = false
+		}
+		return request
+	}
+}
+
+// BadKeypair - reveals a KESK which does not correspond
+// to announced KEPK after refusing confirmation
+func BadKeypair() client.Strategy {
+	return Combine(RefuseConfirmation(), func(request proto.Message) proto.Message {
+		if r, ok := request.(*messages.InitiaiteKESKResponse); ok {
+			r.PrivateKey = make([]byte, 32)
+			rand.Read(r.PrivateKey)
+		}
+		return request
+	})
+}
+
+// MissingKESK - does not reveal KESK after refusing confirmation
+func MissingKESK() client.Strategy {
+	return Combine(RefuseConfirmation(), Offline(&messages.InitiaiteKESKResponse{}))
+}
+
+// WrongMessageCount - announces one more message than sent
+// in DC-SIMPLE round and refuses confirmation
+func WrongMessageCount() client.Strategy {
+	return Combine(RefuseConfirmation(), func(request proto.Message) proto.Message {
+		if r, ok := request.(*messages.KeyExchangeRequest); ok {
+			r.NumMsgs++
+		}
+		return request
+	})
+}
+
+// DCExpMismatch - sends a DC-EXP vector which does not correspond
+// to messages sent in DC-SIMPLE round, which corrupts roots of all peers
+func DCExpMismatch() client.Strategy {
+	return func(request proto.Message) proto.Message {
+		if r, ok := request.(*messages.DCExpRequest); ok && len(r.DCExpVector) != 0 {
+			r.DCExpVector[0]++
+		}
+		return request
+	}
+}
+
+// FalseOK - reports OK = false in DC-SIMPLE round even though
+// our message hashes are in roots and refuses confirmation
+func FalseOK() client.Strategy {
+	return Combine(RefuseConfirmation(), func(request proto.Message) proto.Message {
+		if r, ok := request.(*messages.DCSimpleRequest); ok {
+			r.MyOk = false
+		}
+		return request
+	})
+}
+
+// Offline - drops requests of same type as request
+// i.e. peer appears offline in corresponding round
+func Offline(request proto.Message) client.Strategy {
+	offline := reflect.TypeOf(request)
+	return func(request proto.Message) proto.Message {
+		if reflect.TypeOf(request) == offline {
+			return nil
+		}
+		return request
+	}
+}
+
+// Combine - applies strategies in order
+// request is dropped if any of strategies drops it
+func Combine(strategies ...client.Strategy) client.Strategy {
+	return func(request proto.Message) proto.Message {
+		for _, strategy := range strategies {
+			if request = strategy(request); request == nil {
+				return nil
+			}
+		}
+		return request
+	}
+}
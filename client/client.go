@@ -0,0 +1,115 @@
+package client
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"time"
+
+	"github.com/dev-appmonsters/dicemix-light-server/messages"
+
+	"github.com/golang/protobuf/proto"
+)
+
+// errors returned by Join
+var (
+	// ErrFeesRefused - fee terms announced by server are not acceptable
+	ErrFeesRefused = errors.New("fee terms refused")
+
+	// ErrInvalidMessage - anonymous messages should be of utils.MessageSize bytes
+	ErrInvalidMessage = errors.New("invalid message size")
+
+	// ErrTooManyMessages - our messages do not fit in slots of run
+	ErrTooManyMessages = errors.New("more messages than slots of run")
+
+	// ErrServer - server has responded with an error
+	ErrServer = errors.New("server error")
+
+	// ErrMalformedResponse - server sent response without header
+	ErrMalformedResponse = errors.New("malformed response")
+
+	// ErrConnectionClosed - server closed connection before run completed
+	ErrConnectionClosed = errors.New("connection closed")
+
+	// ErrUnsupportedVersion - server selected unknown protocol version
+	ErrUnsupportedVersion = errors.New("unsupported protocol version")
+)
+
+// ServerError - error response sent by server
+// matches ErrServer with errors.Is
+type ServerError struct {
+	// Code - one of error codes in messages/codes.go
+	Code    uint32
+	Message string
+}
+
+func (e *ServerError) Error() string {
+	return ErrServer.Error() + ": " + e.Message
+}
+
+// Is - reports if target is ErrServer
+func (e *ServerError) Is(target error) bool {
+	return target == ErrServer
+}
+
+// interval between attempts to connect again while resuming a run
+const resumeInterval = time.Second
+
+// Client - The main interface for DiceMix-Light peer.
+type Client interface {
+	Join(ctx context.Context, url string, msgs [][]byte) ([]byte, error)
+}
+
+// Config - contains parameters of a peer
+type Config struct {
+	// PoolID - pool to join, default pool of server if empty
+	PoolID string
+
+	// AuthToken - sent while joining to servers which restrict access
+	AuthToken string
+
+	// Ticket - returns admission ticket for pool, e.g. proof of work
+	// or blind token, required by servers which restrict admission
+	// proof of work is bound to pool peer waits in, i.e. PoolID should
+	// be set to default pool of server instead of being left empty
+	Ticket func(poolID string) ([]byte, error)
+
+	// Compression - negotiate per-message deflate with server
+	Compression bool
+
+	// TorProxy - SOCKS5 address of tor e.g. 127.0.0.1:9050
+	// every Join uses a separate tor circuit, connects directly if empty
+	TorProxy string
+
+	// TLS - used while connecting to wss:// urls, e.g. to present
+	// client certificate, default configuration is used if nil
+	TLS *tls.Config
+
+	// Inputs, ChangeScript and ChangeAmount - funds contributed to transaction
+	Inputs       []*messages.TxInput
+	ChangeScript []byte
+	ChangeAmount uint64
+
+	// AcceptFees - decides if fee terms announced at start of a run
+	// are acceptable, all terms are accepted if nil
+	AcceptFees func(*messages.FeeTerms) bool
+
+	// ResumeWait - time for which connection is retried if it is lost
+	// during a run, e.g. while server restarts, run is not resumed if zero
+	ResumeWait time.Duration
+
+	// Strategy - alters requests before they are sent,
+	// requests are sent as generated if nil
+	Strategy Strategy
+}
+
+// Strategy - alters a request before it is signed and sent to server
+// used to simulate misbehaving peers, request is dropped if nil is returned
+type Strategy func(request proto.Message) proto.Message
+
+// Join - connects to coordinator at url and participates in DiceMix-Light
+// runs till msgs are anonymously included in a successful run
+// returns transaction (PSBT) assembled by coordinator
+func Join(ctx context.Context, url string, msgs [][]byte) ([]byte, error) {
+	return NewClient(&Config{}).Join(ctx, url, msgs)
+}
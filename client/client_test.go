@@ -0,0 +1,207 @@
+package client
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/dev-appmonsters/dicemix-light-server/field"
+	"github.com/dev-appmonsters/dicemix-light-server/messages"
+	"github.com/dev-appmonsters/dicemix-light-server/utils"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/gorilla/websocket"
+)
+
+type simulatedPeer struct {
+	id         int32
+	kesk, kepk []byte
+	msgs       [][]byte
+}
+
+func newSimulatedPeers(t *testing.T, msgs ...[][]byte) ([]*simulatedPeer, []*messages.PeersInfo) {
+	peers := make([]*simulatedPeer, 0)
+	infos := make([]*messages.PeersInfo, 0)
+	for i, m := range msgs {
+		kesk, kepk, err := generateKeypair()
+		if err != nil {
+			t.Fatal(err)
+		}
+		peer := &simulatedPeer{id: int32(10 + i), kesk: kesk, kepk: kepk, msgs: m}
+		peers = append(peers, peer)
+		infos = append(infos, &messages.PeersInfo{Id: peer.id, PublicKey: kepk, NumMsgs: uint32(len(m))})
+	}
+	return peers, infos
+}
+
+func message(b byte) []byte {
+	return bytes.Repeat([]byte{b}, utils.MessageSize)
+}
+
+func TestDCExpVector(t *testing.T) {
+	peers, infos := newSimulatedPeers(t, [][]byte{message(1)}, [][]byte{message(2), message(3)}, [][]byte{message(4)})
+	total := 4
+
+	// randomness cancels out in combined vector
+	combined := make([]uint64, total)
+	for _, peer := range peers {
+		others, err := derivePeers(peer.id, peer.kesk, infos)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		dc := dcExpVector(peer.id, others, peer.msgs, total)
+		for i := range combined {
+			combined[i] = field.NewField(combined[i]).Add(field.NewField(dc[i])).Value()
+		}
+	}
+
+	// combined vector is power sums of all message hashes
+	expected := make([]uint64, total)
+	for b := byte(1); b <= 4; b++ {
+		var pow uint64 = 1
+		for i := range expected {
+			pow = utils.Power(messageHash(message(b)), pow)
+			expected[i] = field.NewField(expected[i]).Add(field.NewField(pow)).Value()
+		}
+	}
+
+	if !utils.CheckEqualUint64(combined, expected) {
+		t.Error("expected", expected, "got", combined)
+	}
+}
+
+func TestDCSimpleVector(t *testing.T) {
+	peers, infos := newSimulatedPeers(t, [][]byte{message(1)}, [][]byte{message(2), message(3)}, [][]byte{message(4)})
+	total := 4
+
+	// roots as solved by server i.e. sorted reduced hashes
+	roots := make([]uint64, 0)
+	for b := byte(1); b <= 4; b++ {
+		roots = append(roots, utils.Reduce(messageHash(message(b))))
+	}
+	sort.Slice(roots, func(i, j int) bool { return roots[i] < roots[j] })
+
+	combined := make([][]byte, total)
+	for i := range combined {
+		combined[i] = make([]byte, utils.MessageSize)
+	}
+
+	for _, peer := range peers {
+		reserved, ok := slots(peer.msgs, roots)
+		if !ok {
+			t.Fatal("expected slots for peer", peer.id)
+		}
+
+		others, err := derivePeers(peer.id, peer.kesk, infos)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		dc := dcSimpleVector(others, peer.msgs, reserved, total)
+		for i := range combined {
+			utils.XorBytes(combined[i], combined[i], dc[i])
+		}
+	}
+
+	// every message is recovered in slot of its hash
+	for b := byte(1); b <= 4; b++ {
+		reserved, _ := slots([][]byte{message(b)}, roots)
+		if !bytes.Equal(combined[reserved[0]], message(b)) {
+			t.Error("expected", message(b), "got", combined[reserved[0]])
+		}
+	}
+
+	if _, ok := slots([][]byte{message(5)}, roots); ok {
+		t.Error("expected no slot for missing message")
+	}
+}
+
+func TestServerError(t *testing.T) {
+	var err error = &ServerError{Code: messages.E_BANNED, Message: "Banned"}
+
+	if !errors.Is(err, ErrServer) {
+		t.Error("expected ServerError to match ErrServer")
+	}
+
+	var serverErr *ServerError
+	if !errors.As(err, &serverErr) || serverErr.Code != messages.E_BANNED {
+		t.Error("expected error code", messages.E_BANNED, "got", serverErr)
+	}
+}
+
+type confirmationTestPair struct {
+	name         string
+	messages     [][]byte
+	transaction  []byte
+	confirmation bool
+}
+
+var confirmationTests = []confirmationTestPair{
+	{"included", [][]byte{message(1), message(2)}, []byte{1}, true},
+	{"missing message", [][]byte{message(2)}, []byte{1}, false},
+	{"missing transaction", [][]byte{message(1), message(2)}, nil, false},
+}
+
+func TestConfirmation(t *testing.T) {
+	for _, pair := range confirmationTests {
+		var sent proto.Message
+		s := &session{config: &Config{Strategy: func(request proto.Message) proto.Message {
+			sent = request
+			return nil
+		}}, msgs: [][]byte{message(1)}}
+
+		err := s.handleDCSimpleResponse(&messages.DCSimpleResponse{Messages: pair.messages, Transaction: pair.transaction})
+		request, ok := sent.(*messages.ConfirmationRequest)
+		if err != nil || !ok || request.Confirmation != pair.confirmation {
+			t.Error("For", pair.name, "expected", pair.confirmation, "got", sent, err)
+		}
+	}
+}
+
+func TestMalformedResponse(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		// response without header
+		conn.WriteMessage(websocket.BinaryMessage, []byte{})
+		conn.ReadMessage()
+	}))
+	defer s.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(s.URL, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	session := &session{conn: conn, config: &Config{}}
+	if _, err := session.run(); err != ErrMalformedResponse {
+		t.Error("For", "response without header", "expected", ErrMalformedResponse, "got", err)
+	}
+}
+
+func TestIsolatedProxy(t *testing.T) {
+	first, err := isolatedProxy("127.0.0.1:9050")
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, _ := isolatedProxy("127.0.0.1:9050")
+
+	if first.Scheme != "socks5" || first.Host != "127.0.0.1:9050" {
+		t.Error("expected socks5://127.0.0.1:9050 got", first)
+	}
+
+	// sessions use separate circuits
+	if first.User.String() == second.User.String() {
+		t.Error("expected different credentials got", first.User, second.User)
+	}
+}
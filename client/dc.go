@@ -0,0 +1,113 @@
+package client
+
+import (
+	"github.com/dev-appmonsters/dicemix-light-server/field"
+	"github.com/dev-appmonsters/dicemix-light-server/messages"
+	"github.com/dev-appmonsters/dicemix-light-server/nike"
+	"github.com/dev-appmonsters/dicemix-light-server/rng"
+	"github.com/dev-appmonsters/dicemix-light-server/utils"
+)
+
+// Peer - other participant of a run along with
+// randomness derived from key shared with him
+type peerInfo struct {
+	ID      int32
+	Dicemix rng.DiceMixRng
+}
+
+// derives randomness shared with every other peer of run
+// from our KESK and their KEPK
+func derivePeers(myID int32, privateKey []byte, peers []*messages.PeersInfo) ([]*peerInfo, error) {
+	nike := nike.NewNike()
+	others := make([]*peerInfo, 0)
+
+	for _, peer := range peers {
+		if peer.Id == myID {
+			continue
+		}
+
+		_, dicemix, err := nike.DeriveSharedKeys(privateKey, peer.PublicKey)
+		if err != nil {
+			return nil, err
+		}
+		others = append(others, &peerInfo{ID: peer.Id, Dicemix: dicemix})
+	}
+	return others, nil
+}
+
+// returns hash of message as used in DC-EXP
+func messageHash(message []byte) uint64 {
+	return utils.ShortHash(utils.BytesToBase58String(message))
+}
+
+// generates DC-EXP vector
+// my_dc[i] := sum(my_msg_hashes[j] ** (i + 1)) (+) sum(sgn(my_id - p.id) (*) p.dicemix.get_field_element())
+func dcExpVector(myID int32, peers []*peerInfo, msgs [][]byte, totalMsgsCount int) []uint64 {
+	dc := make([]uint64, totalMsgsCount)
+
+	// generates power sums of message_hashes
+	for _, message := range msgs {
+		hash := messageHash(message)
+		var pow uint64 = 1
+		for i := 0; i < totalMsgsCount; i++ {
+			pow = utils.Power(hash, pow)
+			dc[i] = field.NewField(dc[i]).Add(field.NewField(pow)).Value()
+		}
+	}
+
+	// encode power sums with randomness shared with peers
+	// which cancels out once vectors of all peers are combined
+	for _, peer := range peers {
+		for i := 0; i < totalMsgsCount; i++ {
+			var op2 = field.NewField(peer.Dicemix.GetFieldElement())
+			if myID < peer.ID {
+				op2 = op2.Neg()
+			}
+			dc[i] = field.NewField(dc[i]).Add(op2).Value()
+		}
+	}
+	return dc
+}
+
+// returns slots reserved for our messages i.e. position
+// of their hashes in roots of DC-EXP
+// ok - false if hash of any of our message is missing
+func slots(msgs [][]byte, roots []uint64) ([]int, bool) {
+	reserved := make([]int, len(msgs))
+	for j, message := range msgs {
+		hash := utils.Reduce(messageHash(message))
+		reserved[j] = -1
+		for i, root := range roots {
+			if root == hash {
+				reserved[j] = i
+				break
+			}
+		}
+
+		if reserved[j] < 0 {
+			return nil, false
+		}
+	}
+	return reserved, true
+}
+
+// generates DC-SIMPLE vector
+// my_dc[slot] := my_msg (xor) <randomness shared with every peer>
+// slots not reserved by us only contain randomness
+func dcSimpleVector(peers []*peerInfo, msgs [][]byte, reserved []int, totalMsgsCount int) [][]byte {
+	dc := make([][]byte, totalMsgsCount)
+	for i := range dc {
+		dc[i] = make([]byte, utils.MessageSize)
+	}
+
+	for j, slot := range reserved {
+		copy(dc[slot], msgs[j])
+	}
+
+	for _, peer := range peers {
+		for i := 0; i < totalMsgsCount; i++ {
+			utils.XorBytes(dc[i], dc[i], peer.Dicemix.GetBytes(utils.MessageSize))
+		}
+	}
+	return dc
+}
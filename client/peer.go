@@ -0,0 +1,428 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/dev-appmonsters/dicemix-light-server/messages"
+	"github.com/dev-appmonsters/dicemix-light-server/utils"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/golang/protobuf/proto"
+	"github.com/gorilla/websocket"
+	"golang.org/x/crypto/curve25519"
+)
+
+type peer struct {
+	config *Config
+	Client
+}
+
+// state of peer during a DiceMix execution
+type session struct {
+	conn      *websocket.Conn
+	config    *Config
+	msgs      [][]byte
+	id        int32
+	sessionID uint64
+
+	// long term key used to sign requests
+	ltsk *btcec.PrivateKey
+
+	// key exchange keys of current run and next run
+	kesk, kepk         []byte
+	nextKESK, nextKEPK []byte
+
+	peers       []*messages.PeersInfo
+	others      []*peerInfo
+	totalMsgs   int
+	transaction []byte
+
+	// connection to server has been lost
+	lost bool
+}
+
+// NewClient creates a new Client instance
+func NewClient(config *Config) Client {
+	if config == nil {
+		config = &Config{}
+	}
+	return &peer{config: config}
+}
+
+// Join - participates in DiceMix-Light runs till msgs are
+// included in a successful run
+func (p *peer) Join(ctx context.Context, url string, msgs [][]byte) ([]byte, error) {
+	for _, message := range msgs {
+		if len(message) != utils.MessageSize {
+			return nil, ErrInvalidMessage
+		}
+	}
+
+	ltsk, err := btcec.NewPrivateKey(btcec.S256())
+	if err != nil {
+		return nil, err
+	}
+
+	s := &session{config: p.config, msgs: msgs, ltsk: ltsk}
+	transaction, err := p.connect(ctx, url, s)
+
+	// server may have restarted during run
+	// run is resumed if we can connect again within ResumeWait
+	deadline := time.Now().Add(p.config.ResumeWait)
+	for err != nil && s.lost && s.sessionID != 0 && ctx.Err() == nil && time.Now().Before(deadline) {
+		select {
+		case <-ctx.Done():
+		case <-time.After(resumeInterval):
+			transaction, err = p.connect(ctx, url, s)
+		}
+	}
+
+	if err != nil && ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	return transaction, err
+}
+
+// dials server and participates in run over new connection
+// till run is over or connection is lost
+func (p *peer) connect(ctx context.Context, url string, s *session) ([]byte, error) {
+	s.lost = true
+
+	dialer := *websocket.DefaultDialer
+	dialer.TLSClientConfig = p.config.TLS
+	dialer.EnableCompression = p.config.Compression
+	if p.config.TorProxy != "" {
+		proxy, err := isolatedProxy(p.config.TorProxy)
+		if err != nil {
+			return nil, err
+		}
+		dialer.Proxy = http.ProxyURL(proxy)
+	}
+
+	conn, _, err := dialer.DialContext(ctx, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	// unblock pending read once context is done
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-done:
+		}
+	}()
+
+	s.conn = conn
+	if err := s.join(); err != nil {
+		return nil, err
+	}
+	s.lost = false
+	return s.run()
+}
+
+// returns SOCKS5 url of tor with random credentials
+// tor isolates streams with different credentials in separate circuits
+// so that sessions of peer can not be linked by exit or guard
+func isolatedProxy(address string) (*url.URL, error) {
+	credentials := make([]byte, 32)
+	if _, err := rand.Read(credentials); err != nil {
+		return nil, err
+	}
+
+	return &url.URL{
+		Scheme: "socks5",
+		Host:   address,
+		User:   url.UserPassword(hex.EncodeToString(credentials[:16]), hex.EncodeToString(credentials[16:])),
+	}, nil
+}
+
+// reads responses from server and sends request expected by server
+// till run is successful
+func (s *session) run() ([]byte, error) {
+	for {
+		_, data, err := s.conn.ReadMessage()
+		if err != nil {
+			s.lost = true
+			if websocket.IsCloseError(err, websocket.CloseNormalClosure) {
+				return nil, ErrConnectionClosed
+			}
+			return nil, err
+		}
+
+		response := &messages.GenericResponse{}
+		if err := proto.Unmarshal(data, response); err != nil {
+			return nil, err
+		}
+
+		if response.Header == nil {
+			return nil, ErrMalformedResponse
+		}
+		if response.Header.Err != "" {
+			return nil, &ServerError{Code: response.Header.ErrorCode, Message: response.Header.Err}
+		}
+
+		done, err := s.handleResponse(response.Header, data)
+		if err != nil || done {
+			return s.transaction, err
+		}
+	}
+}
+
+// handles response of server
+// returns true when DiceMix execution is successful
+func (s *session) handleResponse(header *messages.ResponseHeader, data []byte) (bool, error) {
+	switch header.Code {
+	case messages.S_JOIN_RESPONSE:
+		response := &messages.RegisterResponse{}
+		if err := proto.Unmarshal(data, response); err != nil {
+			return false, err
+		}
+		return false, s.handleJoinResponse(response)
+
+	case messages.S_START_DICEMIX:
+		response := &messages.DiceMixResponse{}
+		if err := proto.Unmarshal(data, response); err != nil {
+			return false, err
+		}
+		return false, s.handleStartDicemix(response)
+
+	case messages.S_KEY_EXCHANGE:
+		response := &messages.DiceMixResponse{}
+		if err := proto.Unmarshal(data, response); err != nil {
+			return false, err
+		}
+		return false, s.handleKeyExchangeResponse(response)
+
+	case messages.S_EXP_DC_VECTOR:
+		response := &messages.DCExpResponse{}
+		if err := proto.Unmarshal(data, response); err != nil {
+			return false, err
+		}
+		return false, s.handleDCExpResponse(response)
+
+	case messages.S_SIMPLE_DC_VECTOR:
+		response := &messages.DCSimpleResponse{}
+		if err := proto.Unmarshal(data, response); err != nil {
+			return false, err
+		}
+		return false, s.handleDCSimpleResponse(response)
+
+	case messages.S_KESK_REQUEST:
+		return false, s.send(&messages.InitiaiteKESKResponse{
+			Header:     s.header(messages.C_KESK_RESPONSE),
+			PrivateKey: s.kesk,
+		})
+
+	case messages.S_TX_SUCCESSFUL:
+		return true, nil
+	}
+
+	return false, nil
+}
+
+// joins pool announcing supported protocol versions
+func (s *session) join() error {
+	// peers of a run resume it with header of run
+	// and have already been admitted
+	var ticket []byte
+	if s.config.Ticket != nil && s.sessionID == 0 {
+		var err error
+		if ticket, err = s.config.Ticket(s.config.PoolID); err != nil {
+			return err
+		}
+	}
+
+	features, _ := messages.VersionFeatures(messages.SupportedVersions[0])
+	return s.send(&messages.JoinRequest{
+		Header:    s.header(messages.C_JOIN_REQUEST),
+		PoolId:    s.config.PoolID,
+		Versions:  messages.SupportedVersions,
+		Features:  features,
+		AuthToken: s.config.AuthToken,
+		Ticket:    ticket,
+	})
+}
+
+// obtains our id assigned on joining
+// sends long term public key once in desired pool
+// server sends last response again once run is resumed
+func (s *session) handleJoinResponse(response *messages.RegisterResponse) error {
+	if s.sessionID != 0 {
+		return nil
+	}
+	s.id = response.Id
+
+	if s.config.PoolID != "" && response.PoolId != s.config.PoolID {
+		return errors.New("unable to join pool " + s.config.PoolID)
+	}
+
+	// servers unaware of negotiation speak legacy version
+	version := response.Version
+	if version == 0 {
+		version = messages.VERSION_1
+	}
+	if _, ok := messages.VersionFeatures(version); !ok {
+		return ErrUnsupportedVersion
+	}
+
+	return s.send(&messages.LtpkExchangeRequest{
+		Header:    s.header(messages.C_LTPK_REQUEST),
+		PublicKey: s.ltsk.PubKey().SerializeCompressed(),
+	})
+}
+
+// checks fee terms and sends our key exchange public key
+func (s *session) handleStartDicemix(response *messages.DiceMixResponse) error {
+	s.sessionID = response.Header.SessionId
+
+	if s.config.AcceptFees != nil && !s.config.AcceptFees(response.FeeTerms) {
+		return ErrFeesRefused
+	}
+
+	var err error
+	if s.kesk, s.kepk, err = generateKeypair(); err != nil {
+		return err
+	}
+
+	return s.send(&messages.KeyExchangeRequest{
+		Header:       s.header(messages.C_KEY_EXCHANGE),
+		PublicKey:    s.kepk,
+		NumMsgs:      uint32(len(s.msgs)),
+		Inputs:       s.config.Inputs,
+		ChangeScript: s.config.ChangeScript,
+		ChangeAmount: s.config.ChangeAmount,
+	})
+}
+
+// starts a new run with active peers
+// derives shared randomness and sends DC-EXP vector
+func (s *session) handleKeyExchangeResponse(response *messages.DiceMixResponse) error {
+	s.peers = response.Peers
+
+	// after BLAME server rotates keys
+	// (kepk) := (my_next_kepk)
+	for _, peer := range s.peers {
+		if peer.Id == s.id && s.nextKEPK != nil && bytes.Equal(peer.PublicKey, s.nextKEPK) {
+			s.kesk, s.kepk = s.nextKESK, s.nextKEPK
+			s.nextKESK, s.nextKEPK = nil, nil
+		}
+	}
+
+	s.totalMsgs = 0
+	for _, peer := range s.peers {
+		s.totalMsgs += int(peer.NumMsgs)
+	}
+
+	var err error
+	if s.others, err = derivePeers(s.id, s.kesk, s.peers); err != nil {
+		return err
+	}
+
+	return s.send(&messages.DCExpRequest{
+		Header:      s.header(messages.C_EXP_DC_VECTOR),
+		DCExpVector: dcExpVector(s.id, s.others, s.msgs, s.totalMsgs),
+	})
+}
+
+// reserves slots for our messages and sends DC-SIMPLE vector
+// along with key exchange public key for next run
+func (s *session) handleDCExpResponse(response *messages.DCExpResponse) error {
+	reserved, ok := slots(s.msgs, response.Roots)
+
+	var err error
+	if s.nextKESK, s.nextKEPK, err = generateKeypair(); err != nil {
+		return err
+	}
+
+	// if our messages are missing in roots report not OK
+	// messages are still sent in leading slots so that
+	// BLAME can verify them against our DC-EXP vector
+	if !ok {
+		if len(s.msgs) > s.totalMsgs {
+			return ErrTooManyMessages
+		}
+		reserved = make([]int, len(s.msgs))
+		for j := range reserved {
+			reserved[j] = j
+		}
+	}
+
+	return s.send(&messages.DCSimpleRequest{
+		Header:         s.header(messages.C_SIMPLE_DC_VECTOR),
+		DCSimpleVector: dcSimpleVector(s.others, s.msgs, reserved, s.totalMsgs),
+		MyOk:           ok,
+		NextPublicKey:  s.nextKEPK,
+	})
+}
+
+// confirms if all of our messages are included
+// in transaction sent along with them
+func (s *session) handleDCSimpleResponse(response *messages.DCSimpleResponse) error {
+	s.transaction = response.Transaction
+
+	return s.send(&messages.ConfirmationRequest{
+		Header:       s.header(messages.C_TX_CONFIRMATION),
+		Confirmation: len(response.Transaction) > 0 && utils.ContainBytes(s.msgs, response.Messages),
+	})
+}
+
+func (s *session) header(code uint32) *messages.RequestHeader {
+	return &messages.RequestHeader{
+		Code:      code,
+		SessionId: s.sessionID,
+		Id:        s.id,
+		Timestamp: utils.Timestamp(),
+	}
+}
+
+// signs request with long term key and sends it to server
+func (s *session) send(request proto.Message) error {
+	if s.config.Strategy != nil {
+		if request = s.config.Strategy(request); request == nil {
+			return nil
+		}
+	}
+
+	data, err := proto.Marshal(request)
+	if err != nil {
+		return err
+	}
+
+	signature, err := s.ltsk.Sign(chainhash.DoubleHashB(data))
+	if err != nil {
+		return err
+	}
+
+	signed, err := proto.Marshal(&messages.SignedRequest{
+		RequestData: data,
+		Signature:   signature.Serialize(),
+	})
+	if err != nil {
+		return err
+	}
+
+	return s.conn.WriteMessage(websocket.BinaryMessage, signed)
+}
+
+// generates curve25519 key exchange keypair
+func generateKeypair() ([]byte, []byte, error) {
+	var privateKey, publicKey [32]byte
+	if _, err := rand.Read(privateKey[:]); err != nil {
+		return nil, nil, err
+	}
+
+	curve25519.ScalarBaseMult(&publicKey, &privateKey)
+	return privateKey[:], publicKey[:], nil
+}
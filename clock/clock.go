@@ -0,0 +1,33 @@
+package clock
+
+import (
+	"time"
+)
+
+// Clock - The main interface for time used by protocol timeouts.
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+	Sleep(d time.Duration)
+}
+
+type realClock struct {
+	Clock
+}
+
+// NewClock creates a new Clock instance backed by system time
+func NewClock() Clock {
+	return &realClock{}
+}
+
+func (c *realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (c *realClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+func (c *realClock) Sleep(d time.Duration) {
+	time.Sleep(d)
+}
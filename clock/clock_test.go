@@ -0,0 +1,64 @@
+package clock
+
+import (
+	"testing"
+	"time"
+)
+
+type fakeTestPair struct {
+	after   time.Duration
+	advance time.Duration
+	fired   bool
+}
+
+var fakeTests = []fakeTestPair{
+	{time.Second, 0, false},
+	{time.Second, time.Second - 1, false},
+	{time.Second, time.Second, true},
+	{time.Second, time.Minute, true},
+	{0, 0, true},
+}
+
+func fired(ch <-chan time.Time) bool {
+	select {
+	case <-ch:
+		return true
+	default:
+		return false
+	}
+}
+
+func TestFakeAfter(t *testing.T) {
+	for _, pair := range fakeTests {
+		c := NewFakeClock(time.Unix(1000000, 0))
+		ch := c.After(pair.after)
+		c.Advance(pair.advance)
+
+		if res := fired(ch); res != pair.fired {
+			t.Error(
+				"For", pair.after, pair.advance,
+				"expected", pair.fired,
+				"got", res,
+			)
+		}
+	}
+}
+
+func TestFakeSleep(t *testing.T) {
+	start := time.Unix(1000000, 0)
+	c := NewFakeClock(start)
+	short, long := c.After(time.Second), c.After(time.Minute)
+
+	if next, ok := c.Next(); !ok || !next.Equal(start.Add(time.Second)) {
+		t.Error("expected next timer at", start.Add(time.Second), "got", next, ok)
+	}
+
+	c.Sleep(2 * time.Second)
+
+	if !c.Now().Equal(start.Add(2 * time.Second)) {
+		t.Error("expected sleep to advance clock got", c.Now())
+	}
+	if !fired(short) || fired(long) || c.Waiters() != 1 {
+		t.Error("expected only expired timer to fire")
+	}
+}
@@ -0,0 +1,106 @@
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+// Fake - Clock which only moves when advanced manually
+// used to test timeouts deterministically
+type Fake interface {
+	Clock
+
+	// Advance - moves clock forward and fires expired timers
+	Advance(d time.Duration)
+
+	// Next - expiry of earliest pending timer
+	Next() (time.Time, bool)
+
+	// Waiters - number of pending timers
+	Waiters() int
+}
+
+type timer struct {
+	until time.Time
+	ch    chan time.Time
+}
+
+type fakeClock struct {
+	now    time.Time
+	timers []*timer
+	sync.Mutex
+	Fake
+}
+
+// NewFakeClock creates a new Fake instance set to now
+func NewFakeClock(now time.Time) Fake {
+	return &fakeClock{now: now, timers: make([]*timer, 0)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.Lock()
+	defer c.Unlock()
+	return c.now
+}
+
+// After - returned channel receives time once clock
+// has been advanced by at least d
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.Lock()
+	defer c.Unlock()
+
+	t := &timer{until: c.now.Add(d), ch: make(chan time.Time, 1)}
+	if d <= 0 {
+		t.ch <- c.now
+		return t.ch
+	}
+
+	c.timers = append(c.timers, t)
+	return t.ch
+}
+
+// Sleep - returns immediately and advances clock by d
+// as if time has passed while sleeping
+func (c *fakeClock) Sleep(d time.Duration) {
+	c.Advance(d)
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.Lock()
+	defer c.Unlock()
+
+	c.now = c.now.Add(d)
+
+	pending := make([]*timer, 0, len(c.timers))
+	for _, t := range c.timers {
+		if t.until.After(c.now) {
+			pending = append(pending, t)
+			continue
+		}
+		t.ch <- c.now
+	}
+	c.timers = pending
+}
+
+func (c *fakeClock) Next() (time.Time, bool) {
+	c.Lock()
+	defer c.Unlock()
+
+	if len(c.timers) == 0 {
+		return time.Time{}, false
+	}
+
+	next := c.timers[0].until
+	for _, t := range c.timers[1:] {
+		if t.until.Before(next) {
+			next = t.until
+		}
+	}
+	return next, true
+}
+
+func (c *fakeClock) Waiters() int {
+	c.Lock()
+	defer c.Unlock()
+	return len(c.timers)
+}
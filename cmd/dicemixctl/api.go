@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// client of admin API
+type api struct {
+	base  string
+	token string
+	http  *http.Client
+}
+
+func newAPI(base, token string) *api {
+	return &api{
+		base:  strings.TrimSuffix(base, "/"),
+		token: token,
+		http:  &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// trusts certificates signed by CAs in PEM file at path
+// e.g. self signed certificate of admin API served over TLS
+func (a *api) trust(path string) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM(data) {
+		return errors.New("no certificates found in " + path)
+	}
+	a.http.Transport = &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots, MinVersion: tls.VersionTLS12}}
+	return nil
+}
+
+// sends request to path and decodes JSON response into result
+// result may be nil if response has no body
+func (a *api) do(method, path string, result interface{}) error {
+	body, err := a.raw(method, path)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	if result == nil {
+		return nil
+	}
+	return json.NewDecoder(body).Decode(result)
+}
+
+// sends request to path and returns body of successful response
+func (a *api) raw(method, path string) (io.ReadCloser, error) {
+	return a.send(context.Background(), a.http, method, path)
+}
+
+// opens streaming response of path which is read till ctx is done
+func (a *api) stream(ctx context.Context, path string) (io.ReadCloser, error) {
+	return a.send(ctx, &http.Client{Transport: a.http.Transport}, http.MethodGet, path)
+}
+
+func (a *api) send(ctx context.Context, client *http.Client, method, path string) (io.ReadCloser, error) {
+	request, err := http.NewRequest(method, a.base+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	request = request.WithContext(ctx)
+	if a.token != "" {
+		request.Header.Set("Authorization", "Bearer "+a.token)
+	}
+
+	response, err := client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+
+	if response.StatusCode >= 300 {
+		defer response.Body.Close()
+		message, _ := ioutil.ReadAll(io.LimitReader(response.Body, 1024))
+		if response.StatusCode == http.StatusUnauthorized {
+			return nil, errors.New("unauthorized, set -token or $DICEMIX_ADMIN_TOKEN")
+		}
+		return nil, fmt.Errorf("%s %s: %s %s", method, path, response.Status, strings.TrimSpace(string(message)))
+	}
+	return response.Body, nil
+}
@@ -0,0 +1,329 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/dev-appmonsters/dicemix-light-server/events"
+	"github.com/dev-appmonsters/dicemix-light-server/evidence"
+	"github.com/dev-appmonsters/dicemix-light-server/messages"
+	"github.com/dev-appmonsters/dicemix-light-server/reputation"
+	"github.com/dev-appmonsters/dicemix-light-server/server"
+)
+
+// names of requests expected by runs
+var stateNames = map[int]string{
+	messages.C_KEY_EXCHANGE:     "key_exchange",
+	messages.C_EXP_DC_VECTOR:    "dc_exp",
+	messages.C_SIMPLE_DC_VECTOR: "dc_simple",
+	messages.C_TX_CONFIRMATION:  "confirmation",
+	messages.C_KESK_RESPONSE:    "blame",
+}
+
+func stateName(state int) string {
+	if name, ok := stateNames[state]; ok {
+		return name
+	}
+	return strconv.Itoa(state)
+}
+
+// executes commands and prints their results
+// as tables or as JSON
+type command struct {
+	api  *api
+	out  io.Writer
+	json bool
+}
+
+// prints value as indented JSON
+func (c *command) printJSON(value interface{}) error {
+	encoder := json.NewEncoder(c.out)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(value)
+}
+
+// prints rows as table aligned by tabs
+func (c *command) printTable(header string, rows []string) error {
+	w := tabwriter.NewWriter(c.out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, header)
+	for _, row := range rows {
+		fmt.Fprintln(w, row)
+	}
+	return w.Flush()
+}
+
+// Status - summary of coordinator
+type Status struct {
+	Pools    int               `json:"pools"`
+	Pending  int               `json:"pending"`
+	Waiting  int               `json:"waiting"`
+	Runs     int               `json:"runs"`
+	Resuming int               `json:"resuming"`
+	Bans     int               `json:"bans"`
+	Counters map[string]uint64 `json:"counters"`
+}
+
+func (c *command) status() error {
+	pools := make([]server.PoolInfo, 0)
+	if err := c.api.do(http.MethodGet, "/pools", &pools); err != nil {
+		return err
+	}
+	clients := &server.ClientsInfo{}
+	if err := c.api.do(http.MethodGet, "/clients", clients); err != nil {
+		return err
+	}
+	runs := make([]server.RunInfo, 0)
+	if err := c.api.do(http.MethodGet, "/runs", &runs); err != nil {
+		return err
+	}
+	bans := make([]reputation.Ban, 0)
+	if err := c.api.do(http.MethodGet, "/bans", &bans); err != nil {
+		return err
+	}
+	counters, err := c.counters()
+	if err != nil {
+		return err
+	}
+
+	status := &Status{Pools: len(pools), Pending: clients.Pending, Waiting: len(clients.Waiting), Runs: len(runs), Bans: len(bans), Counters: counters}
+	for _, run := range runs {
+		if run.Resuming {
+			status.Resuming++
+		}
+	}
+
+	if c.json {
+		return c.printJSON(status)
+	}
+
+	rows := []string{
+		fmt.Sprintf("pools\t%d", status.Pools),
+		fmt.Sprintf("pending\t%d", status.Pending),
+		fmt.Sprintf("waiting\t%d", status.Waiting),
+		fmt.Sprintf("runs\t%d", status.Runs),
+		fmt.Sprintf("resuming\t%d", status.Resuming),
+		fmt.Sprintf("bans\t%d", status.Bans),
+	}
+	names := make([]string, 0, len(counters))
+	for name := range counters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		rows = append(rows, fmt.Sprintf("%s\t%d", name, counters[name]))
+	}
+	return c.printTable("NAME\tVALUE", rows)
+}
+
+// reads counters exposed in prometheus text format
+func (c *command) counters() (map[string]uint64, error) {
+	body, err := c.api.raw(http.MethodGet, "/metrics")
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	counters := make(map[string]uint64)
+	scanner := bufio.NewScanner(body)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		if value, err := strconv.ParseUint(fields[1], 10, 64); err == nil {
+			counters[strings.TrimPrefix(fields[0], "dicemix_")] = value
+		}
+	}
+	return counters, scanner.Err()
+}
+
+func (c *command) pools() error {
+	pools := make([]server.PoolInfo, 0)
+	if err := c.api.do(http.MethodGet, "/pools", &pools); err != nil {
+		return err
+	}
+	if c.json {
+		return c.printJSON(pools)
+	}
+
+	rows := make([]string, 0, len(pools))
+	for _, pool := range pools {
+		rows = append(rows, fmt.Sprintf("%s\t%d\t%s\t%d\t%d\t%d", pool.ID, pool.Denomination, pool.ScriptType, pool.MinPeers, pool.MaxPeers, pool.QueueDepth))
+	}
+	return c.printTable("ID\tDENOMINATION\tSCRIPT\tMIN PEERS\tMAX PEERS\tQUEUE", rows)
+}
+
+func (c *command) runs() error {
+	runs := make([]server.RunInfo, 0)
+	if err := c.api.do(http.MethodGet, "/runs", &runs); err != nil {
+		return err
+	}
+	if c.json {
+		return c.printJSON(runs)
+	}
+
+	rows := make([]string, 0, len(runs))
+	for _, run := range runs {
+		ids := make([]string, 0, len(run.Peers))
+		responded := 0
+		for _, peer := range run.Peers {
+			id := strconv.Itoa(int(peer.ID))
+			if peer.MessageReceived {
+				responded++
+				id += "*"
+			}
+			if !peer.Connected {
+				id += "!"
+			}
+			ids = append(ids, id)
+		}
+		rows = append(rows, fmt.Sprintf("%d\t%d\t%s\t%s\t%d/%d\t%t\t%s", run.SessionID, run.Run, run.PoolID, stateName(run.NextState), responded, len(run.Peers), run.Resuming, strings.Join(ids, ",")))
+	}
+	return c.printTable("SESSION\tRUN\tPOOL\tNEXT STATE\tRESPONDED\tRESUMING\tPEERS (*responded !offline)", rows)
+}
+
+func (c *command) clients() error {
+	clients := &server.ClientsInfo{}
+	if err := c.api.do(http.MethodGet, "/clients", clients); err != nil {
+		return err
+	}
+	if c.json {
+		return c.printJSON(clients)
+	}
+
+	fmt.Fprintln(c.out, "pending connections:", clients.Pending)
+	rows := make([]string, 0, len(clients.Waiting))
+	for _, waiting := range clients.Waiting {
+		rows = append(rows, fmt.Sprintf("%d\t%s\t%t", waiting.ID, waiting.PoolID, waiting.Ready))
+	}
+	return c.printTable("ID\tPOOL\tREADY", rows)
+}
+
+func (c *command) blame(sessionID uint64) error {
+	reports := make([]*evidence.Report, 0)
+	if err := c.api.do(http.MethodGet, "/blame?session="+strconv.FormatUint(sessionID, 10), &reports); err != nil {
+		return err
+	}
+	if c.json {
+		return c.printJSON(reports)
+	}
+
+	rows := make([]string, 0)
+	for _, report := range reports {
+		for _, excluded := range report.Excluded {
+			rows = append(rows, fmt.Sprintf("%d\t%d\t%s\t%s\t%d\t%s\t%s", report.SessionID, report.Run, report.PoolID, report.Timestamp, excluded.PeerID, excluded.Reason, excluded.LTPublicKey))
+		}
+	}
+	return c.printTable("SESSION\tRUN\tPOOL\tTIME\tPEER\tREASON\tKEY", rows)
+}
+
+func (c *command) bans() error {
+	bans := make([]reputation.Ban, 0)
+	if err := c.api.do(http.MethodGet, "/bans", &bans); err != nil {
+		return err
+	}
+	if c.json {
+		return c.printJSON(bans)
+	}
+	return c.printBans(bans)
+}
+
+func (c *command) printBans(bans []reputation.Ban) error {
+	rows := make([]string, 0, len(bans))
+	for _, ban := range bans {
+		rows = append(rows, fmt.Sprintf("%s\t%d\t%d\t%s", ban.Subject, ban.Offences, ban.Timeouts, ban.Until.Format(time.RFC3339)))
+	}
+	return c.printTable("SUBJECT\tOFFENCES\tTIMEOUTS\tUNTIL", rows)
+}
+
+func (c *command) ban(subject string, duration time.Duration) error {
+	path := "/bans?subject=" + url.QueryEscape(subject)
+	if duration > 0 {
+		path += "&duration=" + duration.String()
+	}
+
+	ban := &reputation.Ban{}
+	if err := c.api.do(http.MethodPost, path, ban); err != nil {
+		return err
+	}
+	if c.json {
+		return c.printJSON(ban)
+	}
+	return c.printBans([]reputation.Ban{*ban})
+}
+
+func (c *command) unban(subject string) error {
+	return c.api.do(http.MethodDelete, "/bans?subject="+url.QueryEscape(subject), nil)
+}
+
+// streams protocol events of coordinator and prints them till ctx is done
+func (c *command) events(ctx context.Context) error {
+	body, err := c.api.stream(ctx, "/events")
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	decoder := json.NewDecoder(body)
+	for {
+		event := &events.Event{}
+		if err := decoder.Decode(event); err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		if err := c.printEvent(event); err != nil {
+			return err
+		}
+	}
+}
+
+// prints event as a line of text or JSON
+// fields which do not apply to event are omitted
+func (c *command) printEvent(event *events.Event) error {
+	if c.json {
+		data, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(c.out, string(data))
+		return err
+	}
+
+	fields := []string{event.Time.Format(time.RFC3339), string(event.Type)}
+	if event.SessionID != 0 {
+		fields = append(fields, fmt.Sprintf("session=%d run=%d", event.SessionID, event.Run))
+	}
+	if event.PoolID != "" {
+		fields = append(fields, "pool="+event.PoolID)
+	}
+	if event.PeerID != 0 {
+		fields = append(fields, fmt.Sprintf("peer=%d", event.PeerID))
+	}
+	if len(event.Peers) > 0 {
+		fields = append(fields, fmt.Sprintf("peers=%v", event.Peers))
+	}
+	if event.Code != 0 {
+		fields = append(fields, "request="+stateName(int(event.Code)))
+	}
+	if event.ErrorCode != 0 {
+		fields = append(fields, fmt.Sprintf("error=%d", event.ErrorCode))
+	}
+	if event.Reason != "" {
+		fields = append(fields, strconv.Quote(event.Reason))
+	}
+
+	_, err := fmt.Fprintln(c.out, strings.Join(fields, " "))
+	return err
+}
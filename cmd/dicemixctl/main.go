@@ -0,0 +1,118 @@
+// dicemixctl - command line client of admin API of coordinator
+//
+// usage: dicemixctl [-admin url] [-token token] [-cacert file] [-json] <command> [arguments]
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strconv"
+)
+
+const usage = `usage: dicemixctl [flags] <command> [arguments]
+
+commands:
+  status                      summary of clients, runs, bans and counters
+  pools                       list pools
+  runs                        list runs and progress of their peers
+  clients                     list pending connections and waiting peers
+  blame <session>             blame reports of session
+  bans                        list banned keys and addresses
+  ban [-duration d] <subject> ban key:<hex> or ip:<address>
+  unban <subject>             lift ban of subject
+  events                      stream protocol events till interrupted
+
+flags:
+`
+
+func main() {
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+
+	if err := run(ctx, os.Args[1:], os.Stdout, os.Stderr); err != nil {
+		fmt.Fprintln(os.Stderr, "dicemixctl:", err)
+		os.Exit(1)
+	}
+}
+
+// parses flags and executes command
+func run(ctx context.Context, args []string, stdout, stderr io.Writer) error {
+	flags := flag.NewFlagSet("dicemixctl", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprint(stderr, usage)
+		flags.PrintDefaults()
+	}
+
+	admin := flags.String("admin", "http://127.0.0.1:8083", "url of admin API")
+	token := flags.String("token", os.Getenv("DICEMIX_ADMIN_TOKEN"), "bearer token of admin API, defaults to $DICEMIX_ADMIN_TOKEN")
+	caCert := flags.String("cacert", "", "PEM file of CA certificates trusted for https admin url")
+	asJSON := flags.Bool("json", false, "print JSON instead of tables")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return errors.New("missing command")
+	}
+
+	c := &command{api: newAPI(*admin, *token), out: stdout, json: *asJSON}
+	if *caCert != "" {
+		if err := c.api.trust(*caCert); err != nil {
+			return err
+		}
+	}
+	name, args := flags.Arg(0), flags.Args()[1:]
+
+	switch name {
+	case "status":
+		return c.status()
+	case "pools":
+		return c.pools()
+	case "runs":
+		return c.runs()
+	case "clients":
+		return c.clients()
+	case "bans":
+		return c.bans()
+	case "blame":
+		if len(args) != 1 {
+			return errors.New("usage: blame <session>")
+		}
+		sessionID, err := strconv.ParseUint(args[0], 10, 64)
+		if err != nil {
+			return errors.New("invalid session id " + args[0])
+		}
+		return c.blame(sessionID)
+	case "ban":
+		banFlags := flag.NewFlagSet("ban", flag.ContinueOnError)
+		banFlags.SetOutput(stderr)
+		duration := banFlags.Duration("duration", 0, "ban duration, as per offences of subject if zero")
+		if err := banFlags.Parse(args); err != nil {
+			return err
+		}
+		if banFlags.NArg() != 1 {
+			return errors.New("usage: ban [-duration d] <subject>")
+		}
+		return c.ban(banFlags.Arg(0), *duration)
+	case "unban":
+		if len(args) != 1 {
+			return errors.New("usage: unban <subject>")
+		}
+		return c.unban(args[0])
+	case "events":
+		if len(args) != 0 {
+			return errors.New("usage: events")
+		}
+		return c.events(ctx)
+	}
+
+	flags.Usage()
+	return errors.New("unknown command " + name)
+}
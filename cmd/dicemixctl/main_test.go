@@ -0,0 +1,233 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/pem"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dev-appmonsters/dicemix-light-server/events"
+	"github.com/dev-appmonsters/dicemix-light-server/messages"
+	"github.com/dev-appmonsters/dicemix-light-server/reputation"
+	"github.com/dev-appmonsters/dicemix-light-server/server"
+
+	"github.com/gorilla/websocket"
+)
+
+// starts admin API of a coordinator with default pools
+// returns function executing dicemixctl against it
+func newAdmin(t *testing.T) (func(args ...string) (string, error), func()) {
+	config := server.DefaultConfig()
+	config.Admin.Tokens = []string{"secret"}
+	s := httptest.NewServer(server.NewConnection(config).AdminHandler())
+
+	return func(args ...string) (string, error) {
+		var stdout, stderr bytes.Buffer
+		err := run(context.Background(), append([]string{"-admin", s.URL, "-token", "secret"}, args...), &stdout, &stderr)
+		return stdout.String(), err
+	}, s.Close
+}
+
+func TestCommands(t *testing.T) {
+	ctl, stop := newAdmin(t)
+	defer stop()
+
+	out, err := ctl("-json", "status")
+	status := &Status{}
+	if err != nil || json.Unmarshal([]byte(out), status) != nil || status.Pools != 3 || status.Runs != 0 {
+		t.Error("For", "status", "expected", "3 pools", "got", out, err)
+	}
+
+	out, err = ctl("status")
+	if err != nil || !strings.HasPrefix(out, "NAME") || !strings.Contains(out, "bans") {
+		t.Error("For", "status table", "expected", "bans row", "got", out, err)
+	}
+
+	out, err = ctl("-json", "pools")
+	pools := make([]server.PoolInfo, 0)
+	if err != nil || json.Unmarshal([]byte(out), &pools) != nil || len(pools) != 3 {
+		t.Error("For", "pools", "expected", 3, "got", out, err)
+	}
+
+	out, err = ctl("runs")
+	if err != nil || !strings.HasPrefix(out, "SESSION") || strings.Count(out, "\n") != 1 {
+		t.Error("For", "runs", "expected", "header only", "got", out, err)
+	}
+
+	out, err = ctl("-json", "clients")
+	if err != nil || !strings.Contains(out, `"pending": 0`) {
+		t.Error("For", "clients", "expected", "no clients", "got", out, err)
+	}
+
+	out, err = ctl("blame", "42")
+	if err != nil || strings.Count(out, "\n") != 1 {
+		t.Error("For", "blame", "expected", "header only", "got", out, err)
+	}
+}
+
+func TestBanCommands(t *testing.T) {
+	ctl, stop := newAdmin(t)
+	defer stop()
+
+	out, err := ctl("ban", "-duration", "2h", "key:02ab")
+	if err != nil || !strings.Contains(out, "key:02ab") {
+		t.Error("For", "ban", "expected", "key:02ab", "got", out, err)
+	}
+
+	out, err = ctl("-json", "bans")
+	bans := make([]reputation.Ban, 0)
+	if err != nil || json.Unmarshal([]byte(out), &bans) != nil || len(bans) != 1 || bans[0].Offences != 1 {
+		t.Error("For", "bans", "expected", "1 ban", "got", out, err)
+	}
+
+	if _, err := ctl("unban", "key:02ab"); err != nil {
+		t.Error("For", "unban", "expected", nil, "got", err)
+	}
+	if _, err := ctl("unban", "key:02ab"); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Error("For", "unban twice", "expected", "404", "got", err)
+	}
+	if _, err := ctl("ban", "02ab"); err == nil || !strings.Contains(err.Error(), "400") {
+		t.Error("For", "invalid subject", "expected", "400", "got", err)
+	}
+}
+
+type errorTestPair struct {
+	args []string
+	err  string
+}
+
+var errorTests = []errorTestPair{
+	{[]string{}, "missing command"},
+	{[]string{"reboot"}, "unknown command"},
+	{[]string{"blame"}, "usage"},
+	{[]string{"blame", "abc"}, "invalid session id"},
+	{[]string{"unban"}, "usage"},
+}
+
+func TestErrors(t *testing.T) {
+	ctl, stop := newAdmin(t)
+	defer stop()
+
+	for _, pair := range errorTests {
+		if _, err := ctl(pair.args...); err == nil || !strings.Contains(err.Error(), pair.err) {
+			t.Error("For", pair.args, "expected", pair.err, "got", err)
+		}
+	}
+
+	// token is required
+	var stdout, stderr bytes.Buffer
+	s := httptest.NewServer(server.NewConnection(func() *server.Config {
+		config := server.DefaultConfig()
+		config.Admin.Tokens = []string{"secret"}
+		return config
+	}()).AdminHandler())
+	defer s.Close()
+	if err := run(context.Background(), []string{"-admin", s.URL, "-token", "", "runs"}, &stdout, &stderr); err == nil || !strings.Contains(err.Error(), "unauthorized") {
+		t.Error("For", "without token", "expected", "unauthorized", "got", err)
+	}
+}
+
+func TestTLS(t *testing.T) {
+	config := server.DefaultConfig()
+	config.Admin.Tokens = []string{"secret"}
+	s := httptest.NewTLSServer(server.NewConnection(config).AdminHandler())
+	defer s.Close()
+
+	dir, err := ioutil.TempDir("", "dicemixctl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "ca.pem")
+	ca := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: s.Certificate().Raw})
+	if err := ioutil.WriteFile(path, ca, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	// certificate of admin API is verified against trusted CAs
+	for _, trusted := range []bool{false, true} {
+		args := []string{"-admin", s.URL, "-token", "secret", "runs"}
+		if trusted {
+			args = append([]string{"-cacert", path}, args...)
+		}
+
+		var stdout, stderr bytes.Buffer
+		if err := run(context.Background(), args, &stdout, &stderr); (err == nil) != trusted {
+			t.Error("For", "trusted", trusted, "expected success", trusted, "got", err)
+		}
+	}
+}
+
+func TestEvents(t *testing.T) {
+	connection := server.NewConnection(server.DefaultConfig())
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ws", connection.Register)
+	mux.Handle("/", connection.AdminHandler())
+	s := httptest.NewServer(mux)
+	defer s.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	reader, writer := io.Pipe()
+	done := make(chan error, 1)
+	go func() {
+		var stderr bytes.Buffer
+		done <- run(ctx, []string{"-admin", s.URL, "-json", "events"}, writer, &stderr)
+		writer.Close()
+	}()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(s.URL, "http")+"/ws", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	// malformed requests are rejected till stream reports one
+	lines := make(chan string)
+	go func() {
+		scanner := bufio.NewScanner(reader)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+		close(lines)
+	}()
+
+	ticker := time.NewTicker(50 * time.Millisecond)
+	defer ticker.Stop()
+
+	var line string
+	for line == "" {
+		select {
+		case <-ticker.C:
+			conn.WriteMessage(websocket.BinaryMessage, []byte("malformed"))
+		case line = <-lines:
+		case <-time.After(10 * time.Second):
+			t.Fatal("For", "events", "expected", "request_rejected", "got", "nothing")
+		}
+	}
+
+	event := &events.Event{}
+	if err := json.Unmarshal([]byte(line), event); err != nil || event.Type != events.RequestRejected || event.ErrorCode != messages.E_MALFORMED_REQUEST {
+		t.Error("For", "events", "expected", "request_rejected", "got", line, err)
+	}
+
+	// stream ends once interrupted
+	cancel()
+	go func() {
+		for range lines {
+		}
+	}()
+	if err := <-done; err != nil {
+		t.Error("For", "interrupted", "expected", nil, "got", err)
+	}
+}
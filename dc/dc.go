@@ -6,6 +6,6 @@ import (
 
 // DC - The main interface DC_NET.
 type DC interface {
-	SolveDCExponential([]*messages.PeersInfo) []uint64
-	ResolveDCNet([]*messages.PeersInfo, int) [][]byte
+	SolveDCExponential([]*messages.PeersInfo) ([]uint64, error)
+	ResolveDCNet([]*messages.PeersInfo, int) ([][]byte, error)
 }
@@ -1,6 +1,8 @@
 package dc
 
 import (
+	"errors"
+
 	"github.com/dev-appmonsters/dicemix-light-server/field"
 	"github.com/dev-appmonsters/dicemix-light-server/messages"
 	"github.com/dev-appmonsters/dicemix-light-server/solver"
@@ -16,19 +18,40 @@ func NewDCNetwork() DC {
 	return &dcNet{}
 }
 
+// MaxMsgsCount - FLINT fails to obtain roots of larger DC-COMBINED vectors
+// check - solver/solver_flint.cpp (46)
+const MaxMsgsCount = 1000
+
 // obtains all peers DC-EXP vectors
 // combines them and generates DC-COMBINED vector
 // solves DC-COMBINED vector and obtain's its roots using Flint
-func (d *dcNet) SolveDCExponential(peers []*messages.PeersInfo) []uint64 {
+func (d *dcNet) SolveDCExponential(peers []*messages.PeersInfo) ([]uint64, error) {
 	var i, totalMsgsCount uint32
-	var dcCombined = make([]uint64, len(peers[0].DCVector))
-	copy(dcCombined, peers[0].DCVector)
+	if len(peers) == 0 {
+		return nil, errors.New("no peers to solve DC-EXP vectors")
+	}
 
 	// obtain Total Messages Count
 	for _, peer := range peers {
 		totalMsgsCount += peer.NumMsgs
 	}
 
+	// Basic sanity check to avoid weird inputs
+	if totalMsgsCount == 0 || totalMsgsCount >= MaxMsgsCount {
+		return nil, errors.New("invalid total messages count")
+	}
+
+	// every peer should have sent a DC-EXP vector
+	// with an element for every message
+	for _, peer := range peers {
+		if len(peer.DCVector) != int(totalMsgsCount) {
+			return nil, errors.New("invalid DC-EXP vector length")
+		}
+	}
+
+	var dcCombined = make([]uint64, totalMsgsCount)
+	copy(dcCombined, peers[0].DCVector)
+
 	// generates DC-COMBINED vector
 	for j := 1; j < len(peers); j++ {
 		for i = 0; i < totalMsgsCount; i++ {
@@ -36,16 +59,30 @@ func (d *dcNet) SolveDCExponential(peers []*messages.PeersInfo) []uint64 {
 		}
 	}
 
-	// NOTE: totalMsgsCount should be less than 1000 or else FLINT would fail to obtain roots
-	// and [0,0,......] will be considered as roots
-	// Basic sanity check to avoid weird inputs
-	// check - solver/solver_flint.cpp (46)
-	return solver.Solve(dcCombined, int(totalMsgsCount))
+	return solver.Solve(dcCombined, int(totalMsgsCount)), nil
 }
 
 // Resolve the DC-net
-func (d *dcNet) ResolveDCNet(peers []*messages.PeersInfo, totalMsgsCount int) [][]byte {
-	var allMessages = make([][]byte, len(peers[0].DCSimpleVector))
+func (d *dcNet) ResolveDCNet(peers []*messages.PeersInfo, totalMsgsCount int) ([][]byte, error) {
+	if len(peers) == 0 {
+		return nil, errors.New("no peers to resolve DC-SIMPLE vectors")
+	}
+
+	// every peer should have sent a DC-SIMPLE vector
+	// with a slot of MessageSize for every message
+	for _, peer := range peers {
+		if len(peer.DCSimpleVector) != totalMsgsCount {
+			return nil, errors.New("invalid DC-SIMPLE vector length")
+		}
+
+		for _, slot := range peer.DCSimpleVector {
+			if len(slot) != utils.MessageSize {
+				return nil, errors.New("invalid DC-SIMPLE slot size")
+			}
+		}
+	}
+
+	var allMessages = make([][]byte, totalMsgsCount)
 
 	// copies DCSimpleVector
 	for i, vector := range peers[0].DCSimpleVector {
@@ -62,5 +99,5 @@ func (d *dcNet) ResolveDCNet(peers []*messages.PeersInfo, totalMsgsCount int) []
 
 		}
 	}
-	return allMessages
+	return allMessages, nil
 }
@@ -0,0 +1,110 @@
+package dc
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/dev-appmonsters/dicemix-light-server/messages"
+	"github.com/dev-appmonsters/dicemix-light-server/utils"
+)
+
+// reads fuzzed bytes sequentially, zeros once exhausted
+type fuzzReader struct {
+	data []byte
+}
+
+func (r *fuzzReader) uint64() uint64 {
+	var buf [8]byte
+	r.data = r.data[copy(buf[:], r.data):]
+	return binary.LittleEndian.Uint64(buf[:])
+}
+
+func (r *fuzzReader) bytes(n int) []byte {
+	buf := make([]byte, n)
+	r.data = r.data[copy(buf, r.data):]
+	return buf
+}
+
+// creates a peer for every byte of numMsgs
+// lengths[i] - number of elements in vectors of i-th peer
+func fuzzPeers(numMsgs, lengths, sizes, values []byte) []*messages.PeersInfo {
+	reader := &fuzzReader{data: values}
+	peers := make([]*messages.PeersInfo, 0)
+
+	for i := 0; i < len(numMsgs) && i < 8; i++ {
+		length := 0
+		if i < len(lengths) {
+			length = int(lengths[i] % 16)
+		}
+
+		peer := &messages.PeersInfo{Id: int32(i), NumMsgs: uint32(numMsgs[i] % 8)}
+		for j := 0; j < length; j++ {
+			size := utils.MessageSize
+			if len(sizes) > 0 {
+				size = int(sizes[(i+j)%len(sizes)] % 32)
+			}
+			peer.DCVector = append(peer.DCVector, reader.uint64())
+			peer.DCSimpleVector = append(peer.DCSimpleVector, reader.bytes(size))
+		}
+		peers = append(peers, peer)
+	}
+	return peers
+}
+
+func seedValues(values ...uint64) []byte {
+	buf := make([]byte, 8*len(values))
+	for i, value := range values {
+		binary.LittleEndian.PutUint64(buf[8*i:], value)
+	}
+	return buf
+}
+
+func FuzzSolveDCExponential(f *testing.F) {
+	f.Add([]byte{3, 2}, []byte{5, 5}, seedValues(1859546079985200847, 1646884441642370562, 1945157946220288822, 2071666930927106951, 1683255082316998317))
+	f.Add([]byte{3, 2}, []byte{5, 4}, seedValues(1, 2, 3))
+	f.Add([]byte{1}, []byte{0}, []byte{})
+	f.Add([]byte{}, []byte{}, []byte{})
+	f.Add([]byte{0, 0}, []byte{0, 0}, []byte{})
+
+	f.Fuzz(func(t *testing.T, numMsgs, lengths, values []byte) {
+		peers := fuzzPeers(numMsgs, lengths, nil, values)
+
+		roots, err := NewDCNetwork().SolveDCExponential(peers)
+		if err != nil {
+			return
+		}
+
+		var total uint32
+		for _, peer := range peers {
+			total += peer.NumMsgs
+		}
+		if len(roots) != int(total) {
+			t.Errorf("expected %d roots got %d", total, len(roots))
+		}
+	})
+}
+
+func FuzzResolveDCNet(f *testing.F) {
+	f.Add(uint8(2), []byte{1, 1}, []byte{2, 2}, []byte{20}, []byte{1, 2, 3})
+	f.Add(uint8(2), []byte{1, 1}, []byte{2, 1}, []byte{20}, []byte{1, 2, 3})
+	f.Add(uint8(2), []byte{1, 1}, []byte{2, 2}, []byte{20, 3}, []byte{})
+	f.Add(uint8(0), []byte{}, []byte{}, []byte{}, []byte{})
+
+	f.Fuzz(func(t *testing.T, total uint8, numMsgs, lengths, sizes, values []byte) {
+		peers := fuzzPeers(numMsgs, lengths, sizes, values)
+
+		allMessages, err := NewDCNetwork().ResolveDCNet(peers, int(total))
+		if err != nil {
+			return
+		}
+
+		if len(allMessages) != int(total) {
+			t.Errorf("expected %d messages got %d", total, len(allMessages))
+		}
+		for _, message := range allMessages {
+			if len(message) != utils.MessageSize {
+				t.Errorf("expected message of %d bytes got %d", utils.MessageSize, len(message))
+			}
+		}
+	})
+}
@@ -17,12 +17,15 @@ func NewCurveECDSA() ECDSA {
 // Verify reports whether sig is a valid signature of message by publicKey.
 func (e *curveP256) Verify(publicKeyBytes, message, signatureBytes []byte) bool {
 	publicKey, err := btcec.ParsePubKey(publicKeyBytes, btcec.S256())
-	signature, err := btcec.ParseDERSignature(signatureBytes, btcec.S256())
-	messageHash := chainhash.DoubleHashB(message)
+	if err != nil {
+		return false
+	}
 
+	signature, err := btcec.ParseDERSignature(signatureBytes, btcec.S256())
 	if err != nil {
 		return false
 	}
+	messageHash := chainhash.DoubleHashB(message)
 
 	// Verify the signature for the message using the public key.
 	return signature.Verify(messageHash, publicKey)
@@ -0,0 +1,89 @@
+package events
+
+import "time"
+
+// Type - kind of protocol event
+type Type string
+
+// events emitted by coordinator
+const (
+	// PeerJoined - peer has joined a pool or resumed his run
+	PeerJoined Type = "peer_joined"
+
+	// RunStarted - peers waiting in pool have been moved to a new run
+	RunStarted Type = "run_started"
+
+	// RequestAccepted - request of peer has been accepted in current round
+	RequestAccepted Type = "request_accepted"
+
+	// RequestRejected - request of peer has been refused, see Reason
+	RequestRejected Type = "request_rejected"
+
+	// PhaseTimedOut - some peers have not responded within ResponseWait
+	PhaseTimedOut Type = "phase_timed_out"
+
+	// PeersExcluded - peers removed from run, see Reason
+	PeersExcluded Type = "peers_excluded"
+
+	// BlameStarted - peers have been asked to reveal their KESK
+	BlameStarted Type = "blame_started"
+
+	// RunSucceeded - transaction has been confirmed by all peers
+	RunSucceeded Type = "run_succeeded"
+
+	// RunTerminated - run has ended without transaction, see Reason
+	RunTerminated Type = "run_terminated"
+)
+
+// Event - a state transition of coordinator
+// fields which do not apply to Type are left empty
+type Event struct {
+	Type      Type      `json:"type"`
+	Time      time.Time `json:"time"`
+	SessionID uint64    `json:"sessionId,omitempty"`
+	Run       int       `json:"run,omitempty"`
+	PoolID    string    `json:"poolId,omitempty"`
+	PeerID    int32     `json:"peerId,omitempty"`
+	Peers     []int32   `json:"peers,omitempty"`
+
+	// Count - number of Peers, set when peers are redacted
+	Count int `json:"count,omitempty"`
+
+	// Code - request code of accepted request
+	// or request expected in timed out phase
+	Code uint32 `json:"code,omitempty"`
+
+	// ErrorCode - error code sent to peer along with rejection
+	ErrorCode uint32 `json:"errorCode,omitempty"`
+
+	Reason string `json:"reason,omitempty"`
+}
+
+// Sink - The main interface for consumers of events.
+// Emit is called while coordinator is locked and should not block
+type Sink interface {
+	Emit(*Event)
+}
+
+// SinkFunc - adapts a function to Sink
+type SinkFunc func(*Event)
+
+// Emit - calls f with event
+func (f SinkFunc) Emit(event *Event) {
+	f(event)
+}
+
+type multiSink []Sink
+
+// NewMultiSink creates a new Sink instance
+// which emits every event to all of sinks
+func NewMultiSink(sinks ...Sink) Sink {
+	return multiSink(sinks)
+}
+
+// Emit - emits event to all sinks
+func (m multiSink) Emit(event *Event) {
+	for _, sink := range m {
+		sink.Emit(event)
+	}
+}
@@ -0,0 +1,103 @@
+package events
+
+import (
+	"bufio"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var testEvents = []*Event{
+	{Type: RunStarted, SessionID: 1, PoolID: "1000000-p2wpkh", Peers: []int32{1, 2, 3}},
+	{Type: RequestRejected, SessionID: 1, PeerID: 2, ErrorCode: 209, Reason: "invalid vector"},
+	{Type: RunSucceeded, SessionID: 1},
+}
+
+func TestMultiSink(t *testing.T) {
+	memory := NewMemorySink()
+	counts := make(map[Type]int)
+	sink := NewMultiSink(memory, SinkFunc(func(event *Event) { counts[event.Type]++ }))
+
+	for _, event := range testEvents {
+		sink.Emit(event)
+	}
+
+	events := memory.Events()
+	if len(events) != len(testEvents) || events[1] != testEvents[1] {
+		t.Error("For", "memory", "expected", testEvents, "got", events)
+	}
+	if counts[RunStarted] != 1 || counts[RequestRejected] != 1 || counts[RunSucceeded] != 1 {
+		t.Error("For", "func", "expected", "1 of each", "got", counts)
+	}
+}
+
+func TestFileSink(t *testing.T) {
+	dir, err := ioutil.TempDir("", "events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "events.jsonl")
+	for i := 0; i < 2; i++ {
+		sink, err := NewFileSink(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, event := range testEvents {
+			sink.Emit(event)
+		}
+	}
+
+	// events are appended across restarts
+	file, _ := os.Open(path)
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	lines := 0
+	for ; scanner.Scan(); lines++ {
+		event := &Event{}
+		expected := testEvents[lines%len(testEvents)]
+		if err := json.Unmarshal(scanner.Bytes(), event); err != nil || event.Type != expected.Type || event.Reason != expected.Reason {
+			t.Error("For", "line", lines, "expected", expected, "got", event, err)
+		}
+	}
+	if lines != 2*len(testEvents) {
+		t.Error("For", "lines", "expected", 2*len(testEvents), "got", lines)
+	}
+}
+
+func TestFeed(t *testing.T) {
+	feed := NewFeed()
+	feed.Emit(testEvents[0])
+
+	slow, cancelSlow := feed.Subscribe(1)
+	fast, cancelFast := feed.Subscribe(len(testEvents))
+	for _, event := range testEvents {
+		feed.Emit(event)
+	}
+
+	// slow subscriber loses events instead of blocking feed
+	if event := <-slow; event != testEvents[0] {
+		t.Error("For", "slow", "expected", testEvents[0], "got", event)
+	}
+	cancelSlow()
+	if _, ok := <-slow; ok {
+		t.Error("For", "slow", "expected", "closed", "got", "event")
+	}
+
+	for _, expected := range testEvents {
+		if event := <-fast; event != expected {
+			t.Error("For", "fast", "expected", expected, "got", event)
+		}
+	}
+
+	// cancelled subscribers receive nothing
+	cancelFast()
+	cancelFast()
+	feed.Emit(testEvents[0])
+	if _, ok := <-fast; ok {
+		t.Error("For", "cancelled", "expected", "closed", "got", "event")
+	}
+}
@@ -0,0 +1,56 @@
+package events
+
+import "sync"
+
+// Feed - Sink which forwards events to subscribers, e.g. admin API
+type Feed interface {
+	Sink
+
+	// Subscribe - returns channel of events emitted from now on
+	// and function which cancels subscription
+	Subscribe(buffer int) (<-chan *Event, func())
+}
+
+type feed struct {
+	subscribers map[chan *Event]bool
+	sync.Mutex
+	Feed
+}
+
+// NewFeed creates a new Feed instance
+// events are dropped for subscribers which are not keeping up
+func NewFeed() Feed {
+	return &feed{subscribers: make(map[chan *Event]bool)}
+}
+
+// Emit - forwards event to all subscribers without blocking
+func (f *feed) Emit(event *Event) {
+	f.Lock()
+	defer f.Unlock()
+
+	for subscriber := range f.subscribers {
+		select {
+		case subscriber <- event:
+		default:
+		}
+	}
+}
+
+// Subscribe - registers a new subscriber
+func (f *feed) Subscribe(buffer int) (<-chan *Event, func()) {
+	subscriber := make(chan *Event, buffer)
+
+	f.Lock()
+	f.subscribers[subscriber] = true
+	f.Unlock()
+
+	var once sync.Once
+	return subscriber, func() {
+		once.Do(func() {
+			f.Lock()
+			defer f.Unlock()
+			delete(f.subscribers, subscriber)
+			close(subscriber)
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package events
+
+import (
+	"encoding/json"
+	"os"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+)
+
+type fileSink struct {
+	file *os.File
+	sync.Mutex
+	Sink
+}
+
+// NewFileSink creates a new Sink instance which appends
+// events as JSON lines to file at path, i.e. an audit log
+func NewFileSink(path string) (Sink, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, err
+	}
+	return &fileSink{file: file}, nil
+}
+
+// Emit - appends event to file
+// failures are logged as events can not be refused
+func (s *fileSink) Emit(event *Event) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		log.Error("Error:- ", err)
+		return
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	if _, err := s.file.Write(append(data, '\n')); err != nil {
+		log.Error("Error:- ", err)
+	}
+}
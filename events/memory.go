@@ -0,0 +1,38 @@
+package events
+
+import "sync"
+
+// MemorySink - Sink which keeps all events, used by tests
+type MemorySink interface {
+	Sink
+	Events() []*Event
+}
+
+type memorySink struct {
+	events []*Event
+	sync.Mutex
+	MemorySink
+}
+
+// NewMemorySink creates a new MemorySink instance
+func NewMemorySink() MemorySink {
+	return &memorySink{events: make([]*Event, 0)}
+}
+
+// Emit - stores event
+func (s *memorySink) Emit(event *Event) {
+	s.Lock()
+	defer s.Unlock()
+
+	s.events = append(s.events, event)
+}
+
+// Events - returns all events in order of emission
+func (s *memorySink) Events() []*Event {
+	s.Lock()
+	defer s.Unlock()
+
+	events := make([]*Event, len(s.events))
+	copy(events, s.events)
+	return events
+}
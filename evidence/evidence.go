@@ -0,0 +1,56 @@
+package evidence
+
+// Reason - why a peer has been excluded in BLAME stage
+type Reason string
+
+// reasons for which a peer is excluded in BLAME stage
+const (
+	// MissingKESK - peer has not revealed his KESK
+	MissingKESK Reason = "missing_kesk"
+
+	// BadKeypair - revealed KESK does not correspond to KEPK
+	BadKeypair Reason = "bad_keypair"
+
+	// WrongMessageCount - number of messages recovered from DC-SIMPLE
+	// vector differs from number of messages declared in KE
+	WrongMessageCount Reason = "wrong_message_count"
+
+	// DCExpMismatch - DC-EXP vector does not correspond to messages
+	// sent in DC-SIMPLE vector
+	DCExpMismatch Reason = "dc_exp_mismatch"
+
+	// FalseOK - peer sent OK=false while his message hashes were in roots
+	FalseOK Reason = "false_ok"
+
+	// RefusedConfirmation - peer refused confirmation while
+	// his messages were included
+	RefusedConfirmation Reason = "refused_confirmation"
+
+	// SlotCollision - message hash of peer collides with another peer
+	SlotCollision Reason = "slot_collision"
+)
+
+// Exclusion - an excluded peer along with supporting values
+type Exclusion struct {
+	PeerID      int32                  `json:"peerId"`
+	LTPublicKey string                 `json:"ltPublicKey"`
+	Reason      Reason                 `json:"reason"`
+	Values      map[string]interface{} `json:"values,omitempty"`
+}
+
+// Report - outcome of BLAME stage of a run
+type Report struct {
+	SessionID uint64       `json:"sessionId"`
+	Run       int          `json:"run"`
+	PoolID    string       `json:"poolId"`
+	Timestamp string       `json:"timestamp"`
+	Peers     []int32      `json:"peers"`
+	Excluded  []*Exclusion `json:"excluded"`
+}
+
+// Store - The main interface for storing blame reports.
+type Store interface {
+	Save(*Report) error
+	Get(sessionID uint64) ([]*Report, error)
+	List() ([]*Report, error)
+}
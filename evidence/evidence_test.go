@@ -0,0 +1,69 @@
+package evidence
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var reports = []*Report{
+	{SessionID: 1, Run: 1, PoolID: "1000000-p2wpkh", Peers: []int32{1, 2, 3}, Excluded: []*Exclusion{{PeerID: 2, Reason: BadKeypair}}},
+	{SessionID: 2, Run: 1, PoolID: "1000000-p2wpkh", Peers: []int32{4, 5, 6}, Excluded: []*Exclusion{{PeerID: 4, Reason: SlotCollision}, {PeerID: 5, Reason: SlotCollision}}},
+	{SessionID: 1, Run: 2, PoolID: "1000000-p2wpkh", Peers: []int32{1, 3}, Excluded: []*Exclusion{{PeerID: 3, Reason: FalseOK, Values: map[string]interface{}{"roots": []uint64{1}}}}},
+}
+
+func checkStore(t *testing.T, store Store) {
+	all, err := store.List()
+	if err != nil || len(all) != len(reports) {
+		t.Fatal("expected", len(reports), "reports got", len(all), err)
+	}
+
+	session, err := store.Get(1)
+	if err != nil || len(session) != 2 || session[0].Run != 1 || session[1].Run != 2 {
+		t.Error("expected 2 runs of session 1 got", session, err)
+	}
+
+	if session[1].Excluded[0].Reason != FalseOK || session[1].Excluded[0].PeerID != 3 {
+		t.Error("unexpected exclusion", session[1].Excluded[0])
+	}
+
+	if none, _ := store.Get(3); len(none) != 0 {
+		t.Error("expected no reports for session 3 got", none)
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	store := NewMemoryStore()
+	for _, report := range reports {
+		store.Save(report)
+	}
+	checkStore(t, store)
+}
+
+func TestFileStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "evidence")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "blame.jsonl")
+	store, err := NewFileStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, report := range reports {
+		if err := store.Save(report); err != nil {
+			t.Fatal(err)
+		}
+	}
+	checkStore(t, store)
+
+	// reports are loaded again after restart
+	reopened, err := NewFileStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkStore(t, reopened)
+}
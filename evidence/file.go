@@ -0,0 +1,74 @@
+package evidence
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"sync"
+)
+
+type fileStore struct {
+	file   *os.File
+	memory Store
+	sync.Mutex
+	Store
+}
+
+// NewFileStore creates a new Store instance which persists
+// reports as JSON lines in file at path
+// reports already present in file are loaded
+func NewFileStore(path string) (Store, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	memory := NewMemoryStore()
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		report := &Report{}
+		if err := json.Unmarshal(scanner.Bytes(), report); err != nil {
+			file.Close()
+			return nil, err
+		}
+		memory.Save(report)
+	}
+
+	if err := scanner.Err(); err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	return &fileStore{file: file, memory: memory}, nil
+}
+
+// Save - appends report to file
+func (s *fileStore) Save(report *Report) error {
+	s.Lock()
+	defer s.Unlock()
+
+	data, err := json.Marshal(report)
+	if err != nil {
+		return err
+	}
+
+	if _, err := s.file.Write(append(data, '\n')); err != nil {
+		return err
+	}
+
+	if err := s.file.Sync(); err != nil {
+		return err
+	}
+	return s.memory.Save(report)
+}
+
+// Get - returns reports of all runs of session
+func (s *fileStore) Get(sessionID uint64) ([]*Report, error) {
+	return s.memory.Get(sessionID)
+}
+
+// List - returns all stored reports
+func (s *fileStore) List() ([]*Report, error) {
+	return s.memory.List()
+}
@@ -0,0 +1,50 @@
+package evidence
+
+import (
+	"sync"
+)
+
+type memoryStore struct {
+	reports []*Report
+	sync.Mutex
+	Store
+}
+
+// NewMemoryStore creates a new Store instance
+// which keeps reports in memory
+func NewMemoryStore() Store {
+	return &memoryStore{reports: make([]*Report, 0)}
+}
+
+// Save - stores report
+func (s *memoryStore) Save(report *Report) error {
+	s.Lock()
+	defer s.Unlock()
+
+	s.reports = append(s.reports, report)
+	return nil
+}
+
+// Get - returns reports of all runs of session
+func (s *memoryStore) Get(sessionID uint64) ([]*Report, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	reports := make([]*Report, 0)
+	for _, report := range s.reports {
+		if report.SessionID == sessionID {
+			reports = append(reports, report)
+		}
+	}
+	return reports, nil
+}
+
+// List - returns all stored reports
+func (s *memoryStore) List() ([]*Report, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	reports := make([]*Report, len(s.reports))
+	copy(reports, s.reports)
+	return reports, nil
+}
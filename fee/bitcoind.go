@@ -0,0 +1,97 @@
+package fee
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"time"
+)
+
+type bitcoindEstimator struct {
+	url        string
+	user       string
+	password   string
+	confTarget uint32
+	client     *http.Client
+	Estimator
+}
+
+// JSON-RPC request sent to bitcoind
+type rpcRequest struct {
+	JSONRPC string        `json:"jsonrpc"`
+	ID      string        `json:"id"`
+	Method  string        `json:"method"`
+	Params  []interface{} `json:"params"`
+}
+
+// JSON-RPC response of estimatesmartfee
+type rpcResponse struct {
+	Result *struct {
+		FeeRate float64  `json:"feerate"`
+		Errors  []string `json:"errors"`
+		Blocks  int      `json:"blocks"`
+	} `json:"result"`
+	Error *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// NewBitcoindEstimator creates a new Estimator instance which
+// obtains fee rate from estimatesmartfee RPC of bitcoind
+func NewBitcoindEstimator(url, user, password string, confTarget uint32) Estimator {
+	return &bitcoindEstimator{
+		url:        url,
+		user:       user,
+		password:   password,
+		confTarget: confTarget,
+		client:     &http.Client{Timeout: 5 * time.Second},
+	}
+}
+
+// EstimateFeeRate - returns fee rate for confirmation within confTarget blocks
+// bitcoind returns fee rate in BTC/kvB which is converted to sat/vB
+func (e *bitcoindEstimator) EstimateFeeRate() (uint64, error) {
+	body, err := json.Marshal(&rpcRequest{
+		JSONRPC: "1.0",
+		ID:      "dicemix",
+		Method:  "estimatesmartfee",
+		Params:  []interface{}{e.confTarget},
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	request, err := http.NewRequest(http.MethodPost, e.url, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.SetBasicAuth(e.user, e.password)
+
+	response, err := e.client.Do(request)
+	if err != nil {
+		return 0, err
+	}
+	defer response.Body.Close()
+
+	result := &rpcResponse{}
+	if err := json.NewDecoder(response.Body).Decode(result); err != nil {
+		return 0, fmt.Errorf("estimatesmartfee: %s, %v", response.Status, err)
+	}
+
+	if result.Error != nil {
+		return 0, errors.New("estimatesmartfee: " + result.Error.Message)
+	}
+
+	if result.Result == nil || result.Result.FeeRate <= 0 {
+		return 0, errors.New("estimatesmartfee: insufficient data")
+	}
+
+	// 1 BTC/kvB = 100000000 sat / 1000 vB
+	satPerKvB := uint64(math.Round(result.Result.FeeRate * 1e8))
+	return (satPerKvB + 999) / 1000, nil
+}
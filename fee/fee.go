@@ -0,0 +1,7 @@
+package fee
+
+// Estimator - The main interface for miner fee rate estimation.
+// fee rate is in satoshis per virtual byte
+type Estimator interface {
+	EstimateFeeRate() (uint64, error)
+}
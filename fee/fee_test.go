@@ -0,0 +1,66 @@
+package fee
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type bitcoindTestPair struct {
+	response string
+	res      uint64
+	err      bool
+}
+
+type failingEstimator struct {
+	Estimator
+}
+
+func (e *failingEstimator) EstimateFeeRate() (uint64, error) {
+	return 0, errors.New("estimation failed")
+}
+
+var bitcoindTests = []bitcoindTestPair{
+	{`{"result":{"feerate":0.00001,"blocks":6},"error":null,"id":"dicemix"}`, 1, false},
+	{`{"result":{"feerate":0.00012345,"blocks":6},"error":null,"id":"dicemix"}`, 13, false},
+	{`{"result":{"errors":["Insufficient data or no feerate found"],"blocks":0},"error":null,"id":"dicemix"}`, 0, true},
+	{`{"result":null,"error":{"code":-32601,"message":"Method not found"},"id":"dicemix"}`, 0, true},
+	{`not json`, 0, true},
+}
+
+func TestBitcoindEstimator(t *testing.T) {
+	for _, pair := range bitcoindTests {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if user, password, ok := r.BasicAuth(); !ok || user != "user" || password != "password" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			w.Write([]byte(pair.response))
+		}))
+
+		feeRate, err := NewBitcoindEstimator(server.URL, "user", "password", 6).EstimateFeeRate()
+		if feeRate != pair.res || (err != nil) != pair.err {
+			t.Error(
+				"For", pair.response,
+				"expected", pair.res,
+				"got", feeRate, err,
+			)
+		}
+		server.Close()
+	}
+}
+
+func TestFallbackEstimator(t *testing.T) {
+	if feeRate, err := NewStaticEstimator(7).EstimateFeeRate(); feeRate != 7 || err != nil {
+		t.Error("expected 7 got", feeRate, err)
+	}
+
+	if feeRate, err := NewFallbackEstimator(&failingEstimator{}, NewStaticEstimator(5)).EstimateFeeRate(); feeRate != 5 || err != nil {
+		t.Error("expected fallback 5 got", feeRate, err)
+	}
+
+	if feeRate, err := NewFallbackEstimator(NewStaticEstimator(3), NewStaticEstimator(5)).EstimateFeeRate(); feeRate != 3 || err != nil {
+		t.Error("expected primary 3 got", feeRate, err)
+	}
+}
@@ -0,0 +1,45 @@
+package fee
+
+import (
+	log "github.com/sirupsen/logrus"
+)
+
+type staticEstimator struct {
+	feeRate uint64
+	Estimator
+}
+
+type fallbackEstimator struct {
+	primary  Estimator
+	fallback Estimator
+	Estimator
+}
+
+// NewStaticEstimator creates a new Estimator instance
+// which always returns configured fee rate
+func NewStaticEstimator(feeRate uint64) Estimator {
+	return &staticEstimator{feeRate: feeRate}
+}
+
+// NewFallbackEstimator creates a new Estimator instance which
+// uses fallback estimator when primary estimator fails
+func NewFallbackEstimator(primary, fallback Estimator) Estimator {
+	return &fallbackEstimator{primary: primary, fallback: fallback}
+}
+
+// EstimateFeeRate - returns configured fee rate
+func (e *staticEstimator) EstimateFeeRate() (uint64, error) {
+	return e.feeRate, nil
+}
+
+// EstimateFeeRate - returns fee rate estimated by primary estimator
+// if primary estimator fails returns fee rate of fallback estimator
+func (e *fallbackEstimator) EstimateFeeRate() (uint64, error) {
+	feeRate, err := e.primary.EstimateFeeRate()
+	if err == nil {
+		return feeRate, nil
+	}
+
+	log.Warn("Fee estimation failed, using fallback. Error - ", err)
+	return e.fallback.EstimateFeeRate()
+}
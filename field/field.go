@@ -1,8 +1,6 @@
 package field
 
 import (
-	"log"
-
 	"github.com/cznic/mathutil"
 )
 
@@ -27,10 +25,12 @@ func (src UInt64) reduceOnce() UInt64 {
 	return value
 }
 
+// reduces value which may still exceed P after a single reduction
+// result is always consistent i.e. 0 <= res < P
 func (src UInt64) reduceOnceAssert() UInt64 {
 	var res = src.reduceOnce()
 	if res >= P {
-		log.Fatalf("Error: Expected result should be less than field size %d >= %d", res, P)
+		res -= P
 	}
 	return res
 }
@@ -0,0 +1,114 @@
+package logging
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/dev-appmonsters/dicemix-light-server/clock"
+)
+
+type redactorTestPair struct {
+	level   Level
+	session string
+	peer    string
+	subject string
+}
+
+var redactorTests = []redactorTestPair{
+	{Full, "42", "7", "ip:192.0.2.1"},
+	{"", "42", "7", "ip:192.0.2.1"},
+	{Minimal, "", "", ""},
+}
+
+func TestRedactor(t *testing.T) {
+	for _, pair := range redactorTests {
+		r, err := NewRedactor(pair.level)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if session, peer, subject := r.Session(42), r.Peer(42, 7), r.Subject("ip:192.0.2.1"); session != pair.session || peer != pair.peer || subject != pair.subject {
+			t.Error("For", pair.level, "expected", pair.session, pair.peer, pair.subject, "got", session, peer, subject)
+		}
+	}
+
+	if _, err := NewRedactor("verbose"); err == nil {
+		t.Error("For", "verbose", "expected", "error", "got", nil)
+	}
+}
+
+func TestPseudonymousRedactor(t *testing.T) {
+	r, _ := NewRedactor(Pseudonymous)
+
+	// pseudonyms are stable within run
+	peer := r.Peer(42, 7)
+	if len(peer) != 2*pseudonymSize || peer == "7" || r.Peer(42, 7) != peer {
+		t.Error("For", "peer", "expected", "stable pseudonym", "got", peer, r.Peer(42, 7))
+	}
+	if r.Peer(42, 8) == peer || r.Session(42) == peer {
+		t.Error("For", "other peer", "expected", "distinct pseudonym", "got", r.Peer(42, 8))
+	}
+
+	// but not linkable across runs
+	if r.Peer(43, 7) == peer {
+		t.Error("For", "other run", "expected", "distinct pseudonym", "got", peer)
+	}
+
+	// nor after run is over
+	r.Forget(42)
+	if r.Peer(42, 7) == peer {
+		t.Error("For", "forgotten run", "expected", "new pseudonym", "got", peer)
+	}
+
+	// other redactors use other keys
+	other, _ := NewRedactor(Pseudonymous)
+	if other.Subject("ip:192.0.2.1") == r.Subject("ip:192.0.2.1") {
+		t.Error("For", "other redactor", "expected", "distinct pseudonym", "got", r.Subject("ip:192.0.2.1"))
+	}
+}
+
+func TestRotatingFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logging")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "server.log")
+	c := clock.NewFakeClock(time.Date(2017, 7, 14, 12, 0, 0, 0, time.UTC))
+
+	// stale file of an earlier run of server
+	ioutil.WriteFile(path+".2017-07-01", []byte("old\n"), 0600)
+
+	w, err := NewRotatingFile(path, 48*time.Hour, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	for day := 0; day < 4; day++ {
+		w.Write([]byte("line\n"))
+		c.Advance(24 * time.Hour)
+	}
+	w.Write([]byte("line\n"))
+
+	// files of last 48 hours and current day are kept
+	paths, _ := filepath.Glob(path + ".*")
+	sort.Strings(paths)
+	expected := []string{path + ".2017-07-16", path + ".2017-07-17", path + ".2017-07-18"}
+	if len(paths) != len(expected) {
+		t.Fatal("For", "retention", "expected", expected, "got", paths)
+	}
+	for i := range expected {
+		if paths[i] != expected[i] {
+			t.Error("For", "retention", "expected", expected[i], "got", paths[i])
+		}
+	}
+
+	if data, _ := ioutil.ReadFile(path + ".2017-07-18"); string(data) != "line\n" {
+		t.Error("For", "current day", "expected", "line", "got", string(data))
+	}
+}
@@ -0,0 +1,158 @@
+package logging
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"sync"
+)
+
+// Level - how much of identity of peers is revealed by logs
+type Level string
+
+// levels of redaction
+const (
+	// Full - identifiers are logged as they are, for development
+	Full Level = "full"
+
+	// Pseudonymous - identifiers are replaced by HMACs under a key of their run
+	// keys are forgotten once run is over so pseudonyms can not be
+	// linked across runs or to identifiers after run is over
+	Pseudonymous Level = "pseudonymous"
+
+	// Minimal - no identifiers are logged, only counts
+	Minimal Level = "minimal"
+)
+
+// length of pseudonyms in bytes
+const pseudonymSize = 8
+
+// Redactor - The main interface for identifiers written to logs.
+// identifiers are redacted as per Level, empty means omit
+type Redactor interface {
+	Level() Level
+
+	// Session - identifier of run
+	Session(sessionID uint64) string
+
+	// Peer - id of peer in run, peers waiting in pools have sessionID 0
+	Peer(sessionID uint64, id int32) string
+
+	// Subject - identifier not bound to a run e.g. address or key
+	Subject(subject string) string
+
+	// Forget - discards key of run once it is over
+	Forget(sessionID uint64)
+}
+
+type fullRedactor struct {
+	Redactor
+}
+
+type pseudonymousRedactor struct {
+	keys map[uint64][]byte
+	sync.Mutex
+	Redactor
+}
+
+type minimalRedactor struct {
+	Redactor
+}
+
+// NewRedactor creates a new Redactor instance for level
+// Full is used if level is empty
+func NewRedactor(level Level) (Redactor, error) {
+	switch level {
+	case Full, "":
+		return &fullRedactor{}, nil
+	case Pseudonymous:
+		return &pseudonymousRedactor{keys: make(map[uint64][]byte)}, nil
+	case Minimal:
+		return &minimalRedactor{}, nil
+	}
+	return nil, errors.New("unknown log level " + string(level))
+}
+
+func (r *fullRedactor) Level() Level {
+	return Full
+}
+
+func (r *fullRedactor) Session(sessionID uint64) string {
+	return strconv.FormatUint(sessionID, 10)
+}
+
+func (r *fullRedactor) Peer(sessionID uint64, id int32) string {
+	return strconv.FormatInt(int64(id), 10)
+}
+
+func (r *fullRedactor) Subject(subject string) string {
+	return subject
+}
+
+func (r *fullRedactor) Forget(sessionID uint64) {}
+
+func (r *pseudonymousRedactor) Level() Level {
+	return Pseudonymous
+}
+
+func (r *pseudonymousRedactor) Session(sessionID uint64) string {
+	return r.pseudonym(sessionID, "session")
+}
+
+func (r *pseudonymousRedactor) Peer(sessionID uint64, id int32) string {
+	return r.pseudonym(sessionID, "peer:"+strconv.FormatInt(int64(id), 10))
+}
+
+// subjects are not bound to a run and share key of waiting peers
+func (r *pseudonymousRedactor) Subject(subject string) string {
+	return r.pseudonym(0, "subject:"+subject)
+}
+
+func (r *pseudonymousRedactor) Forget(sessionID uint64) {
+	r.Lock()
+	defer r.Unlock()
+
+	delete(r.keys, sessionID)
+}
+
+// returns HMAC of value under key of run
+// a random key is generated for run on first use
+// identifier is omitted if key can not be generated
+func (r *pseudonymousRedactor) pseudonym(sessionID uint64, value string) string {
+	r.Lock()
+	key, ok := r.keys[sessionID]
+	if !ok {
+		key = make([]byte, sha256.Size)
+		if _, err := rand.Read(key); err != nil {
+			r.Unlock()
+			return ""
+		}
+		r.keys[sessionID] = key
+	}
+	r.Unlock()
+
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil)[:pseudonymSize])
+}
+
+func (r *minimalRedactor) Level() Level {
+	return Minimal
+}
+
+func (r *minimalRedactor) Session(sessionID uint64) string {
+	return ""
+}
+
+func (r *minimalRedactor) Peer(sessionID uint64, id int32) string {
+	return ""
+}
+
+func (r *minimalRedactor) Subject(subject string) string {
+	return ""
+}
+
+func (r *minimalRedactor) Forget(sessionID uint64) {}
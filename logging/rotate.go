@@ -0,0 +1,99 @@
+package logging
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dev-appmonsters/dicemix-light-server/clock"
+)
+
+// layout of date appended to path of daily log files
+const dateLayout = "2006-01-02"
+
+type rotatingFile struct {
+	path      string
+	retention time.Duration
+	clock     clock.Clock
+	date      string
+	file      *os.File
+	sync.Mutex
+	io.WriteCloser
+}
+
+// NewRotatingFile creates a new io.WriteCloser which writes to a file
+// per day i.e. path.YYYY-MM-DD and removes files older than retention
+// files are kept forever if retention is 0
+func NewRotatingFile(path string, retention time.Duration, c clock.Clock) (io.WriteCloser, error) {
+	if c == nil {
+		c = clock.NewClock()
+	}
+
+	r := &rotatingFile{path: path, retention: retention, clock: c}
+	if err := r.rotate(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Write - appends p to file of current day
+func (r *rotatingFile) Write(p []byte) (int, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	if r.clock.Now().UTC().Format(dateLayout) != r.date {
+		if err := r.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	return r.file.Write(p)
+}
+
+// Close - closes file of current day
+func (r *rotatingFile) Close() error {
+	r.Lock()
+	defer r.Unlock()
+
+	return r.file.Close()
+}
+
+// opens file of current day and removes expired files
+func (r *rotatingFile) rotate() error {
+	now := r.clock.Now().UTC()
+	date := now.Format(dateLayout)
+
+	file, err := os.OpenFile(r.path+"."+date, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	if r.file != nil {
+		r.file.Close()
+	}
+	r.file, r.date = file, date
+
+	if r.retention > 0 {
+		r.purge(now.Add(-r.retention))
+	}
+	return nil
+}
+
+// removes files of days which have ended before expiry
+func (r *rotatingFile) purge(expiry time.Time) {
+	paths, err := filepath.Glob(r.path + ".*")
+	if err != nil {
+		return
+	}
+
+	for _, path := range paths {
+		day, err := time.Parse(dateLayout, strings.TrimPrefix(path, r.path+"."))
+		if err != nil {
+			continue
+		}
+		if day.Add(24 * time.Hour).Before(expiry) {
+			os.Remove(path)
+		}
+	}
+}
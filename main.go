@@ -3,13 +3,19 @@ package main
 import (
 	"flag"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 
+	"github.com/dev-appmonsters/dicemix-light-server/logging"
 	"github.com/dev-appmonsters/dicemix-light-server/server"
 
 	log "github.com/sirupsen/logrus"
 )
 
-var addr = flag.String("addr", ":8082", "http service address")
+var addr = flag.String("addr", ":8082", "http service address, empty to serve only via Tor")
+var adminAddr = flag.String("admin", "127.0.0.1:8083", "admin http service address, empty to disable")
+var configPath = flag.String("config", "", "path of JSON configuration file")
 
 func main() {
 	// setup logger
@@ -20,14 +26,118 @@ func main() {
 
 	flag.Parse()
 
-	connection := server.NewConnection()
+	config := server.DefaultConfig()
+	if *configPath != "" {
+		var err error
+		if config, err = server.LoadConfig(*configPath); err != nil {
+			log.Fatal("LoadConfig: ", err)
+		}
+	}
+
+	if err := config.Validate(); err != nil {
+		log.Fatal("Config: ", err)
+	}
+	if *adminAddr != "" {
+		if err := config.ValidateAdmin(*adminAddr); err != nil {
+			log.Fatal("Config: ", err)
+		}
+	}
+
+	// logs are kept in daily files only for retention period
+	if config.Logging.Path != "" {
+		output, err := logging.NewRotatingFile(config.Logging.Path, config.Logging.Retention(), nil)
+		if err != nil {
+			log.Fatal("NewRotatingFile: ", err)
+		}
+		log.SetOutput(output)
+	}
+
+	connection := server.NewConnection(config)
 
 	log.Info("Server Started")
 	http.HandleFunc("/ws", func(w http.ResponseWriter, r *http.Request) {
 		connection.Register(w, r)
 	})
+	http.HandleFunc("/pools", func(w http.ResponseWriter, r *http.Request) {
+		connection.PoolsInfo(w, r)
+	})
+	http.HandleFunc("/info", func(w http.ResponseWriter, r *http.Request) {
+		connection.Info(w, r)
+	})
+
+	// onion service is served on a separate listener
+	// to which local tor daemon forwards connections
+	if config.Tor.Enabled() {
+		listener, err := server.ListenTor(config.Tor)
+		if err != nil {
+			log.Fatal("ListenTor: ", err)
+		}
+
+		go func() {
+			log.Info("Tor Listener Started ", config.Tor.OnionAddress)
+			if err := http.Serve(listener, server.TorHandler(http.DefaultServeMux)); err != nil {
+				log.Fatal("Serve: ", err)
+			}
+		}()
+	}
+
+	var certificates server.Certificates
+	if config.TLS.Enabled() {
+		var err error
+		if certificates, err = server.NewCertificates(config.TLS); err != nil {
+			log.Fatal("NewCertificates: ", err)
+		}
+		go reloadCertificates(certificates)
+	}
+
+	// admin API is served on a separate listener
+	// over TLS if it can be reached by other hosts
+	if *adminAddr != "" {
+		go func() {
+			log.Info("Admin API Started")
+			if !config.AdminTLS(*adminAddr) {
+				if err := http.ListenAndServe(*adminAddr, connection.AdminHandler()); err != nil {
+					log.Fatal("ListenAndServe: ", err)
+				}
+				return
+			}
+
+			s := &http.Server{Addr: *adminAddr, Handler: connection.AdminHandler(), TLSConfig: certificates.TLSConfig()}
+			if err := s.ListenAndServeTLS("", ""); err != nil {
+				log.Fatal("ListenAndServeTLS: ", err)
+			}
+		}()
+	}
+
+	if *addr == "" {
+		if !config.Tor.Enabled() {
+			log.Fatal("No listener configured")
+		}
+		select {}
+	}
+
+	if !config.TLS.Enabled() {
+		if err := http.ListenAndServe(*addr, nil); err != nil {
+			log.Fatal("ListenAndServe: ", err)
+		}
+		return
+	}
+
+	s := &http.Server{Addr: *addr, TLSConfig: certificates.TLSConfig()}
+	if err := s.ListenAndServeTLS("", ""); err != nil {
+		log.Fatal("ListenAndServeTLS: ", err)
+	}
+}
+
+// reloads TLS certificates whenever SIGHUP is received
+// so that renewed certificates are used without restart
+func reloadCertificates(certificates server.Certificates) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
 
-	if err := http.ListenAndServe(*addr, nil); err != nil {
-		log.Fatal("ListenAndServe: ", err)
+	for range signals {
+		if err := certificates.Reload(); err != nil {
+			log.Error("Reload: ", err)
+		}
 	}
 }
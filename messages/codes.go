@@ -9,6 +9,8 @@ const (
 	C_SIMPLE_DC_VECTOR = 5
 	C_TX_CONFIRMATION  = 6
 	C_KESK_RESPONSE    = 7
+	C_LEAVE            = 8
+	C_QUEUE_STATUS     = 9
 )
 
 // constant Response Codes
@@ -20,4 +22,31 @@ const (
 	S_SIMPLE_DC_VECTOR = 105
 	S_TX_SUCCESSFUL    = 106
 	S_KESK_REQUEST     = 107
+	S_REQUEST_REJECTED = 108
+	S_RUN_TERMINATED   = 109
+	S_LEAVE_RESPONSE   = 110
+	S_QUEUE_STATUS     = 111
+)
+
+// constant Error Codes sent in ResponseHeader.ErrorCode
+// along with description in ResponseHeader.Err
+const (
+	E_MALFORMED_REQUEST   = 201
+	E_UNKNOWN_PEER        = 202
+	E_UNKNOWN_SESSION     = 203
+	E_INVALID_SIGNATURE   = 204
+	E_UNEXPECTED_REQUEST  = 205
+	E_DUPLICATE_REQUEST   = 206
+	E_INVALID_MSG_COUNT   = 207
+	E_INVALID_KEY         = 208
+	E_INVALID_VECTOR      = 209
+	E_INSUFFICIENT_FUNDS  = 210
+	E_UNKNOWN_POOL        = 211
+	E_BANNED              = 212
+	E_INTERNAL            = 213
+	E_UNSUPPORTED_VERSION = 214
+	E_UNAUTHORIZED        = 215
+	E_RATE_LIMITED        = 216
+	E_ADMISSION_REFUSED   = 217
+	E_SESSION_ABORTED     = 218
 )
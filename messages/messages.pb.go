@@ -32,7 +32,7 @@ func (m *RequestHeader) Reset()         { *m = RequestHeader{} }
 func (m *RequestHeader) String() string { return proto.CompactTextString(m) }
 func (*RequestHeader) ProtoMessage()    {}
 func (*RequestHeader) Descriptor() ([]byte, []int) {
-	return fileDescriptor_messages_ccb5dc8f6ef7098f, []int{0}
+	return fileDescriptor_messages_0afe2d4e18efa11a, []int{0}
 }
 func (m *RequestHeader) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_RequestHeader.Unmarshal(m, b)
@@ -94,7 +94,7 @@ func (m *GenericRequest) Reset()         { *m = GenericRequest{} }
 func (m *GenericRequest) String() string { return proto.CompactTextString(m) }
 func (*GenericRequest) ProtoMessage()    {}
 func (*GenericRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_messages_ccb5dc8f6ef7098f, []int{1}
+	return fileDescriptor_messages_0afe2d4e18efa11a, []int{1}
 }
 func (m *GenericRequest) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_GenericRequest.Unmarshal(m, b)
@@ -133,7 +133,7 @@ func (m *SignedRequest) Reset()         { *m = SignedRequest{} }
 func (m *SignedRequest) String() string { return proto.CompactTextString(m) }
 func (*SignedRequest) ProtoMessage()    {}
 func (*SignedRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_messages_ccb5dc8f6ef7098f, []int{2}
+	return fileDescriptor_messages_0afe2d4e18efa11a, []int{2}
 }
 func (m *SignedRequest) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_SignedRequest.Unmarshal(m, b)
@@ -167,6 +167,150 @@ func (m *SignedRequest) GetSignature() []byte {
 	return nil
 }
 
+// wire format parameters of a protocol version
+// unset fields are not constrained by peer
+type Features struct {
+	MessageLength        uint32   `protobuf:"varint,1,opt,name=MessageLength,proto3" json:"MessageLength,omitempty"`
+	HashFunction         string   `protobuf:"bytes,2,opt,name=HashFunction,proto3" json:"HashFunction,omitempty"`
+	TxMode               string   `protobuf:"bytes,3,opt,name=TxMode,proto3" json:"TxMode,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Features) Reset()         { *m = Features{} }
+func (m *Features) String() string { return proto.CompactTextString(m) }
+func (*Features) ProtoMessage()    {}
+func (*Features) Descriptor() ([]byte, []int) {
+	return fileDescriptor_messages_0afe2d4e18efa11a, []int{3}
+}
+func (m *Features) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Features.Unmarshal(m, b)
+}
+func (m *Features) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Features.Marshal(b, m, deterministic)
+}
+func (dst *Features) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Features.Merge(dst, src)
+}
+func (m *Features) XXX_Size() int {
+	return xxx_messageInfo_Features.Size(m)
+}
+func (m *Features) XXX_DiscardUnknown() {
+	xxx_messageInfo_Features.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Features proto.InternalMessageInfo
+
+func (m *Features) GetMessageLength() uint32 {
+	if m != nil {
+		return m.MessageLength
+	}
+	return 0
+}
+
+func (m *Features) GetHashFunction() string {
+	if m != nil {
+		return m.HashFunction
+	}
+	return ""
+}
+
+func (m *Features) GetTxMode() string {
+	if m != nil {
+		return m.TxMode
+	}
+	return ""
+}
+
+// for joining waiting queue of a pool
+// of fixed denomination and script type
+// first request sent after connecting, peers are assigned
+// an id and enqueued only after it has been validated
+// PoolId - default pool of server if empty
+// Versions - protocol versions supported by peer
+// Features - parameters expected by peer
+// AuthToken - required if server restricts access
+// Ticket - proof of work or blind token if server requires admission
+// Code - C_JOIN_REQUEST
+type JoinRequest struct {
+	Header               *RequestHeader `protobuf:"bytes,1,opt,name=Header,proto3" json:"Header,omitempty"`
+	PoolId               string         `protobuf:"bytes,2,opt,name=PoolId,proto3" json:"PoolId,omitempty"`
+	Versions             []uint32       `protobuf:"varint,3,rep,packed,name=Versions,proto3" json:"Versions,omitempty"`
+	Features             *Features      `protobuf:"bytes,4,opt,name=Features,proto3" json:"Features,omitempty"`
+	AuthToken            string         `protobuf:"bytes,5,opt,name=AuthToken,proto3" json:"AuthToken,omitempty"`
+	Ticket               []byte         `protobuf:"bytes,6,opt,name=Ticket,proto3" json:"Ticket,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}       `json:"-"`
+	XXX_unrecognized     []byte         `json:"-"`
+	XXX_sizecache        int32          `json:"-"`
+}
+
+func (m *JoinRequest) Reset()         { *m = JoinRequest{} }
+func (m *JoinRequest) String() string { return proto.CompactTextString(m) }
+func (*JoinRequest) ProtoMessage()    {}
+func (*JoinRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_messages_0afe2d4e18efa11a, []int{4}
+}
+func (m *JoinRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_JoinRequest.Unmarshal(m, b)
+}
+func (m *JoinRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_JoinRequest.Marshal(b, m, deterministic)
+}
+func (dst *JoinRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_JoinRequest.Merge(dst, src)
+}
+func (m *JoinRequest) XXX_Size() int {
+	return xxx_messageInfo_JoinRequest.Size(m)
+}
+func (m *JoinRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_JoinRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_JoinRequest proto.InternalMessageInfo
+
+func (m *JoinRequest) GetHeader() *RequestHeader {
+	if m != nil {
+		return m.Header
+	}
+	return nil
+}
+
+func (m *JoinRequest) GetPoolId() string {
+	if m != nil {
+		return m.PoolId
+	}
+	return ""
+}
+
+func (m *JoinRequest) GetVersions() []uint32 {
+	if m != nil {
+		return m.Versions
+	}
+	return nil
+}
+
+func (m *JoinRequest) GetFeatures() *Features {
+	if m != nil {
+		return m.Features
+	}
+	return nil
+}
+
+func (m *JoinRequest) GetAuthToken() string {
+	if m != nil {
+		return m.AuthToken
+	}
+	return ""
+}
+
+func (m *JoinRequest) GetTicket() []byte {
+	if m != nil {
+		return m.Ticket
+	}
+	return nil
+}
+
 // for broadcasting our LTPK
 // to initiate DiceMix Run
 // Code - C_LTPK_REQUEST
@@ -182,7 +326,7 @@ func (m *LtpkExchangeRequest) Reset()         { *m = LtpkExchangeRequest{} }
 func (m *LtpkExchangeRequest) String() string { return proto.CompactTextString(m) }
 func (*LtpkExchangeRequest) ProtoMessage()    {}
 func (*LtpkExchangeRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_messages_ccb5dc8f6ef7098f, []int{3}
+	return fileDescriptor_messages_0afe2d4e18efa11a, []int{5}
 }
 func (m *LtpkExchangeRequest) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_LtpkExchangeRequest.Unmarshal(m, b)
@@ -218,11 +362,16 @@ func (m *LtpkExchangeRequest) GetPublicKey() []byte {
 
 // For broadcasting our public key
 // to initiate KeyExchange
+// also declares inputs and change output
+// contributed by us to transaction
 // Code - C_KEY_EXCHANGE
 type KeyExchangeRequest struct {
 	Header               *RequestHeader `protobuf:"bytes,1,opt,name=Header,proto3" json:"Header,omitempty"`
 	PublicKey            []byte         `protobuf:"bytes,2,opt,name=PublicKey,proto3" json:"PublicKey,omitempty"`
 	NumMsgs              uint32         `protobuf:"varint,3,opt,name=NumMsgs,proto3" json:"NumMsgs,omitempty"`
+	Inputs               []*TxInput     `protobuf:"bytes,4,rep,name=Inputs,proto3" json:"Inputs,omitempty"`
+	ChangeScript         []byte         `protobuf:"bytes,5,opt,name=ChangeScript,proto3" json:"ChangeScript,omitempty"`
+	ChangeAmount         uint64         `protobuf:"varint,6,opt,name=ChangeAmount,proto3" json:"ChangeAmount,omitempty"`
 	XXX_NoUnkeyedLiteral struct{}       `json:"-"`
 	XXX_unrecognized     []byte         `json:"-"`
 	XXX_sizecache        int32          `json:"-"`
@@ -232,7 +381,7 @@ func (m *KeyExchangeRequest) Reset()         { *m = KeyExchangeRequest{} }
 func (m *KeyExchangeRequest) String() string { return proto.CompactTextString(m) }
 func (*KeyExchangeRequest) ProtoMessage()    {}
 func (*KeyExchangeRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_messages_ccb5dc8f6ef7098f, []int{4}
+	return fileDescriptor_messages_0afe2d4e18efa11a, []int{6}
 }
 func (m *KeyExchangeRequest) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_KeyExchangeRequest.Unmarshal(m, b)
@@ -273,6 +422,27 @@ func (m *KeyExchangeRequest) GetNumMsgs() uint32 {
 	return 0
 }
 
+func (m *KeyExchangeRequest) GetInputs() []*TxInput {
+	if m != nil {
+		return m.Inputs
+	}
+	return nil
+}
+
+func (m *KeyExchangeRequest) GetChangeScript() []byte {
+	if m != nil {
+		return m.ChangeScript
+	}
+	return nil
+}
+
+func (m *KeyExchangeRequest) GetChangeAmount() uint64 {
+	if m != nil {
+		return m.ChangeAmount
+	}
+	return 0
+}
+
 // For broadcasting our DC Exponential Vector
 // to initiate DC-EXP
 // Code - C_EXP_DC_VECTOR
@@ -288,7 +458,7 @@ func (m *DCExpRequest) Reset()         { *m = DCExpRequest{} }
 func (m *DCExpRequest) String() string { return proto.CompactTextString(m) }
 func (*DCExpRequest) ProtoMessage()    {}
 func (*DCExpRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_messages_ccb5dc8f6ef7098f, []int{5}
+	return fileDescriptor_messages_0afe2d4e18efa11a, []int{7}
 }
 func (m *DCExpRequest) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_DCExpRequest.Unmarshal(m, b)
@@ -339,7 +509,7 @@ func (m *DCSimpleRequest) Reset()         { *m = DCSimpleRequest{} }
 func (m *DCSimpleRequest) String() string { return proto.CompactTextString(m) }
 func (*DCSimpleRequest) ProtoMessage()    {}
 func (*DCSimpleRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_messages_ccb5dc8f6ef7098f, []int{6}
+	return fileDescriptor_messages_0afe2d4e18efa11a, []int{8}
 }
 func (m *DCSimpleRequest) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_DCSimpleRequest.Unmarshal(m, b)
@@ -401,7 +571,7 @@ func (m *ConfirmationRequest) Reset()         { *m = ConfirmationRequest{} }
 func (m *ConfirmationRequest) String() string { return proto.CompactTextString(m) }
 func (*ConfirmationRequest) ProtoMessage()    {}
 func (*ConfirmationRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_messages_ccb5dc8f6ef7098f, []int{7}
+	return fileDescriptor_messages_0afe2d4e18efa11a, []int{9}
 }
 func (m *ConfirmationRequest) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_ConfirmationRequest.Unmarshal(m, b)
@@ -449,7 +619,7 @@ func (m *InitiaiteKESKResponse) Reset()         { *m = InitiaiteKESKResponse{} }
 func (m *InitiaiteKESKResponse) String() string { return proto.CompactTextString(m) }
 func (*InitiaiteKESKResponse) ProtoMessage()    {}
 func (*InitiaiteKESKResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_messages_ccb5dc8f6ef7098f, []int{8}
+	return fileDescriptor_messages_0afe2d4e18efa11a, []int{10}
 }
 func (m *InitiaiteKESKResponse) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_InitiaiteKESKResponse.Unmarshal(m, b)
@@ -483,12 +653,94 @@ func (m *InitiaiteKESKResponse) GetPrivateKey() []byte {
 	return nil
 }
 
+// for leaving waiting queue before a run is started
+// Code - C_LEAVE
+type LeaveRequest struct {
+	Header               *RequestHeader `protobuf:"bytes,1,opt,name=Header,proto3" json:"Header,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}       `json:"-"`
+	XXX_unrecognized     []byte         `json:"-"`
+	XXX_sizecache        int32          `json:"-"`
+}
+
+func (m *LeaveRequest) Reset()         { *m = LeaveRequest{} }
+func (m *LeaveRequest) String() string { return proto.CompactTextString(m) }
+func (*LeaveRequest) ProtoMessage()    {}
+func (*LeaveRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_messages_0afe2d4e18efa11a, []int{11}
+}
+func (m *LeaveRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_LeaveRequest.Unmarshal(m, b)
+}
+func (m *LeaveRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_LeaveRequest.Marshal(b, m, deterministic)
+}
+func (dst *LeaveRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_LeaveRequest.Merge(dst, src)
+}
+func (m *LeaveRequest) XXX_Size() int {
+	return xxx_messageInfo_LeaveRequest.Size(m)
+}
+func (m *LeaveRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_LeaveRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_LeaveRequest proto.InternalMessageInfo
+
+func (m *LeaveRequest) GetHeader() *RequestHeader {
+	if m != nil {
+		return m.Header
+	}
+	return nil
+}
+
+// for obtaining our position in waiting queue
+// Code - C_QUEUE_STATUS
+type QueueStatusRequest struct {
+	Header               *RequestHeader `protobuf:"bytes,1,opt,name=Header,proto3" json:"Header,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}       `json:"-"`
+	XXX_unrecognized     []byte         `json:"-"`
+	XXX_sizecache        int32          `json:"-"`
+}
+
+func (m *QueueStatusRequest) Reset()         { *m = QueueStatusRequest{} }
+func (m *QueueStatusRequest) String() string { return proto.CompactTextString(m) }
+func (*QueueStatusRequest) ProtoMessage()    {}
+func (*QueueStatusRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_messages_0afe2d4e18efa11a, []int{12}
+}
+func (m *QueueStatusRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_QueueStatusRequest.Unmarshal(m, b)
+}
+func (m *QueueStatusRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_QueueStatusRequest.Marshal(b, m, deterministic)
+}
+func (dst *QueueStatusRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_QueueStatusRequest.Merge(dst, src)
+}
+func (m *QueueStatusRequest) XXX_Size() int {
+	return xxx_messageInfo_QueueStatusRequest.Size(m)
+}
+func (m *QueueStatusRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_QueueStatusRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_QueueStatusRequest proto.InternalMessageInfo
+
+func (m *QueueStatusRequest) GetHeader() *RequestHeader {
+	if m != nil {
+		return m.Header
+	}
+	return nil
+}
+
 type ResponseHeader struct {
-	Code                 uint32   `protobuf:"varint,1,opt,name=Code,proto3" json:"Code,omitempty"`
-	SessionId            uint64   `protobuf:"varint,2,opt,name=SessionId,proto3" json:"SessionId,omitempty"`
-	Timestamp            string   `protobuf:"bytes,3,opt,name=Timestamp,proto3" json:"Timestamp,omitempty"`
-	Message              string   `protobuf:"bytes,4,opt,name=Message,proto3" json:"Message,omitempty"`
-	Err                  string   `protobuf:"bytes,5,opt,name=Err,proto3" json:"Err,omitempty"`
+	Code      uint32 `protobuf:"varint,1,opt,name=Code,proto3" json:"Code,omitempty"`
+	SessionId uint64 `protobuf:"varint,2,opt,name=SessionId,proto3" json:"SessionId,omitempty"`
+	Timestamp string `protobuf:"bytes,3,opt,name=Timestamp,proto3" json:"Timestamp,omitempty"`
+	Message   string `protobuf:"bytes,4,opt,name=Message,proto3" json:"Message,omitempty"`
+	Err       string `protobuf:"bytes,5,opt,name=Err,proto3" json:"Err,omitempty"`
+	// one of error codes in codes.go if Err is set
+	ErrorCode            uint32   `protobuf:"varint,6,opt,name=ErrorCode,proto3" json:"ErrorCode,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -498,7 +750,7 @@ func (m *ResponseHeader) Reset()         { *m = ResponseHeader{} }
 func (m *ResponseHeader) String() string { return proto.CompactTextString(m) }
 func (*ResponseHeader) ProtoMessage()    {}
 func (*ResponseHeader) Descriptor() ([]byte, []int) {
-	return fileDescriptor_messages_ccb5dc8f6ef7098f, []int{9}
+	return fileDescriptor_messages_0afe2d4e18efa11a, []int{13}
 }
 func (m *ResponseHeader) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_ResponseHeader.Unmarshal(m, b)
@@ -553,6 +805,13 @@ func (m *ResponseHeader) GetErr() string {
 	return ""
 }
 
+func (m *ResponseHeader) GetErrorCode() uint32 {
+	if m != nil {
+		return m.ErrorCode
+	}
+	return 0
+}
+
 // for obtaining Status Code from response messages from server
 // to parse response into suitable object
 type GenericResponse struct {
@@ -566,7 +825,7 @@ func (m *GenericResponse) Reset()         { *m = GenericResponse{} }
 func (m *GenericResponse) String() string { return proto.CompactTextString(m) }
 func (*GenericResponse) ProtoMessage()    {}
 func (*GenericResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_messages_ccb5dc8f6ef7098f, []int{10}
+	return fileDescriptor_messages_0afe2d4e18efa11a, []int{14}
 }
 func (m *GenericResponse) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_GenericResponse.Unmarshal(m, b)
@@ -596,18 +855,22 @@ func (m *GenericResponse) GetHeader() *ResponseHeader {
 // Response returned by server when attempt to join dicemix
 // S_JOIN_RESPONSE
 type RegisterResponse struct {
-	Header               *ResponseHeader `protobuf:"bytes,1,opt,name=Header,proto3" json:"Header,omitempty"`
-	Id                   int32           `protobuf:"zigzag32,2,opt,name=Id,proto3" json:"Id,omitempty"`
-	XXX_NoUnkeyedLiteral struct{}        `json:"-"`
-	XXX_unrecognized     []byte          `json:"-"`
-	XXX_sizecache        int32           `json:"-"`
+	Header *ResponseHeader `protobuf:"bytes,1,opt,name=Header,proto3" json:"Header,omitempty"`
+	Id     int32           `protobuf:"zigzag32,2,opt,name=Id,proto3" json:"Id,omitempty"`
+	PoolId string          `protobuf:"bytes,3,opt,name=PoolId,proto3" json:"PoolId,omitempty"`
+	// protocol version selected by server and its features
+	Version              uint32    `protobuf:"varint,4,opt,name=Version,proto3" json:"Version,omitempty"`
+	Features             *Features `protobuf:"bytes,5,opt,name=Features,proto3" json:"Features,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}  `json:"-"`
+	XXX_unrecognized     []byte    `json:"-"`
+	XXX_sizecache        int32     `json:"-"`
 }
 
 func (m *RegisterResponse) Reset()         { *m = RegisterResponse{} }
 func (m *RegisterResponse) String() string { return proto.CompactTextString(m) }
 func (*RegisterResponse) ProtoMessage()    {}
 func (*RegisterResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_messages_ccb5dc8f6ef7098f, []int{11}
+	return fileDescriptor_messages_0afe2d4e18efa11a, []int{15}
 }
 func (m *RegisterResponse) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_RegisterResponse.Unmarshal(m, b)
@@ -641,6 +904,27 @@ func (m *RegisterResponse) GetId() int32 {
 	return 0
 }
 
+func (m *RegisterResponse) GetPoolId() string {
+	if m != nil {
+		return m.PoolId
+	}
+	return ""
+}
+
+func (m *RegisterResponse) GetVersion() uint32 {
+	if m != nil {
+		return m.Version
+	}
+	return 0
+}
+
+func (m *RegisterResponse) GetFeatures() *Features {
+	if m != nil {
+		return m.Features
+	}
+	return nil
+}
+
 // Response returned by server for -
 // StartDiceMix - Code S_START_DICEMIX
 // KeyExchangeResponse - Code S_KEY_EXCHANGE
@@ -649,6 +933,7 @@ func (m *RegisterResponse) GetId() int32 {
 type DiceMixResponse struct {
 	Header               *ResponseHeader `protobuf:"bytes,1,opt,name=Header,proto3" json:"Header,omitempty"`
 	Peers                []*PeersInfo    `protobuf:"bytes,2,rep,name=Peers,proto3" json:"Peers,omitempty"`
+	FeeTerms             *FeeTerms       `protobuf:"bytes,3,opt,name=FeeTerms,proto3" json:"FeeTerms,omitempty"`
 	XXX_NoUnkeyedLiteral struct{}        `json:"-"`
 	XXX_unrecognized     []byte          `json:"-"`
 	XXX_sizecache        int32           `json:"-"`
@@ -658,7 +943,7 @@ func (m *DiceMixResponse) Reset()         { *m = DiceMixResponse{} }
 func (m *DiceMixResponse) String() string { return proto.CompactTextString(m) }
 func (*DiceMixResponse) ProtoMessage()    {}
 func (*DiceMixResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_messages_ccb5dc8f6ef7098f, []int{12}
+	return fileDescriptor_messages_0afe2d4e18efa11a, []int{16}
 }
 func (m *DiceMixResponse) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_DiceMixResponse.Unmarshal(m, b)
@@ -692,6 +977,13 @@ func (m *DiceMixResponse) GetPeers() []*PeersInfo {
 	return nil
 }
 
+func (m *DiceMixResponse) GetFeeTerms() *FeeTerms {
+	if m != nil {
+		return m.FeeTerms
+	}
+	return nil
+}
+
 // Response against DCExpRequest
 // conatins ROOTS calculated by server using FLINT
 // Code - S_EXP_DC_VECTOR
@@ -707,7 +999,7 @@ func (m *DCExpResponse) Reset()         { *m = DCExpResponse{} }
 func (m *DCExpResponse) String() string { return proto.CompactTextString(m) }
 func (*DCExpResponse) ProtoMessage()    {}
 func (*DCExpResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_messages_ccb5dc8f6ef7098f, []int{13}
+	return fileDescriptor_messages_0afe2d4e18efa11a, []int{17}
 }
 func (m *DCExpResponse) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_DCExpResponse.Unmarshal(m, b)
@@ -743,11 +1035,13 @@ func (m *DCExpResponse) GetRoots() []uint64 {
 
 // Response against DCSimpleResponse
 // conatins messages resolved via DC-SIMPLE vectors
+// and transaction (PSBT) assembled from them
 // Code - S_SIMPLE_DC_VECTOR
 type DCSimpleResponse struct {
 	Header               *ResponseHeader `protobuf:"bytes,1,opt,name=Header,proto3" json:"Header,omitempty"`
 	Messages             [][]byte        `protobuf:"bytes,2,rep,name=Messages,proto3" json:"Messages,omitempty"`
 	Peers                []*PeersInfo    `protobuf:"bytes,3,rep,name=Peers,proto3" json:"Peers,omitempty"`
+	Transaction          []byte          `protobuf:"bytes,4,opt,name=Transaction,proto3" json:"Transaction,omitempty"`
 	XXX_NoUnkeyedLiteral struct{}        `json:"-"`
 	XXX_unrecognized     []byte          `json:"-"`
 	XXX_sizecache        int32           `json:"-"`
@@ -757,7 +1051,7 @@ func (m *DCSimpleResponse) Reset()         { *m = DCSimpleResponse{} }
 func (m *DCSimpleResponse) String() string { return proto.CompactTextString(m) }
 func (*DCSimpleResponse) ProtoMessage()    {}
 func (*DCSimpleResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_messages_ccb5dc8f6ef7098f, []int{14}
+	return fileDescriptor_messages_0afe2d4e18efa11a, []int{18}
 }
 func (m *DCSimpleResponse) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_DCSimpleResponse.Unmarshal(m, b)
@@ -798,6 +1092,13 @@ func (m *DCSimpleResponse) GetPeers() []*PeersInfo {
 	return nil
 }
 
+func (m *DCSimpleResponse) GetTransaction() []byte {
+	if m != nil {
+		return m.Transaction
+	}
+	return nil
+}
+
 // Possible response against ConfirmationRequest
 // only when all peers send valid confirmations to server
 // Code - S_TX_SUCCESSFUL
@@ -812,7 +1113,7 @@ func (m *TXDoneResponse) Reset()         { *m = TXDoneResponse{} }
 func (m *TXDoneResponse) String() string { return proto.CompactTextString(m) }
 func (*TXDoneResponse) ProtoMessage()    {}
 func (*TXDoneResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_messages_ccb5dc8f6ef7098f, []int{15}
+	return fileDescriptor_messages_0afe2d4e18efa11a, []int{19}
 }
 func (m *TXDoneResponse) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_TXDoneResponse.Unmarshal(m, b)
@@ -852,7 +1153,7 @@ func (m *InitiaiteKESK) Reset()         { *m = InitiaiteKESK{} }
 func (m *InitiaiteKESK) String() string { return proto.CompactTextString(m) }
 func (*InitiaiteKESK) ProtoMessage()    {}
 func (*InitiaiteKESK) Descriptor() ([]byte, []int) {
-	return fileDescriptor_messages_ccb5dc8f6ef7098f, []int{16}
+	return fileDescriptor_messages_0afe2d4e18efa11a, []int{20}
 }
 func (m *InitiaiteKESK) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_InitiaiteKESK.Unmarshal(m, b)
@@ -879,30 +1180,125 @@ func (m *InitiaiteKESK) GetHeader() *ResponseHeader {
 	return nil
 }
 
+// Response against QueueStatusRequest
+// Position - 1 based position of peer in waiting queue
+// ReadyPeers - peers in queue which have sent their long term public key
+// EstimatedStart - unix time at which run is expected to start
+// 0 if it can not be estimated yet
+// Code - S_QUEUE_STATUS
+type QueueStatusResponse struct {
+	Header               *ResponseHeader `protobuf:"bytes,1,opt,name=Header,proto3" json:"Header,omitempty"`
+	PoolId               string          `protobuf:"bytes,2,opt,name=PoolId,proto3" json:"PoolId,omitempty"`
+	Position             uint32          `protobuf:"varint,3,opt,name=Position,proto3" json:"Position,omitempty"`
+	QueueDepth           uint32          `protobuf:"varint,4,opt,name=QueueDepth,proto3" json:"QueueDepth,omitempty"`
+	ReadyPeers           uint32          `protobuf:"varint,5,opt,name=ReadyPeers,proto3" json:"ReadyPeers,omitempty"`
+	MinPeers             uint32          `protobuf:"varint,6,opt,name=MinPeers,proto3" json:"MinPeers,omitempty"`
+	EstimatedStart       int64           `protobuf:"varint,7,opt,name=EstimatedStart,proto3" json:"EstimatedStart,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}        `json:"-"`
+	XXX_unrecognized     []byte          `json:"-"`
+	XXX_sizecache        int32           `json:"-"`
+}
+
+func (m *QueueStatusResponse) Reset()         { *m = QueueStatusResponse{} }
+func (m *QueueStatusResponse) String() string { return proto.CompactTextString(m) }
+func (*QueueStatusResponse) ProtoMessage()    {}
+func (*QueueStatusResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_messages_0afe2d4e18efa11a, []int{21}
+}
+func (m *QueueStatusResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_QueueStatusResponse.Unmarshal(m, b)
+}
+func (m *QueueStatusResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_QueueStatusResponse.Marshal(b, m, deterministic)
+}
+func (dst *QueueStatusResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_QueueStatusResponse.Merge(dst, src)
+}
+func (m *QueueStatusResponse) XXX_Size() int {
+	return xxx_messageInfo_QueueStatusResponse.Size(m)
+}
+func (m *QueueStatusResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_QueueStatusResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_QueueStatusResponse proto.InternalMessageInfo
+
+func (m *QueueStatusResponse) GetHeader() *ResponseHeader {
+	if m != nil {
+		return m.Header
+	}
+	return nil
+}
+
+func (m *QueueStatusResponse) GetPoolId() string {
+	if m != nil {
+		return m.PoolId
+	}
+	return ""
+}
+
+func (m *QueueStatusResponse) GetPosition() uint32 {
+	if m != nil {
+		return m.Position
+	}
+	return 0
+}
+
+func (m *QueueStatusResponse) GetQueueDepth() uint32 {
+	if m != nil {
+		return m.QueueDepth
+	}
+	return 0
+}
+
+func (m *QueueStatusResponse) GetReadyPeers() uint32 {
+	if m != nil {
+		return m.ReadyPeers
+	}
+	return 0
+}
+
+func (m *QueueStatusResponse) GetMinPeers() uint32 {
+	if m != nil {
+		return m.MinPeers
+	}
+	return 0
+}
+
+func (m *QueueStatusResponse) GetEstimatedStart() int64 {
+	if m != nil {
+		return m.EstimatedStart
+	}
+	return 0
+}
+
 // Sub-message for DiceMixResponse
 type PeersInfo struct {
-	Id                   int32    `protobuf:"varint,1,opt,name=Id,proto3" json:"Id,omitempty"`
-	LTPublicKey          []byte   `protobuf:"bytes,2,opt,name=LTPublicKey,proto3" json:"LTPublicKey,omitempty"`
-	PublicKey            []byte   `protobuf:"bytes,3,opt,name=PublicKey,proto3" json:"PublicKey,omitempty"`
-	PrivateKey           []byte   `protobuf:"bytes,4,opt,name=PrivateKey,proto3" json:"PrivateKey,omitempty"`
-	NextPublicKey        []byte   `protobuf:"bytes,5,opt,name=NextPublicKey,proto3" json:"NextPublicKey,omitempty"`
-	NumMsgs              uint32   `protobuf:"varint,6,opt,name=NumMsgs,proto3" json:"NumMsgs,omitempty"`
-	DCVector             []uint64 `protobuf:"varint,7,rep,packed,name=DCVector,proto3" json:"DCVector,omitempty"`
-	DCSimpleVector       [][]byte `protobuf:"bytes,8,rep,name=DCSimpleVector,proto3" json:"DCSimpleVector,omitempty"`
-	OK                   bool     `protobuf:"varint,9,opt,name=OK,proto3" json:"OK,omitempty"`
-	Messages             [][]byte `protobuf:"bytes,10,rep,name=Messages,proto3" json:"Messages,omitempty"`
-	Confirmation         bool     `protobuf:"varint,11,opt,name=Confirmation,proto3" json:"Confirmation,omitempty"`
-	MessageReceived      bool     `protobuf:"varint,12,opt,name=MessageReceived,proto3" json:"MessageReceived,omitempty"`
-	XXX_NoUnkeyedLiteral struct{} `json:"-"`
-	XXX_unrecognized     []byte   `json:"-"`
-	XXX_sizecache        int32    `json:"-"`
+	Id                   int32      `protobuf:"varint,1,opt,name=Id,proto3" json:"Id,omitempty"`
+	LTPublicKey          []byte     `protobuf:"bytes,2,opt,name=LTPublicKey,proto3" json:"LTPublicKey,omitempty"`
+	PublicKey            []byte     `protobuf:"bytes,3,opt,name=PublicKey,proto3" json:"PublicKey,omitempty"`
+	PrivateKey           []byte     `protobuf:"bytes,4,opt,name=PrivateKey,proto3" json:"PrivateKey,omitempty"`
+	NextPublicKey        []byte     `protobuf:"bytes,5,opt,name=NextPublicKey,proto3" json:"NextPublicKey,omitempty"`
+	NumMsgs              uint32     `protobuf:"varint,6,opt,name=NumMsgs,proto3" json:"NumMsgs,omitempty"`
+	DCVector             []uint64   `protobuf:"varint,7,rep,packed,name=DCVector,proto3" json:"DCVector,omitempty"`
+	DCSimpleVector       [][]byte   `protobuf:"bytes,8,rep,name=DCSimpleVector,proto3" json:"DCSimpleVector,omitempty"`
+	OK                   bool       `protobuf:"varint,9,opt,name=OK,proto3" json:"OK,omitempty"`
+	Messages             [][]byte   `protobuf:"bytes,10,rep,name=Messages,proto3" json:"Messages,omitempty"`
+	Confirmation         bool       `protobuf:"varint,11,opt,name=Confirmation,proto3" json:"Confirmation,omitempty"`
+	MessageReceived      bool       `protobuf:"varint,12,opt,name=MessageReceived,proto3" json:"MessageReceived,omitempty"`
+	Inputs               []*TxInput `protobuf:"bytes,13,rep,name=Inputs,proto3" json:"Inputs,omitempty"`
+	ChangeScript         []byte     `protobuf:"bytes,14,opt,name=ChangeScript,proto3" json:"ChangeScript,omitempty"`
+	ChangeAmount         uint64     `protobuf:"varint,15,opt,name=ChangeAmount,proto3" json:"ChangeAmount,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}   `json:"-"`
+	XXX_unrecognized     []byte     `json:"-"`
+	XXX_sizecache        int32      `json:"-"`
 }
 
 func (m *PeersInfo) Reset()         { *m = PeersInfo{} }
 func (m *PeersInfo) String() string { return proto.CompactTextString(m) }
 func (*PeersInfo) ProtoMessage()    {}
 func (*PeersInfo) Descriptor() ([]byte, []int) {
-	return fileDescriptor_messages_ccb5dc8f6ef7098f, []int{17}
+	return fileDescriptor_messages_0afe2d4e18efa11a, []int{22}
 }
 func (m *PeersInfo) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_PeersInfo.Unmarshal(m, b)
@@ -1006,16 +1402,187 @@ func (m *PeersInfo) GetMessageReceived() bool {
 	return false
 }
 
+func (m *PeersInfo) GetInputs() []*TxInput {
+	if m != nil {
+		return m.Inputs
+	}
+	return nil
+}
+
+func (m *PeersInfo) GetChangeScript() []byte {
+	if m != nil {
+		return m.ChangeScript
+	}
+	return nil
+}
+
+func (m *PeersInfo) GetChangeAmount() uint64 {
+	if m != nil {
+		return m.ChangeAmount
+	}
+	return 0
+}
+
+// Sub-message for KeyExchangeRequest
+// an unspent output spent by peer in transaction
+type TxInput struct {
+	TxHash               []byte   `protobuf:"bytes,1,opt,name=TxHash,proto3" json:"TxHash,omitempty"`
+	Index                uint32   `protobuf:"varint,2,opt,name=Index,proto3" json:"Index,omitempty"`
+	Amount               uint64   `protobuf:"varint,3,opt,name=Amount,proto3" json:"Amount,omitempty"`
+	PkScript             []byte   `protobuf:"bytes,4,opt,name=PkScript,proto3" json:"PkScript,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *TxInput) Reset()         { *m = TxInput{} }
+func (m *TxInput) String() string { return proto.CompactTextString(m) }
+func (*TxInput) ProtoMessage()    {}
+func (*TxInput) Descriptor() ([]byte, []int) {
+	return fileDescriptor_messages_0afe2d4e18efa11a, []int{23}
+}
+func (m *TxInput) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_TxInput.Unmarshal(m, b)
+}
+func (m *TxInput) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_TxInput.Marshal(b, m, deterministic)
+}
+func (dst *TxInput) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_TxInput.Merge(dst, src)
+}
+func (m *TxInput) XXX_Size() int {
+	return xxx_messageInfo_TxInput.Size(m)
+}
+func (m *TxInput) XXX_DiscardUnknown() {
+	xxx_messageInfo_TxInput.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_TxInput proto.InternalMessageInfo
+
+func (m *TxInput) GetTxHash() []byte {
+	if m != nil {
+		return m.TxHash
+	}
+	return nil
+}
+
+func (m *TxInput) GetIndex() uint32 {
+	if m != nil {
+		return m.Index
+	}
+	return 0
+}
+
+func (m *TxInput) GetAmount() uint64 {
+	if m != nil {
+		return m.Amount
+	}
+	return 0
+}
+
+func (m *TxInput) GetPkScript() []byte {
+	if m != nil {
+		return m.PkScript
+	}
+	return nil
+}
+
+// Sub-message for DiceMixResponse
+// terms announced to peers at start of a run
+// FeeRate - miner fee rate in satoshis per virtual byte
+// so that they can refuse unacceptable fees before KE
+type FeeTerms struct {
+	Denomination              uint64   `protobuf:"varint,1,opt,name=Denomination,proto3" json:"Denomination,omitempty"`
+	ScriptType                string   `protobuf:"bytes,2,opt,name=ScriptType,proto3" json:"ScriptType,omitempty"`
+	CoordinatorFeeFlat        uint64   `protobuf:"varint,3,opt,name=CoordinatorFeeFlat,proto3" json:"CoordinatorFeeFlat,omitempty"`
+	CoordinatorFeeBasisPoints uint32   `protobuf:"varint,4,opt,name=CoordinatorFeeBasisPoints,proto3" json:"CoordinatorFeeBasisPoints,omitempty"`
+	CoordinatorFeeAddress     string   `protobuf:"bytes,5,opt,name=CoordinatorFeeAddress,proto3" json:"CoordinatorFeeAddress,omitempty"`
+	FeeRate                   uint64   `protobuf:"varint,6,opt,name=FeeRate,proto3" json:"FeeRate,omitempty"`
+	XXX_NoUnkeyedLiteral      struct{} `json:"-"`
+	XXX_unrecognized          []byte   `json:"-"`
+	XXX_sizecache             int32    `json:"-"`
+}
+
+func (m *FeeTerms) Reset()         { *m = FeeTerms{} }
+func (m *FeeTerms) String() string { return proto.CompactTextString(m) }
+func (*FeeTerms) ProtoMessage()    {}
+func (*FeeTerms) Descriptor() ([]byte, []int) {
+	return fileDescriptor_messages_0afe2d4e18efa11a, []int{24}
+}
+func (m *FeeTerms) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_FeeTerms.Unmarshal(m, b)
+}
+func (m *FeeTerms) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_FeeTerms.Marshal(b, m, deterministic)
+}
+func (dst *FeeTerms) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_FeeTerms.Merge(dst, src)
+}
+func (m *FeeTerms) XXX_Size() int {
+	return xxx_messageInfo_FeeTerms.Size(m)
+}
+func (m *FeeTerms) XXX_DiscardUnknown() {
+	xxx_messageInfo_FeeTerms.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_FeeTerms proto.InternalMessageInfo
+
+func (m *FeeTerms) GetDenomination() uint64 {
+	if m != nil {
+		return m.Denomination
+	}
+	return 0
+}
+
+func (m *FeeTerms) GetScriptType() string {
+	if m != nil {
+		return m.ScriptType
+	}
+	return ""
+}
+
+func (m *FeeTerms) GetCoordinatorFeeFlat() uint64 {
+	if m != nil {
+		return m.CoordinatorFeeFlat
+	}
+	return 0
+}
+
+func (m *FeeTerms) GetCoordinatorFeeBasisPoints() uint32 {
+	if m != nil {
+		return m.CoordinatorFeeBasisPoints
+	}
+	return 0
+}
+
+func (m *FeeTerms) GetCoordinatorFeeAddress() string {
+	if m != nil {
+		return m.CoordinatorFeeAddress
+	}
+	return ""
+}
+
+func (m *FeeTerms) GetFeeRate() uint64 {
+	if m != nil {
+		return m.FeeRate
+	}
+	return 0
+}
+
 func init() {
 	proto.RegisterType((*RequestHeader)(nil), "messages.RequestHeader")
 	proto.RegisterType((*GenericRequest)(nil), "messages.GenericRequest")
 	proto.RegisterType((*SignedRequest)(nil), "messages.SignedRequest")
+	proto.RegisterType((*Features)(nil), "messages.Features")
+	proto.RegisterType((*JoinRequest)(nil), "messages.JoinRequest")
 	proto.RegisterType((*LtpkExchangeRequest)(nil), "messages.LtpkExchangeRequest")
 	proto.RegisterType((*KeyExchangeRequest)(nil), "messages.KeyExchangeRequest")
 	proto.RegisterType((*DCExpRequest)(nil), "messages.DCExpRequest")
 	proto.RegisterType((*DCSimpleRequest)(nil), "messages.DCSimpleRequest")
 	proto.RegisterType((*ConfirmationRequest)(nil), "messages.ConfirmationRequest")
 	proto.RegisterType((*InitiaiteKESKResponse)(nil), "messages.InitiaiteKESKResponse")
+	proto.RegisterType((*LeaveRequest)(nil), "messages.LeaveRequest")
+	proto.RegisterType((*QueueStatusRequest)(nil), "messages.QueueStatusRequest")
 	proto.RegisterType((*ResponseHeader)(nil), "messages.ResponseHeader")
 	proto.RegisterType((*GenericResponse)(nil), "messages.GenericResponse")
 	proto.RegisterType((*RegisterResponse)(nil), "messages.RegisterResponse")
@@ -1024,53 +1591,87 @@ func init() {
 	proto.RegisterType((*DCSimpleResponse)(nil), "messages.DCSimpleResponse")
 	proto.RegisterType((*TXDoneResponse)(nil), "messages.TXDoneResponse")
 	proto.RegisterType((*InitiaiteKESK)(nil), "messages.InitiaiteKESK")
+	proto.RegisterType((*QueueStatusResponse)(nil), "messages.QueueStatusResponse")
 	proto.RegisterType((*PeersInfo)(nil), "messages.PeersInfo")
-}
-
-func init() { proto.RegisterFile("messages/messages.proto", fileDescriptor_messages_ccb5dc8f6ef7098f) }
-
-var fileDescriptor_messages_ccb5dc8f6ef7098f = []byte{
-	// 668 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xb4, 0x56, 0x5d, 0x6f, 0x12, 0x41,
-	0x14, 0xcd, 0x2e, 0xd0, 0xc2, 0x85, 0x85, 0xba, 0xd5, 0x74, 0x63, 0x8c, 0xd9, 0x4c, 0x8c, 0xc1,
-	0x97, 0xd6, 0xd4, 0x5f, 0x50, 0x81, 0x28, 0xa1, 0x94, 0x66, 0x20, 0xea, 0xeb, 0x96, 0xbd, 0xa5,
-	0x63, 0xcb, 0x0e, 0xee, 0x0c, 0x0d, 0x7d, 0xf0, 0x17, 0xe8, 0xcf, 0xf0, 0x4f, 0xfa, 0x66, 0x76,
-	0x98, 0xfd, 0x6c, 0x13, 0xe3, 0x36, 0xbe, 0xcd, 0x3d, 0x99, 0x3d, 0xe7, 0xde, 0xbd, 0x7b, 0x0e,
-	0xc0, 0xc1, 0x12, 0x85, 0xf0, 0x16, 0x28, 0x8e, 0xe2, 0xc3, 0xe1, 0x2a, 0xe4, 0x92, 0xdb, 0xf5,
-	0xb8, 0x26, 0x1c, 0x2c, 0x8a, 0xdf, 0xd6, 0x28, 0xe4, 0x47, 0xf4, 0x7c, 0x0c, 0x6d, 0x1b, 0xaa,
-	0x3d, 0xee, 0xa3, 0x63, 0xb8, 0x46, 0xd7, 0xa2, 0xea, 0x6c, 0xbf, 0x80, 0xc6, 0x14, 0x85, 0x60,
-	0x3c, 0x18, 0xfa, 0x8e, 0xe9, 0x1a, 0xdd, 0x2a, 0x4d, 0x01, 0xbb, 0x0d, 0xe6, 0xd0, 0x77, 0x2a,
-	0xae, 0xd1, 0x7d, 0x42, 0xcd, 0xa1, 0x1f, 0xdd, 0x9e, 0xb1, 0x25, 0x0a, 0xe9, 0x2d, 0x57, 0x4e,
-	0xd5, 0x35, 0xba, 0x0d, 0x9a, 0x02, 0xe4, 0x04, 0xda, 0x1f, 0x30, 0xc0, 0x90, 0xcd, 0xb5, 0xae,
-	0x7d, 0x04, 0x3b, 0x5b, 0x6d, 0xa5, 0xd9, 0x3c, 0x3e, 0x38, 0x4c, 0xba, 0xcd, 0xb5, 0x46, 0xf5,
-	0x35, 0x32, 0x01, 0x6b, 0xca, 0x16, 0x01, 0xfa, 0x31, 0x83, 0x0b, 0x4d, 0x7d, 0xec, 0x7b, 0xd2,
-	0x53, 0x34, 0x2d, 0x9a, 0x85, 0xd4, 0x04, 0x6c, 0x11, 0x78, 0x72, 0x1d, 0xa2, 0x9a, 0xa0, 0x45,
-	0x53, 0x80, 0xf8, 0xb0, 0x7f, 0x2a, 0x57, 0xd7, 0x83, 0xcd, 0xfc, 0xca, 0x0b, 0x16, 0x58, 0xb6,
-	0xb1, 0x48, 0xe5, 0x7c, 0x7d, 0x71, 0xc3, 0xe6, 0x23, 0xbc, 0x8b, 0x55, 0x12, 0x80, 0x7c, 0x07,
-	0x7b, 0x84, 0x77, 0xff, 0x57, 0xc4, 0x76, 0x60, 0xf7, 0x6c, 0xbd, 0x1c, 0x8b, 0x85, 0x50, 0x1b,
-	0xb1, 0x68, 0x5c, 0x12, 0x0f, 0x5a, 0xfd, 0xde, 0x60, 0xb3, 0x2a, 0x2d, 0xec, 0x42, 0x53, 0x11,
-	0x7c, 0xc2, 0xb9, 0xe4, 0xa1, 0x63, 0xba, 0x95, 0x6e, 0x95, 0x66, 0x21, 0xf2, 0xcb, 0x80, 0x4e,
-	0xbf, 0x37, 0x65, 0xcb, 0xd5, 0x4d, 0xf9, 0xf9, 0x5e, 0x43, 0x3b, 0xe6, 0xc8, 0x28, 0xb5, 0x68,
-	0x01, 0x8d, 0x3e, 0xd4, 0xf1, 0xdd, 0xe4, 0x5a, 0x8d, 0x59, 0xa7, 0xea, 0x6c, 0xbf, 0x02, 0xeb,
-	0x0c, 0x37, 0x32, 0x7d, 0x3f, 0x55, 0xf5, 0x7e, 0xf2, 0x20, 0xf9, 0x0a, 0xfb, 0x3d, 0x1e, 0x5c,
-	0xb2, 0x70, 0xe9, 0x49, 0xc6, 0x83, 0xd2, 0x9d, 0x12, 0x68, 0x65, 0x79, 0xd4, 0x32, 0xea, 0x34,
-	0x87, 0x91, 0x2b, 0x78, 0x36, 0x0c, 0x98, 0x64, 0x1e, 0x93, 0x38, 0x1a, 0x4c, 0x47, 0x14, 0xc5,
-	0x8a, 0x07, 0x02, 0xff, 0x5d, 0xed, 0x25, 0xc0, 0x79, 0xc8, 0x6e, 0x3d, 0x89, 0xe9, 0xe2, 0x33,
-	0x08, 0xf9, 0x69, 0x40, 0x3b, 0x66, 0x2f, 0xed, 0xe5, 0x9c, 0x77, 0x2b, 0x05, 0xef, 0x46, 0x1f,
-	0xd7, 0x78, 0xdb, 0xa4, 0xf6, 0x75, 0x5c, 0xda, 0x7b, 0x50, 0x19, 0x84, 0xa1, 0x53, 0x53, 0x68,
-	0x74, 0x24, 0x3d, 0xe8, 0x24, 0x3e, 0xd7, 0x23, 0xbf, 0x2d, 0x8c, 0xec, 0x64, 0x47, 0xce, 0x36,
-	0x9e, 0x38, 0x7d, 0x06, 0x7b, 0x14, 0x17, 0x4c, 0x48, 0x0c, 0xcb, 0xb3, 0xe8, 0x80, 0x32, 0xe3,
-	0x80, 0x22, 0x01, 0x74, 0xfa, 0x6c, 0x8e, 0x63, 0xb6, 0x79, 0x04, 0xe9, 0x1b, 0xa8, 0x9d, 0x23,
-	0x86, 0x42, 0x7d, 0x9d, 0xcd, 0xe3, 0xfd, 0xf4, 0x01, 0x05, 0x0f, 0x83, 0x4b, 0x4e, 0xb7, 0x37,
-	0xc8, 0x67, 0xb0, 0xb4, 0xf3, 0x4a, 0xab, 0x3d, 0x85, 0x1a, 0xe5, 0x5c, 0x0a, 0xed, 0xba, 0x6d,
-	0x41, 0x7e, 0x18, 0xb0, 0x97, 0xfa, 0xad, 0x34, 0xf9, 0x73, 0xa8, 0xeb, 0x3d, 0x0a, 0xed, 0xb5,
-	0xa4, 0x4e, 0xc7, 0xac, 0xfc, 0x75, 0xcc, 0xf7, 0xd0, 0x9e, 0x7d, 0xe9, 0xf3, 0xe0, 0x11, 0xad,
-	0x90, 0x13, 0xb0, 0x72, 0x76, 0x29, 0x41, 0xf1, 0xdb, 0x84, 0x46, 0xd2, 0x9b, 0xde, 0x7d, 0xf4,
-	0x6c, 0x4d, 0xfd, 0x38, 0xb9, 0xd0, 0x3c, 0x9d, 0x15, 0xf3, 0x33, 0x0b, 0xe5, 0xf3, 0xb5, 0x52,
-	0xcc, 0xd7, 0xbc, 0x0b, 0xab, 0x45, 0x17, 0xde, 0x4f, 0xa0, 0xda, 0x03, 0x09, 0x94, 0x4d, 0xe9,
-	0x9d, 0x5c, 0x4a, 0x47, 0xbb, 0xe8, 0xf7, 0x74, 0xee, 0xed, 0xaa, 0x5d, 0x27, 0xf5, 0x03, 0xc9,
-	0x58, 0x7f, 0x30, 0x19, 0xdb, 0x60, 0x4e, 0x46, 0x4e, 0x43, 0xa5, 0x91, 0x39, 0x19, 0xe5, 0xf6,
-	0x0b, 0x85, 0xfd, 0x16, 0x33, 0xac, 0x79, 0x3f, 0xc3, 0xec, 0x2e, 0x74, 0xf4, 0x7d, 0x8a, 0x73,
-	0x64, 0xb7, 0xe8, 0x3b, 0x2d, 0x75, 0xad, 0x08, 0x5f, 0xec, 0xa8, 0xbf, 0x17, 0xef, 0xfe, 0x04,
-	0x00, 0x00, 0xff, 0xff, 0x18, 0x5d, 0xe2, 0xcc, 0x79, 0x08, 0x00, 0x00,
+	proto.RegisterType((*TxInput)(nil), "messages.TxInput")
+	proto.RegisterType((*FeeTerms)(nil), "messages.FeeTerms")
+}
+
+func init() { proto.RegisterFile("messages/messages.proto", fileDescriptor_messages_0afe2d4e18efa11a) }
+
+var fileDescriptor_messages_0afe2d4e18efa11a = []byte{
+	// 1164 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xb4, 0x57, 0xcd, 0x8e, 0x1b, 0x45,
+	0x10, 0xd6, 0x78, 0x6c, 0xaf, 0x5d, 0xfe, 0xd9, 0xcd, 0x2c, 0x21, 0xc3, 0x6a, 0x85, 0xac, 0x11,
+	0x42, 0xce, 0x65, 0x83, 0x16, 0x8e, 0x48, 0x68, 0x63, 0x7b, 0x89, 0xf1, 0x6e, 0xd6, 0xb4, 0xad,
+	0xc0, 0x75, 0xe2, 0xa9, 0xd8, 0x8d, 0xd7, 0xdd, 0x66, 0xba, 0xbd, 0xf2, 0xbe, 0x03, 0xcf, 0xc0,
+	0x89, 0x13, 0x12, 0x57, 0x9e, 0x85, 0x87, 0xe0, 0xc0, 0x1b, 0x80, 0xba, 0xa7, 0xe7, 0xcf, 0x31,
+	0x84, 0x4c, 0x94, 0xdb, 0xd4, 0xd7, 0xe5, 0xea, 0xfa, 0xe9, 0xfa, 0xaa, 0x0c, 0x8f, 0x56, 0x28,
+	0x84, 0x3f, 0x47, 0xf1, 0x24, 0xfe, 0x38, 0x5b, 0x87, 0x5c, 0x72, 0xa7, 0x16, 0xcb, 0x1e, 0x87,
+	0x16, 0xc1, 0x1f, 0x37, 0x28, 0xe4, 0x33, 0xf4, 0x03, 0x0c, 0x1d, 0x07, 0xca, 0x3d, 0x1e, 0xa0,
+	0x6b, 0x75, 0xac, 0x6e, 0x8b, 0xe8, 0x6f, 0xe7, 0x14, 0xea, 0x13, 0x14, 0x82, 0x72, 0x36, 0x0c,
+	0xdc, 0x52, 0xc7, 0xea, 0x96, 0x49, 0x0a, 0x38, 0x6d, 0x28, 0x0d, 0x03, 0xd7, 0xee, 0x58, 0xdd,
+	0x07, 0xa4, 0x34, 0x0c, 0x94, 0xf6, 0x94, 0xae, 0x50, 0x48, 0x7f, 0xb5, 0x76, 0xcb, 0x1d, 0xab,
+	0x5b, 0x27, 0x29, 0xe0, 0x5d, 0x40, 0xfb, 0x6b, 0x64, 0x18, 0xd2, 0x99, 0xb9, 0xd7, 0x79, 0x02,
+	0xd5, 0xe8, 0x6e, 0x7d, 0x67, 0xe3, 0xfc, 0xd1, 0x59, 0xe2, 0x6d, 0xce, 0x35, 0x62, 0xd4, 0xbc,
+	0x1b, 0x68, 0x4d, 0xe8, 0x9c, 0x61, 0x10, 0x5b, 0xe8, 0x40, 0xc3, 0x7c, 0xf6, 0x7d, 0xe9, 0x6b,
+	0x33, 0x4d, 0x92, 0x85, 0x74, 0x04, 0x74, 0xce, 0x7c, 0xb9, 0x09, 0x51, 0x47, 0xd0, 0x24, 0x29,
+	0xe0, 0xdd, 0x42, 0xed, 0x12, 0xf5, 0xa7, 0x70, 0x3e, 0x81, 0xd6, 0x75, 0x74, 0xfd, 0x15, 0xb2,
+	0xb9, 0x5c, 0x98, 0x44, 0xe4, 0x41, 0xc7, 0x83, 0xe6, 0x33, 0x5f, 0x2c, 0x2e, 0x37, 0x6c, 0x26,
+	0x29, 0x67, 0xda, 0x64, 0x9d, 0xe4, 0x30, 0xe7, 0x43, 0xa8, 0x4e, 0xb7, 0xd7, 0x2a, 0x97, 0xb6,
+	0x3e, 0x35, 0x92, 0xf7, 0x87, 0x05, 0x8d, 0x6f, 0x38, 0x65, 0x45, 0xe3, 0x57, 0x86, 0xc7, 0x9c,
+	0xdf, 0x9a, 0x5a, 0xd4, 0x89, 0x91, 0x9c, 0x13, 0xa8, 0xbd, 0xc0, 0x50, 0x55, 0x45, 0xb8, 0x76,
+	0xc7, 0xee, 0xb6, 0x48, 0x22, 0x3b, 0x67, 0x69, 0x88, 0xba, 0x26, 0x8d, 0x73, 0x27, 0xbd, 0x26,
+	0x3e, 0x21, 0x69, 0x1a, 0x4e, 0xa1, 0x7e, 0xb1, 0x91, 0x8b, 0x29, 0x5f, 0x22, 0x73, 0x2b, 0x51,
+	0x11, 0x13, 0x40, 0x87, 0x46, 0x67, 0x4b, 0x94, 0x6e, 0x55, 0xe7, 0xd2, 0x48, 0x5e, 0x00, 0xc7,
+	0x57, 0x72, 0xbd, 0x1c, 0x6c, 0x67, 0x0b, 0x9f, 0xcd, 0xb1, 0x70, 0x84, 0xa7, 0x50, 0x1f, 0x6f,
+	0x5e, 0xde, 0xd2, 0xd9, 0x08, 0xef, 0xe3, 0x72, 0x25, 0x80, 0xf7, 0x97, 0x05, 0xce, 0x08, 0xef,
+	0xdf, 0xef, 0x2d, 0x8e, 0x0b, 0x07, 0xcf, 0x37, 0xab, 0x6b, 0x31, 0x17, 0xba, 0x7e, 0x2d, 0x12,
+	0x8b, 0xce, 0x63, 0xa8, 0x0e, 0xd9, 0x7a, 0x23, 0x55, 0x26, 0xed, 0x6e, 0xe3, 0xfc, 0x41, 0x7a,
+	0xd1, 0x74, 0xab, 0x4f, 0x88, 0x51, 0x50, 0xef, 0xa4, 0xa7, 0x9d, 0x9c, 0xcc, 0x42, 0xba, 0x96,
+	0x3a, 0x93, 0x4d, 0x92, 0xc3, 0x52, 0x9d, 0x8b, 0x15, 0xdf, 0xb0, 0x28, 0xa5, 0x65, 0x92, 0xc3,
+	0x3c, 0x1f, 0x9a, 0xfd, 0xde, 0x60, 0xbb, 0x2e, 0x1c, 0x6b, 0x07, 0x1a, 0xda, 0xc0, 0x0b, 0x9c,
+	0x49, 0x1e, 0xba, 0xa5, 0x8e, 0xdd, 0x2d, 0x93, 0x2c, 0xe4, 0xfd, 0x62, 0xc1, 0x61, 0xbf, 0x37,
+	0xa1, 0xab, 0xf5, 0x6d, 0xf1, 0x94, 0x7e, 0x0a, 0xed, 0xd8, 0x46, 0xe6, 0xa6, 0x26, 0xd9, 0x41,
+	0x15, 0xcb, 0x5c, 0xdf, 0xdf, 0x2c, 0x75, 0x66, 0x6b, 0x44, 0x7f, 0xab, 0xce, 0x7b, 0x8e, 0x5b,
+	0x99, 0x96, 0xa4, 0xac, 0x93, 0x95, 0x07, 0xbd, 0x1f, 0xe0, 0xb8, 0xc7, 0xd9, 0x2b, 0x1a, 0xae,
+	0x7c, 0xd5, 0x65, 0x85, 0x3d, 0x55, 0x59, 0xcf, 0xd8, 0xd1, 0xf5, 0xaf, 0x91, 0x1c, 0xe6, 0x2d,
+	0xe0, 0xe1, 0x90, 0x51, 0x49, 0x7d, 0x2a, 0x71, 0x34, 0x98, 0x8c, 0x08, 0x8a, 0x35, 0x67, 0x02,
+	0xdf, 0xfe, 0xb6, 0x8f, 0x01, 0xc6, 0x21, 0xbd, 0xf3, 0x25, 0xa6, 0x6f, 0x2d, 0x83, 0x78, 0x5f,
+	0x41, 0xf3, 0x0a, 0xfd, 0xbb, 0xc2, 0x89, 0xf7, 0x06, 0xe0, 0x7c, 0xbb, 0xc1, 0x0d, 0x4e, 0xa4,
+	0x2f, 0x37, 0xa2, 0xb0, 0x99, 0x5f, 0x2d, 0x68, 0xc7, 0x51, 0x16, 0x1e, 0x08, 0xb9, 0x01, 0x60,
+	0xef, 0x0c, 0x00, 0xd5, 0x57, 0x86, 0x4b, 0xcd, 0x70, 0x88, 0x45, 0xe7, 0x08, 0xec, 0x41, 0x18,
+	0x1a, 0xb6, 0x51, 0x9f, 0xca, 0xd2, 0x20, 0x0c, 0x79, 0xa8, 0x1d, 0xa8, 0x6a, 0x07, 0x52, 0xc0,
+	0xeb, 0xc1, 0x61, 0x32, 0x4a, 0x4c, 0x61, 0x3e, 0xdb, 0x09, 0xd8, 0xcd, 0x06, 0x9c, 0x0d, 0x2b,
+	0x89, 0xf8, 0x77, 0x0b, 0x8e, 0x08, 0xce, 0xa9, 0x90, 0x18, 0x16, 0x37, 0x63, 0x86, 0x60, 0x29,
+	0x19, 0x82, 0x29, 0x47, 0xdb, 0x39, 0x8e, 0x76, 0xe1, 0xc0, 0x70, 0xb2, 0x8e, 0xbe, 0x45, 0x62,
+	0x31, 0xc7, 0xd0, 0x95, 0x37, 0x33, 0xb4, 0xf7, 0xb3, 0xea, 0x57, 0x3a, 0xc3, 0x6b, 0xba, 0x7d,
+	0x07, 0xbf, 0x1f, 0x43, 0x65, 0x8c, 0x18, 0x0a, 0xdd, 0xa7, 0x8d, 0xf3, 0xe3, 0xf4, 0x07, 0x1a,
+	0x1e, 0xb2, 0x57, 0x9c, 0x44, 0x1a, 0x91, 0x83, 0x38, 0xc5, 0x70, 0x15, 0x31, 0xe2, 0x8e, 0x83,
+	0xd1, 0x09, 0x49, 0x74, 0xbc, 0xef, 0xa0, 0x65, 0x38, 0xab, 0xb0, 0x77, 0x1f, 0x40, 0x85, 0x70,
+	0x2e, 0x85, 0xe1, 0xab, 0x48, 0xf0, 0x7e, 0xb3, 0xe0, 0x28, 0x65, 0xaa, 0xc2, 0xc6, 0x4f, 0xa0,
+	0x66, 0x5e, 0x9e, 0x30, 0x2c, 0x95, 0xc8, 0x69, 0x5a, 0xec, 0x37, 0xa6, 0xa5, 0x03, 0x8d, 0x69,
+	0xe8, 0x33, 0xe1, 0xcf, 0x64, 0x5c, 0xd5, 0x26, 0xc9, 0x42, 0xde, 0x53, 0x68, 0x4f, 0xbf, 0xef,
+	0x73, 0xf6, 0x0e, 0xce, 0x7a, 0x17, 0xd0, 0xca, 0x51, 0x51, 0x01, 0x13, 0x7f, 0x5b, 0x70, 0x9c,
+	0xe3, 0x88, 0xc2, 0x99, 0xfb, 0x8f, 0x05, 0x64, 0xcc, 0x05, 0xd5, 0x79, 0x88, 0x66, 0x66, 0x22,
+	0x2b, 0x06, 0xd4, 0x97, 0xf7, 0x71, 0x2d, 0x17, 0xe6, 0xed, 0x67, 0x10, 0x75, 0x4e, 0xd0, 0x0f,
+	0xee, 0xa3, 0xb4, 0x57, 0xa2, 0xf3, 0x14, 0xd1, 0xd5, 0xa2, 0x2c, 0x3a, 0x8d, 0x98, 0x20, 0x91,
+	0xd5, 0xd4, 0x19, 0x08, 0x49, 0x57, 0xbe, 0xc4, 0x60, 0x22, 0xfd, 0x50, 0xba, 0x07, 0x1d, 0xab,
+	0x6b, 0x93, 0x1d, 0xd4, 0xfb, 0xd3, 0x86, 0x7a, 0x52, 0x3f, 0xd3, 0xb2, 0x2a, 0xe6, 0x8a, 0x6e,
+	0xd9, 0x0e, 0x34, 0xae, 0xa6, 0xbb, 0x0b, 0x41, 0x16, 0xca, 0x2f, 0x0c, 0xf6, 0xee, 0xc2, 0x90,
+	0xe7, 0xf8, 0xf2, 0x2e, 0xc7, 0xbf, 0x3e, 0xdf, 0x2a, 0x7b, 0xe6, 0x5b, 0x76, 0xed, 0xa8, 0xe6,
+	0xd7, 0x8e, 0x13, 0xa8, 0xf5, 0x7b, 0x66, 0xaa, 0x1e, 0xe8, 0x7e, 0x48, 0xe4, 0x3d, 0x73, 0xb7,
+	0xb6, 0x77, 0xee, 0xb6, 0xa1, 0x74, 0x33, 0x72, 0xeb, 0x7a, 0xd6, 0x95, 0x6e, 0x46, 0xb9, 0x1e,
+	0x80, 0x9d, 0x1e, 0xd8, 0x9d, 0x90, 0x8d, 0xd7, 0x27, 0xa4, 0xd3, 0x85, 0x43, 0xa3, 0x4f, 0x70,
+	0x86, 0xf4, 0x0e, 0x03, 0xb7, 0xa9, 0xd5, 0x76, 0xe1, 0xcc, 0xd2, 0xd4, 0x7a, 0xdb, 0xa5, 0xa9,
+	0xfd, 0x3f, 0x96, 0xa6, 0xc3, 0x3d, 0x4b, 0xd3, 0x12, 0x0e, 0x8c, 0xe9, 0x68, 0x17, 0x57, 0xdb,
+	0xb9, 0xf9, 0x73, 0x60, 0x24, 0x45, 0x30, 0x43, 0x16, 0xe0, 0x56, 0x57, 0xbb, 0x45, 0x22, 0x41,
+	0x69, 0x1b, 0xb3, 0xb6, 0x36, 0x6b, 0x24, 0xfd, 0xbe, 0x97, 0xc6, 0xa9, 0xa8, 0xbe, 0x89, 0xec,
+	0xfd, 0x54, 0x4a, 0xe9, 0x51, 0x79, 0xd7, 0x47, 0xc6, 0x57, 0x94, 0x45, 0xa9, 0xb3, 0x22, 0xef,
+	0xb2, 0x98, 0x7a, 0x2e, 0xd1, 0x4f, 0xa7, 0xf7, 0x6b, 0x34, 0x8d, 0x94, 0x41, 0x9c, 0x33, 0x70,
+	0x7a, 0x9c, 0x87, 0x81, 0xd2, 0xe7, 0xe1, 0x25, 0xe2, 0xe5, 0xad, 0x1f, 0x3b, 0xb4, 0xe7, 0xc4,
+	0xf9, 0x12, 0x3e, 0xca, 0xa3, 0x4f, 0x7d, 0x41, 0xc5, 0x98, 0x53, 0x26, 0x85, 0xe9, 0xb7, 0x7f,
+	0x57, 0x70, 0xbe, 0x80, 0x87, 0xf9, 0xc3, 0x8b, 0x20, 0x08, 0x51, 0x08, 0x33, 0x8d, 0xf7, 0x1f,
+	0xaa, 0xc7, 0x7a, 0x89, 0x48, 0x7c, 0x89, 0x66, 0x6b, 0x8d, 0xc5, 0x97, 0x55, 0xfd, 0x47, 0xf3,
+	0xf3, 0x7f, 0x06, 0x00, 0x87, 0x78, 0xcd, 0xfa, 0x83, 0x0e, 0x00, 0x00,
 }
@@ -0,0 +1,46 @@
+package messages
+
+// constant Protocol Versions negotiated via C_JOIN_REQUEST
+// VERSION_1 - legacy protocol spoken by peers
+// which do not announce supported versions
+const (
+	VERSION_1 = 1
+)
+
+// constant Features of protocol versions
+const (
+	MESSAGE_LENGTH_20 = 20
+	HASH_FNV64_BASE58 = "fnv64-base58"
+	TX_MODE_PSBT      = "psbt"
+)
+
+// SupportedVersions - protocol versions known to this implementation
+// in decreasing order of preference
+var SupportedVersions = []uint32{VERSION_1}
+
+// VersionFeatures - returns wire format parameters of protocol version
+func VersionFeatures(version uint32) (*Features, bool) {
+	switch version {
+	case VERSION_1:
+		return &Features{
+			MessageLength: MESSAGE_LENGTH_20,
+			HashFunction:  HASH_FNV64_BASE58,
+			TxMode:        TX_MODE_PSBT,
+		}, true
+	}
+
+	return nil, false
+}
+
+// Compatible - checks whether features expected by peer
+// are satisfied by features of a version
+// unset fields of expected are not constrained
+func (f *Features) Compatible(expected *Features) bool {
+	if expected == nil {
+		return true
+	}
+
+	return (expected.MessageLength == 0 || expected.MessageLength == f.MessageLength) &&
+		(expected.HashFunction == "" || expected.HashFunction == f.HashFunction) &&
+		(expected.TxMode == "" || expected.TxMode == f.TxMode)
+}
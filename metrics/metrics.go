@@ -0,0 +1,68 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+)
+
+// Metrics - The main interface for counting events of coordinator
+// such as rejected connections, exposed to operators via admin API
+type Metrics interface {
+	Inc(name string)
+	Counters() map[string]uint64
+	WriteTo(w io.Writer) (int64, error)
+}
+
+type counters struct {
+	values map[string]uint64
+	sync.Mutex
+	Metrics
+}
+
+// NewMetrics creates a new in memory Metrics instance
+func NewMetrics() Metrics {
+	return &counters{values: make(map[string]uint64)}
+}
+
+// Inc - increments counter with specified name
+func (c *counters) Inc(name string) {
+	c.Lock()
+	defer c.Unlock()
+
+	c.values[name]++
+}
+
+// Counters - returns copy of all counters
+func (c *counters) Counters() map[string]uint64 {
+	c.Lock()
+	defer c.Unlock()
+
+	values := make(map[string]uint64, len(c.values))
+	for name, value := range c.values {
+		values[name] = value
+	}
+	return values
+}
+
+// WriteTo - writes counters in prometheus text format
+// sorted by name and prefixed with dicemix_
+func (c *counters) WriteTo(w io.Writer) (int64, error) {
+	values := c.Counters()
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var written int64
+	for _, name := range names {
+		n, err := fmt.Fprintf(w, "# TYPE dicemix_%s counter\ndicemix_%s %d\n", name, name, values[name])
+		written += int64(n)
+		if err != nil {
+			return written, err
+		}
+	}
+	return written, nil
+}
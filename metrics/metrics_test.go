@@ -0,0 +1,25 @@
+package metrics
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestMetrics(t *testing.T) {
+	m := NewMetrics()
+	m.Inc("rejected_connections_total")
+	m.Inc("rejected_connections_total")
+	m.Inc("rejected_keys_total")
+
+	counters := m.Counters()
+	if counters["rejected_connections_total"] != 2 || counters["rejected_keys_total"] != 1 {
+		t.Error("For", "counters", "expected", "2 and 1", "got", counters)
+	}
+
+	var buffer bytes.Buffer
+	expected := "# TYPE dicemix_rejected_connections_total counter\ndicemix_rejected_connections_total 2\n" +
+		"# TYPE dicemix_rejected_keys_total counter\ndicemix_rejected_keys_total 1\n"
+	if n, err := m.WriteTo(&buffer); err != nil || buffer.String() != expected || n != int64(len(expected)) {
+		t.Error("For", "prometheus", "expected", expected, "got", buffer.String(), n, err)
+	}
+}
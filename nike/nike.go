@@ -4,5 +4,5 @@ import "github.com/dev-appmonsters/dicemix-light-server/rng"
 
 // NIKE - The main interface for Non-interactive Key Exchange (NIKE).
 type NIKE interface {
-	DeriveSharedKeys([]byte, []byte) ([]byte, rng.DiceMixRng)
+	DeriveSharedKeys([]byte, []byte) ([]byte, rng.DiceMixRng, error)
 }
@@ -1,12 +1,11 @@
 package nike
 
 import (
+	"errors"
 	"sync"
 
 	"github.com/dev-appmonsters/dicemix-light-server/ecdh"
 	"github.com/dev-appmonsters/dicemix-light-server/rng"
-
-	log "github.com/sirupsen/logrus"
 )
 
 type nike struct {
@@ -21,21 +20,26 @@ func NewNike() NIKE {
 
 // DeriveSharedKeys - derives shared keys from My Private Key and Peers Public Key
 // generates RNG based on shared key using ChaCha20
-func (n *nike) DeriveSharedKeys(priv []byte, pub []byte) ([]byte, rng.DiceMixRng) {
+func (n *nike) DeriveSharedKeys(priv []byte, pub []byte) ([]byte, rng.DiceMixRng, error) {
 	ecdh := ecdh.NewCurve25519ECDH()
-	privateKey, _ := ecdh.UnmarshalSK(priv)
-	publicKey, res := ecdh.Unmarshal(pub)
+	privateKey, res := ecdh.UnmarshalSK(priv)
+	if !res {
+		return nil, rng.DiceMixRng{}, errors.New("invalid private key")
+	}
 
+	publicKey, res := ecdh.Unmarshal(pub)
 	if !res {
-		log.Fatalf("Error: generating NIKE Shared Keys %v", res)
+		return nil, rng.DiceMixRng{}, errors.New("invalid public key")
 	}
 
 	sharedKey, err := ecdh.GenerateSharedSecret(privateKey, publicKey)
-
 	if err != nil {
-		log.Fatalf("Error: generating NIKE Shared Keys %v", err)
+		return nil, rng.DiceMixRng{}, err
 	}
 
-	dicemix := rng.NewRng(sharedKey)
-	return sharedKey, dicemix
+	dicemix, err := rng.NewRng(sharedKey)
+	if err != nil {
+		return nil, rng.DiceMixRng{}, err
+	}
+	return sharedKey, dicemix, nil
 }
@@ -29,7 +29,11 @@ var sharedSecretTests = []testpair{
 func TestSharedSecret(t *testing.T) {
 	nike := NewNike()
 	for _, pair := range sharedSecretTests {
-		secret, _ := nike.DeriveSharedKeys(pair.keys[0], pair.keys[1])
+		secret, _, err := nike.DeriveSharedKeys(pair.keys[0], pair.keys[1])
+		if err != nil {
+			t.Error("For", pair.keys, "unexpected error", err)
+			continue
+		}
 
 		if !bytes.Equal(pair.secret, secret) {
 			t.Error(
@@ -40,3 +44,14 @@ func TestSharedSecret(t *testing.T) {
 		}
 	}
 }
+
+func TestInvalidKeys(t *testing.T) {
+	nike := NewNike()
+	valid := sharedSecretTests[0].keys
+
+	for _, keys := range [][][]byte{{valid[0][:31], valid[1]}, {valid[0], valid[1][:31]}, {nil, nil}} {
+		if _, _, err := nike.DeriveSharedKeys(keys[0], keys[1]); err == nil {
+			t.Error("For", keys, "expected error")
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package ratelimit
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// maximum number of keys whose buckets are kept
+// least recently used bucket is discarded beyond it
+const maxKeys = 10000
+
+// tokens available to a key
+type bucket struct {
+	key    string
+	tokens float64
+	last   time.Time
+}
+
+type tokenBucket struct {
+	buckets map[string]*list.Element
+
+	// buckets ordered from most to least recently used
+	order *list.List
+
+	// tokens added per second
+	rate float64
+
+	// maximum tokens of a bucket
+	burst float64
+
+	now func() time.Time
+	sync.Mutex
+	Limiter
+}
+
+// NewTokenBucket creates a new Limiter instance which allows burst
+// actions per key and refills rate actions per minute
+// every action is allowed if rate or burst is not positive
+func NewTokenBucket(rate float64, burst int) Limiter {
+	return &tokenBucket{
+		buckets: make(map[string]*list.Element),
+		order:   list.New(),
+		rate:    rate / 60,
+		burst:   float64(burst),
+		now:     time.Now,
+	}
+}
+
+// Allow - consumes a token of key if available
+func (l *tokenBucket) Allow(key string) bool {
+	if l.rate <= 0 || l.burst <= 0 {
+		return true
+	}
+
+	l.Lock()
+	defer l.Unlock()
+
+	now := l.now()
+	element, ok := l.buckets[key]
+	if !ok {
+		if len(l.buckets) >= maxKeys {
+			l.prune(now)
+		}
+		element = l.order.PushFront(&bucket{key: key, tokens: l.burst, last: now})
+		l.buckets[key] = element
+	}
+	l.order.MoveToFront(element)
+
+	b := element.Value.(*bucket)
+	l.refill(b, now)
+	if b.tokens < 1 {
+		return false
+	}
+
+	b.tokens--
+	return true
+}
+
+// adds tokens accumulated since last action of bucket
+func (l *tokenBucket) refill(b *bucket, now time.Time) {
+	b.tokens += now.Sub(b.last).Seconds() * l.rate
+	if b.tokens > l.burst {
+		b.tokens = l.burst
+	}
+	b.last = now
+}
+
+// discards least recently used buckets which have been refilled
+// completely as they behave same as new buckets
+// if none of them is refilled least recently used bucket is discarded
+// so that number of buckets stays bounded
+func (l *tokenBucket) prune(now time.Time) {
+	for element := l.order.Back(); element != nil; element = l.order.Back() {
+		b := element.Value.(*bucket)
+		if l.refill(b, now); b.tokens < l.burst {
+			break
+		}
+		l.remove(element)
+	}
+
+	if len(l.buckets) >= maxKeys {
+		l.remove(l.order.Back())
+	}
+}
+
+// removes bucket of element
+func (l *tokenBucket) remove(element *list.Element) {
+	l.order.Remove(element)
+	delete(l.buckets, element.Value.(*bucket).key)
+}
@@ -0,0 +1,7 @@
+package ratelimit
+
+// Limiter - The main interface for limiting actions of peers.
+// keys are subjects such as remote addresses and long term public keys
+type Limiter interface {
+	Allow(key string) bool
+}
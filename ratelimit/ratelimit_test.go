@@ -0,0 +1,108 @@
+package ratelimit
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+func newTestLimiter(now *time.Time, rate float64, burst int) *tokenBucket {
+	l := NewTokenBucket(rate, burst).(*tokenBucket)
+	l.now = func() time.Time { return *now }
+	return l
+}
+
+type bucketTestPair struct {
+	name    string
+	elapsed time.Duration
+	allowed int
+}
+
+// burst of 3 with 6 actions per minute i.e. a token per 10 seconds
+var bucketTests = []bucketTestPair{
+	{"burst", 0, 3},
+	{"partial refill", 5 * time.Second, 0},
+	{"single token", 10 * time.Second, 1},
+	{"two tokens", 20 * time.Second, 2},
+	{"capped at burst", time.Hour, 3},
+}
+
+func TestTokenBucket(t *testing.T) {
+	for _, pair := range bucketTests {
+		now := time.Unix(1000000, 0)
+		l := newTestLimiter(&now, 6, 3)
+
+		// drain bucket unless burst is being tested
+		if pair.elapsed > 0 {
+			for l.Allow("ip:1.2.3.4") {
+			}
+		}
+		now = now.Add(pair.elapsed)
+
+		allowed := 0
+		for l.Allow("ip:1.2.3.4") {
+			allowed++
+		}
+
+		if allowed != pair.allowed {
+			t.Error("For", pair.name, "expected", pair.allowed, "got", allowed)
+		}
+
+		// other keys have their own bucket
+		if !l.Allow("ip:5.6.7.8") {
+			t.Error("For", pair.name, "expected", "other key allowed", "got", false)
+		}
+	}
+}
+
+func TestUnlimited(t *testing.T) {
+	for _, l := range []Limiter{NewTokenBucket(0, 5), NewTokenBucket(5, 0)} {
+		for i := 0; i < 100; i++ {
+			if !l.Allow("ip:1.2.3.4") {
+				t.Error("For", i, "expected", true, "got", false)
+				break
+			}
+		}
+	}
+}
+
+func TestPrune(t *testing.T) {
+	now := time.Unix(1000000, 0)
+	l := newTestLimiter(&now, 6, 3)
+
+	for i := 0; i < maxKeys; i++ {
+		l.Allow("ip:" + strconv.Itoa(i))
+	}
+	for l.Allow("ip:0") {
+	}
+
+	// only drained bucket survives once others are refilled
+	now = now.Add(15 * time.Second)
+	l.Allow("ip:new")
+
+	if len(l.buckets) != 2 || l.Allow("ip:0") != true || l.Allow("ip:0") != false {
+		t.Error("For", "prune", "expected", 2, "got", len(l.buckets))
+	}
+}
+
+func TestEviction(t *testing.T) {
+	now := time.Unix(1000000, 0)
+	l := newTestLimiter(&now, 6, 1)
+
+	// drained buckets are not refilled yet
+	for i := 0; i < maxKeys; i++ {
+		l.Allow("ip:" + strconv.Itoa(i))
+	}
+	l.Allow("ip:0")
+
+	// least recently used bucket is discarded for a new key
+	if !l.Allow("ip:new") || len(l.buckets) != maxKeys {
+		t.Error("For", "eviction", "expected", maxKeys, "got", len(l.buckets))
+	}
+	if _, ok := l.buckets["ip:1"]; ok {
+		t.Error("For", "eviction", "expected", "ip:1 discarded", "got", true)
+	}
+	if l.Allow("ip:0") {
+		t.Error("For", "eviction", "expected", "ip:0 kept", "got", false)
+	}
+}
@@ -0,0 +1,190 @@
+package reputation
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/dev-appmonsters/dicemix-light-server/clock"
+)
+
+// offences of a subject
+// seen - time of last recorded timeout or offence
+type record struct {
+	offences int
+	timeouts int
+	until    time.Time
+	seen     time.Time
+}
+
+type backoffReputation struct {
+	records map[string]*record
+
+	// ban duration for first offence
+	// doubled for every subsequent offence
+	base time.Duration
+
+	// maximum ban duration
+	max time.Duration
+
+	// number of timeouts considered as an offence
+	timeoutsPerOffence int
+
+	// time of last sweep of expired records
+	pruned time.Time
+
+	clock clock.Clock
+	sync.Mutex
+	Reputation
+}
+
+// NewBackoffReputation creates a new Reputation instance which bans
+// subjects with exponential backoff i.e. base * 2^(offences - 1) upto max
+// bans are measured with clock
+func NewBackoffReputation(base, max time.Duration, timeoutsPerOffence int, clock clock.Clock) (Reputation, error) {
+	if base <= 0 || max < base {
+		return nil, errors.New("ban durations should satisfy 0 < base <= max")
+	}
+	if timeoutsPerOffence < 1 {
+		return nil, errors.New("timeouts per offence should be positive")
+	}
+
+	return &backoffReputation{
+		records:            make(map[string]*record),
+		base:               base,
+		max:                max,
+		timeoutsPerOffence: timeoutsPerOffence,
+		clock:              clock,
+	}, nil
+}
+
+// RecordExclusion - records exclusion of peer in BLAME stage
+// subjects are banned immediately
+func (r *backoffReputation) RecordExclusion(subjects ...string) {
+	r.Lock()
+	defer r.Unlock()
+
+	for _, subject := range subjects {
+		r.offence(r.record(subject))
+	}
+}
+
+// RecordTimeout - records peer which has not responded in time
+// subjects are banned after every timeoutsPerOffence timeouts
+func (r *backoffReputation) RecordTimeout(subjects ...string) {
+	r.Lock()
+	defer r.Unlock()
+
+	for _, subject := range subjects {
+		record := r.record(subject)
+		record.timeouts++
+		if record.timeouts%r.timeoutsPerOffence == 0 {
+			r.offence(record)
+		}
+	}
+}
+
+// Banned - returns time till which subject is banned
+func (r *backoffReputation) Banned(subject string) (time.Time, bool) {
+	r.Lock()
+	defer r.Unlock()
+
+	if record, ok := r.records[subject]; ok && record.until.After(r.clock.Now()) {
+		return record.until, true
+	}
+	return time.Time{}, false
+}
+
+// Bans - lists currently banned subjects
+func (r *backoffReputation) Bans() []Ban {
+	r.Lock()
+	defer r.Unlock()
+
+	bans := make([]Ban, 0)
+	now := r.clock.Now()
+	for subject, record := range r.records {
+		if record.until.After(now) {
+			bans = append(bans, Ban{Subject: subject, Offences: record.offences, Timeouts: record.timeouts, Until: record.until})
+		}
+	}
+
+	sort.Slice(bans, func(i, j int) bool { return bans[i].Subject < bans[j].Subject })
+	return bans
+}
+
+// Ban - bans subject on behalf of operator and records an offence
+// ban lasts for duration or as per backoff if duration is zero
+// returns time till which subject is banned
+func (r *backoffReputation) Ban(subject string, duration time.Duration) time.Time {
+	r.Lock()
+	defer r.Unlock()
+
+	record := r.record(subject)
+	r.offence(record)
+	if duration > 0 {
+		record.until = r.clock.Now().Add(duration)
+	}
+	return record.until
+}
+
+// Clear - lifts ban and forgets offences of subject
+// returns true if subject had any record
+func (r *backoffReputation) Clear(subject string) bool {
+	r.Lock()
+	defer r.Unlock()
+
+	_, ok := r.records[subject]
+	delete(r.records, subject)
+	return ok
+}
+
+// returns record of subject to which timeout or offence is added
+// record of subject which has not been seen for max is started again
+func (r *backoffReputation) record(subject string) *record {
+	now := r.clock.Now()
+	r.prune(now)
+
+	if existing, ok := r.records[subject]; !ok || r.expired(existing, now) {
+		r.records[subject] = &record{}
+	}
+	r.records[subject].seen = now
+	return r.records[subject]
+}
+
+// forgets records which have expired
+// so that subjects which do not return are not kept forever
+// records are swept at most once in max
+func (r *backoffReputation) prune(now time.Time) {
+	if now.Before(r.pruned.Add(r.max)) {
+		return
+	}
+	r.pruned = now
+
+	for subject, record := range r.records {
+		if r.expired(record, now) {
+			delete(r.records, subject)
+		}
+	}
+}
+
+// record has expired once subject has neither been banned
+// nor seen for max i.e. offences have decayed
+func (r *backoffReputation) expired(record *record, now time.Time) bool {
+	return !now.Before(record.until.Add(r.max)) && !now.Before(record.seen.Add(r.max))
+}
+
+// bans subject for base * 2^(offences - 1) upto max
+func (r *backoffReputation) offence(record *record) {
+	record.offences++
+
+	duration := r.base
+	for i := 1; i < record.offences && duration < r.max; i++ {
+		duration *= 2
+	}
+	if duration > r.max {
+		duration = r.max
+	}
+
+	record.until = r.clock.Now().Add(duration)
+}
@@ -0,0 +1,35 @@
+package reputation
+
+import (
+	"time"
+)
+
+// Ban - a temporarily banned long term public key or remote address
+type Ban struct {
+	Subject  string    `json:"subject"`
+	Offences int       `json:"offences"`
+	Timeouts int       `json:"timeouts"`
+	Until    time.Time `json:"until"`
+}
+
+// Reputation - The main interface for tracking misbehaving peers.
+// subjects are long term public keys and remote addresses of peers
+// as returned by KeySubject and IPSubject
+type Reputation interface {
+	RecordExclusion(subjects ...string)
+	RecordTimeout(subjects ...string)
+	Banned(subject string) (time.Time, bool)
+	Bans() []Ban
+	Ban(subject string, duration time.Duration) time.Time
+	Clear(subject string) bool
+}
+
+// KeySubject - returns subject for a long term public key
+func KeySubject(publicKey string) string {
+	return "key:" + publicKey
+}
+
+// IPSubject - returns subject for a remote address
+func IPSubject(ip string) string {
+	return "ip:" + ip
+}
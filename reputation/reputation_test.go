@@ -0,0 +1,188 @@
+package reputation
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dev-appmonsters/dicemix-light-server/clock"
+)
+
+type backoffTestPair struct {
+	offences int
+	res      time.Duration
+}
+
+var backoffTests = []backoffTestPair{
+	{1, time.Minute},
+	{2, 2 * time.Minute},
+	{3, 4 * time.Minute},
+	{5, 16 * time.Minute},
+	{10, time.Hour},
+}
+
+// clock reading time from variable of test
+type testClock struct {
+	now *time.Time
+	clock.Clock
+}
+
+func (c *testClock) Now() time.Time {
+	return *c.now
+}
+
+func newTestReputation(now *time.Time) *backoffReputation {
+	r, _ := NewBackoffReputation(time.Minute, time.Hour, 3, &testClock{now: now})
+	return r.(*backoffReputation)
+}
+
+type backoffArgsTestPair struct {
+	base               time.Duration
+	max                time.Duration
+	timeoutsPerOffence int
+	valid              bool
+}
+
+var backoffArgsTests = []backoffArgsTestPair{
+	{time.Minute, time.Hour, 3, true},
+	{time.Minute, time.Minute, 1, true},
+	{time.Minute, time.Hour, 0, false},
+	{0, time.Hour, 3, false},
+	{time.Hour, time.Minute, 3, false},
+}
+
+func TestNewBackoffReputation(t *testing.T) {
+	for _, pair := range backoffArgsTests {
+		_, err := NewBackoffReputation(pair.base, pair.max, pair.timeoutsPerOffence, clock.NewClock())
+		if (err == nil) != pair.valid {
+			t.Error("For", pair.base, pair.max, pair.timeoutsPerOffence, "expected valid", pair.valid, "got", err)
+		}
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	for _, pair := range backoffTests {
+		now := time.Unix(1000000, 0)
+		r := newTestReputation(&now)
+
+		subject := KeySubject("02ab")
+		for i := 0; i < pair.offences; i++ {
+			r.RecordExclusion(subject)
+		}
+
+		until, banned := r.Banned(subject)
+		if !banned || until.Sub(now) != pair.res {
+			t.Error(
+				"For", pair.offences,
+				"expected", pair.res,
+				"got", until.Sub(now), banned,
+			)
+		}
+
+		// ban expires
+		now = until
+		if _, banned := r.Banned(subject); banned {
+			t.Error("For", pair.offences, "expected ban to expire")
+		}
+	}
+}
+
+func TestTimeouts(t *testing.T) {
+	now := time.Unix(1000000, 0)
+	r := newTestReputation(&now)
+	subject := IPSubject("10.0.0.1")
+
+	r.RecordTimeout(subject)
+	r.RecordTimeout(subject)
+	if _, banned := r.Banned(subject); banned {
+		t.Error("expected no ban after 2 timeouts")
+	}
+
+	r.RecordTimeout(subject)
+	if until, banned := r.Banned(subject); !banned || until.Sub(now) != time.Minute {
+		t.Error("expected ban of 1 minute after 3 timeouts got", until.Sub(now), banned)
+	}
+}
+
+func TestClear(t *testing.T) {
+	now := time.Unix(1000000, 0)
+	r := newTestReputation(&now)
+
+	r.RecordExclusion(KeySubject("02ab"), IPSubject("10.0.0.1"))
+	if bans := r.Bans(); len(bans) != 2 || bans[0].Subject != "ip:10.0.0.1" || bans[1].Subject != "key:02ab" {
+		t.Error("expected 2 bans got", bans)
+	}
+
+	if !r.Clear(KeySubject("02ab")) {
+		t.Error("expected record of key to be cleared")
+	}
+	if _, banned := r.Banned(KeySubject("02ab")); banned {
+		t.Error("expected key to be unbanned")
+	}
+	if r.Clear(KeySubject("02ab")) {
+		t.Error("expected no record of key")
+	}
+	if bans := r.Bans(); len(bans) != 1 {
+		t.Error("expected 1 ban got", bans)
+	}
+}
+
+func TestPrune(t *testing.T) {
+	now := time.Unix(1000000, 0)
+	r := newTestReputation(&now)
+	start := now
+
+	// key is banned for 2 minutes
+	r.RecordExclusion(KeySubject("02ab"))
+	r.RecordExclusion(KeySubject("02ab"))
+	r.RecordTimeout(IPSubject("10.0.0.1"))
+
+	now = start.Add(30 * time.Minute)
+	r.RecordTimeout(IPSubject("10.0.0.2"))
+
+	// timeout not followed by others for max is forgotten
+	// before ban of key has decayed
+	now = start.Add(61 * time.Minute)
+	r.RecordTimeout(IPSubject("10.0.0.3"))
+	if _, ok := r.records[IPSubject("10.0.0.1")]; ok || len(r.records) != 3 {
+		t.Error("For", "sweep", "expected", 3, "records", "got", len(r.records))
+	}
+
+	// offences of returning key have decayed
+	now = start.Add(3 * time.Hour)
+	r.RecordExclusion(KeySubject("02ab"))
+	if until, banned := r.Banned(KeySubject("02ab")); !banned || until.Sub(now) != time.Minute {
+		t.Error("For", "decayed offences", "expected", time.Minute, "got", until.Sub(now), banned)
+	}
+	if len(r.records) != 1 {
+		t.Error("For", "decayed records", "expected", 1, "records", "got", len(r.records))
+	}
+}
+
+type banTestPair struct {
+	duration time.Duration
+	res      time.Duration
+}
+
+var banTests = []banTestPair{
+	{0, time.Minute},
+	{30 * time.Second, 30 * time.Second},
+	{48 * time.Hour, 48 * time.Hour},
+}
+
+func TestBan(t *testing.T) {
+	for _, pair := range banTests {
+		now := time.Unix(1000000, 0)
+		r := newTestReputation(&now)
+		subject := KeySubject("02ab")
+
+		until := r.Ban(subject, pair.duration)
+		if banned, ok := r.Banned(subject); !ok || !banned.Equal(until) || until.Sub(now) != pair.res {
+			t.Error("For", pair.duration, "expected", pair.res, "got", until.Sub(now), ok)
+		}
+
+		// ban by operator counts as offence
+		if bans := r.Bans(); len(bans) != 1 || bans[0].Offences != 1 {
+			t.Error("For", pair.duration, "expected", 1, "got", bans)
+		}
+	}
+}
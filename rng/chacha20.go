@@ -6,7 +6,6 @@ import (
 	"encoding/hex"
 
 	"github.com/codahale/chacha20"
-	log "github.com/sirupsen/logrus"
 )
 
 // DiceMixRng -- data structure to hold stream
@@ -18,26 +17,27 @@ type DiceMixRng struct {
 }
 
 // NewRng -- creates DiceMixRng object using seed provided
-func NewRng(seed []byte) DiceMixRng {
+// returns error if seed is not a valid chacha20 key
+func NewRng(seed []byte) (DiceMixRng, error) {
 	// default -- using nonce value as 0
 	nonceHex := "0000000000000000"
 	nonce, err := hex.DecodeString(nonceHex)
 
 	if err != nil {
-		log.Fatal("Error Occured: ", err)
+		return DiceMixRng{}, err
 	}
 
 	var dicemix = DiceMixRng{}
 	dicemix.chachaStream, dicemix.chachaStreamErr = chacha20.New(seed, nonce)
 
 	if dicemix.chachaStreamErr != nil {
-		log.Fatal("Error Occured: ", dicemix.chachaStreamErr)
+		return DiceMixRng{}, dicemix.chachaStreamErr
 	}
 
 	// generate random number for DC Exponential by extracting first 8 bytes
 	dicemix.chachaExpRng = getPRG(dicemix.chachaStream, 8)
 
-	return dicemix
+	return dicemix, nil
 }
 
 // GetFieldElement - converts []byte of len 8 to uint64
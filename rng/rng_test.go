@@ -22,7 +22,11 @@ func decodeString(key string) []byte {
 
 func TestRng(t *testing.T) {
 	for _, pair := range testcases {
-		v := NewRng(decodeString(pair.seed))
+		v, err := NewRng(decodeString(pair.seed))
+		if err != nil {
+			t.Error("For", pair.seed, "unexpected error", err)
+			continue
+		}
 		if hex.EncodeToString(v.chachaExpRng) != pair.prg {
 			t.Error(
 				"For", pair.seed,
@@ -32,3 +36,9 @@ func TestRng(t *testing.T) {
 		}
 	}
 }
+
+func TestInvalidSeed(t *testing.T) {
+	if _, err := NewRng(decodeString("0000")); err == nil {
+		t.Error("expected error for seed of 2 bytes")
+	}
+}
@@ -0,0 +1,91 @@
+package runstore
+
+import "sync"
+
+// pending write of async store
+// snapshot is nil if run has finished
+// done is closed once preceding writes have been applied
+type operation struct {
+	sessionID uint64
+	snapshot  *Snapshot
+	done      chan struct{}
+}
+
+type asyncStore struct {
+	store      Store
+	operations chan *operation
+
+	// error of last failed write
+	err error
+	sync.Mutex
+}
+
+// NewAsyncStore creates a new Store instance which applies writes
+// to store in background so that callers do not wait for disk
+// writes are applied in order, Save and Delete block only
+// once buffer writes are pending
+// snapshots must not be modified after they are saved
+func NewAsyncStore(store Store, buffer int) Store {
+	s := &asyncStore{store: store, operations: make(chan *operation, buffer)}
+	go s.writer()
+	return s
+}
+
+// applies pending writes to store
+func (s *asyncStore) writer() {
+	for op := range s.operations {
+		var err error
+		switch {
+		case op.done != nil:
+			close(op.done)
+			continue
+		case op.snapshot == nil:
+			err = s.store.Delete(op.sessionID)
+		default:
+			err = s.store.Save(op.snapshot)
+		}
+
+		if err != nil {
+			s.Lock()
+			s.err = err
+			s.Unlock()
+		}
+	}
+}
+
+// returns and clears error of last failed write
+func (s *asyncStore) lastError() error {
+	s.Lock()
+	defer s.Unlock()
+
+	err := s.err
+	s.err = nil
+	return err
+}
+
+// Save - queues snapshot to be written
+// returns error of an earlier write which has failed
+func (s *asyncStore) Save(snapshot *Snapshot) error {
+	s.operations <- &operation{sessionID: snapshot.SessionID, snapshot: snapshot}
+	return s.lastError()
+}
+
+// Delete - queues removal of run to be written
+// returns error of an earlier write which has failed
+func (s *asyncStore) Delete(sessionID uint64) error {
+	s.operations <- &operation{sessionID: sessionID}
+	return s.lastError()
+}
+
+// List - returns snapshots of all unfinished runs ordered by session id
+// once pending writes have been applied
+func (s *asyncStore) List() ([]*Snapshot, error) {
+	done := make(chan struct{})
+	s.operations <- &operation{done: done}
+	<-done
+
+	if err := s.lastError(); err != nil {
+		return nil, err
+	}
+	return s.store.List()
+}
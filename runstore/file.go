@@ -0,0 +1,178 @@
+package runstore
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"sync"
+)
+
+// entry of log of file store
+// snapshot is nil if run has finished
+type record struct {
+	SessionID uint64    `json:"sessionId"`
+	Snapshot  *Snapshot `json:"snapshot,omitempty"`
+}
+
+// log is compacted once it holds compactRatio records per unfinished run
+// and at least minCompactRecords records
+const (
+	compactRatio      = 4
+	minCompactRecords = 1024
+)
+
+type fileStore struct {
+	path   string
+	file   *os.File
+	memory Store
+
+	// records in log and number of records at which it is compacted
+	records    int
+	compaction int
+	sync.Mutex
+	Store
+}
+
+// NewFileStore creates a new Store instance which persists
+// snapshots as a log of JSON lines in file at path
+// log is replayed and compacted to latest snapshots on opening
+// and compacted again once most of its records are superseded
+func NewFileStore(path string) (Store, error) {
+	memory := NewMemoryStore()
+	if err := replay(path, memory); err != nil {
+		return nil, err
+	}
+
+	s := &fileStore{path: path, memory: memory}
+	if err := s.compact(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// loads records of log at path into memory
+func replay(path string, memory Store) error {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		r := &record{}
+		if err := json.Unmarshal(scanner.Bytes(), r); err != nil {
+			// last record may be incomplete if server crashed while writing
+			break
+		}
+
+		if r.Snapshot == nil {
+			memory.Delete(r.SessionID)
+		} else {
+			memory.Save(r.Snapshot)
+		}
+	}
+	return scanner.Err()
+}
+
+// rewrites log with latest snapshots only
+// new log replaces old one atomically
+func (s *fileStore) compact() error {
+	snapshots, _ := s.memory.List()
+	s.records = len(snapshots)
+	s.compaction = compactRatio * len(snapshots)
+	if s.compaction < minCompactRecords {
+		s.compaction = minCompactRecords
+	}
+
+	tmp, err := os.OpenFile(s.path+".tmp", os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+
+	writer := bufio.NewWriter(tmp)
+	for _, snapshot := range snapshots {
+		data, err := json.Marshal(&record{SessionID: snapshot.SessionID, Snapshot: snapshot})
+		if err != nil {
+			tmp.Close()
+			return err
+		}
+		writer.Write(append(data, '\n'))
+	}
+
+	if err := writer.Flush(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(s.path+".tmp", s.path); err != nil {
+		return err
+	}
+
+	if s.file != nil {
+		s.file.Close()
+	}
+	s.file, err = os.OpenFile(s.path, os.O_WRONLY|os.O_APPEND, 0600)
+	return err
+}
+
+// appends record to log and waits till it is on disk
+func (s *fileStore) append(r *record) error {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+
+	if _, err := s.file.Write(append(data, '\n')); err != nil {
+		return err
+	}
+	s.records++
+	return s.file.Sync()
+}
+
+// compacts log once most of its records are superseded
+func (s *fileStore) maybeCompact() error {
+	if s.records < s.compaction {
+		return nil
+	}
+	return s.compact()
+}
+
+// Save - appends snapshot to log
+func (s *fileStore) Save(snapshot *Snapshot) error {
+	s.Lock()
+	defer s.Unlock()
+
+	if err := s.append(&record{SessionID: snapshot.SessionID, Snapshot: snapshot}); err != nil {
+		return err
+	}
+	s.memory.Save(snapshot)
+	return s.maybeCompact()
+}
+
+// Delete - appends removal of run to log
+func (s *fileStore) Delete(sessionID uint64) error {
+	s.Lock()
+	defer s.Unlock()
+
+	if err := s.append(&record{SessionID: sessionID}); err != nil {
+		return err
+	}
+	s.memory.Delete(sessionID)
+	return s.maybeCompact()
+}
+
+// List - returns snapshots of all unfinished runs ordered by session id
+func (s *fileStore) List() ([]*Snapshot, error) {
+	return s.memory.List()
+}
@@ -0,0 +1,51 @@
+package runstore
+
+import (
+	"sort"
+	"sync"
+)
+
+type memoryStore struct {
+	snapshots map[uint64]*Snapshot
+	sync.Mutex
+	Store
+}
+
+// NewMemoryStore creates a new Store instance
+// which keeps snapshots in memory
+func NewMemoryStore() Store {
+	return &memoryStore{snapshots: make(map[uint64]*Snapshot)}
+}
+
+// Save - replaces snapshot of run
+func (s *memoryStore) Save(snapshot *Snapshot) error {
+	s.Lock()
+	defer s.Unlock()
+
+	s.snapshots[snapshot.SessionID] = snapshot
+	return nil
+}
+
+// Delete - removes snapshot of finished run
+func (s *memoryStore) Delete(sessionID uint64) error {
+	s.Lock()
+	defer s.Unlock()
+
+	delete(s.snapshots, sessionID)
+	return nil
+}
+
+// List - returns snapshots of all unfinished runs ordered by session id
+func (s *memoryStore) List() ([]*Snapshot, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	snapshots := make([]*Snapshot, 0, len(s.snapshots))
+	for _, snapshot := range s.snapshots {
+		snapshots = append(snapshots, snapshot)
+	}
+	sort.Slice(snapshots, func(i, j int) bool {
+		return snapshots[i].SessionID < snapshots[j].SessionID
+	})
+	return snapshots, nil
+}
@@ -0,0 +1,35 @@
+package runstore
+
+import "github.com/dev-appmonsters/dicemix-light-server/messages"
+
+// Snapshot - state of a run after a state transition
+// i.e. after server has broadcasted a response to peers
+type Snapshot struct {
+	SessionID uint64                `json:"sessionId"`
+	Run       int                   `json:"run"`
+	PoolID    string                `json:"poolId"`
+	FeeTerms  *messages.FeeTerms    `json:"feeTerms"`
+	Peers     []*messages.PeersInfo `json:"peers"`
+	NextState int                   `json:"nextState"`
+	Version   uint32                `json:"version,omitempty"`
+	Messages  [][]byte              `json:"messages,omitempty"`
+
+	// serialized PSBT assembled in DC-SIMPLE round
+	Transaction []byte `json:"transaction,omitempty"`
+
+	// last response broadcasted to peers
+	// sent again once peers have reconnected
+	Response []byte `json:"response"`
+
+	Timestamp string `json:"timestamp"`
+}
+
+// Store - The main interface for persisting state of runs.
+// holds latest snapshot of every run which has not finished yet
+// implemented by memory and append-only file stores, database
+// backends such as BoltDB or SQLite can implement it as well
+type Store interface {
+	Save(*Snapshot) error
+	Delete(sessionID uint64) error
+	List() ([]*Snapshot, error)
+}
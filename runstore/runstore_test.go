@@ -0,0 +1,135 @@
+package runstore
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dev-appmonsters/dicemix-light-server/messages"
+)
+
+var snapshots = []*Snapshot{
+	{SessionID: 2, Run: 0, PoolID: "1000000-p2wpkh", NextState: messages.C_KEY_EXCHANGE, Peers: []*messages.PeersInfo{{Id: 4}, {Id: 5}}, Response: []byte{1}},
+	{SessionID: 1, Run: 0, PoolID: "1000000-p2wpkh", NextState: messages.C_KEY_EXCHANGE, Peers: []*messages.PeersInfo{{Id: 1}, {Id: 2}, {Id: 3}}, Response: []byte{2}},
+	{SessionID: 1, Run: 1, PoolID: "1000000-p2wpkh", NextState: messages.C_EXP_DC_VECTOR, Peers: []*messages.PeersInfo{{Id: 1, NumMsgs: 2}, {Id: 3, NumMsgs: 1}}, Response: []byte{3}},
+	{SessionID: 3, Run: 0, PoolID: "1000000-p2wpkh", NextState: messages.C_KEY_EXCHANGE, Peers: []*messages.PeersInfo{{Id: 6}, {Id: 7}}, Response: []byte{4}},
+}
+
+// stores snapshots and finishes session 3
+func fillStore(t *testing.T, store Store) {
+	for _, snapshot := range snapshots {
+		if err := store.Save(snapshot); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := store.Delete(3); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func checkStore(t *testing.T, store Store) {
+	all, err := store.List()
+	if err != nil || len(all) != 2 {
+		t.Fatal("expected", 2, "snapshots got", len(all), err)
+	}
+
+	// only latest snapshot of session is kept
+	if all[0].SessionID != 1 || all[0].Run != 1 || all[0].NextState != messages.C_EXP_DC_VECTOR {
+		t.Error("For", "session 1", "expected", snapshots[2], "got", all[0])
+	}
+	if len(all[0].Peers) != 2 || all[0].Peers[0].NumMsgs != 2 || all[0].Response[0] != 3 {
+		t.Error("For", "session 1 peers", "expected", snapshots[2].Peers, "got", all[0].Peers)
+	}
+
+	if all[1].SessionID != 2 {
+		t.Error("For", "session 2", "expected", 2, "got", all[1].SessionID)
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	store := NewMemoryStore()
+	fillStore(t, store)
+	checkStore(t, store)
+}
+
+func TestAsyncStore(t *testing.T) {
+	store := NewAsyncStore(NewMemoryStore(), 1)
+	fillStore(t, store)
+	checkStore(t, store)
+}
+
+func TestFileStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "runstore")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "runs.jsonl")
+	store, err := NewFileStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fillStore(t, store)
+	checkStore(t, store)
+
+	// snapshots are loaded again after restart
+	reopened, err := NewFileStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkStore(t, reopened)
+
+	// log is compacted to one record per unfinished run
+	data, _ := ioutil.ReadFile(path)
+	if lines := bytes.Count(data, []byte("\n")); lines != 2 {
+		t.Error("For", "compaction", "expected", 2, "got", lines)
+	}
+
+	// record torn by crash is ignored
+	f, _ := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0600)
+	f.Write([]byte(`{"sessionId":2,"snaps`))
+	f.Close()
+
+	recovered, err := NewFileStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkStore(t, recovered)
+}
+
+func TestFileStoreCompaction(t *testing.T) {
+	dir, err := ioutil.TempDir("", "runstore")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "runs.jsonl")
+	store, err := NewFileStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// superseded snapshots are discarded while store is open
+	for i := 0; i < minCompactRecords+10; i++ {
+		if err := store.Save(snapshots[0]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	data, _ := ioutil.ReadFile(path)
+	if lines := bytes.Count(data, []byte("\n")); lines != 11 {
+		t.Error("For", "compaction", "expected", 11, "got", lines)
+	}
+
+	reopened, err := NewFileStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if all, _ := reopened.List(); len(all) != 1 || all[0].SessionID != snapshots[0].SessionID {
+		t.Error("For", "compacted log", "expected", snapshots[0], "got", all)
+	}
+}
@@ -0,0 +1,397 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dev-appmonsters/dicemix-light-server/events"
+	"github.com/dev-appmonsters/dicemix-light-server/reputation"
+	"github.com/dev-appmonsters/dicemix-light-server/utils"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// WaitingClientInfo - peer waiting in a pool
+type WaitingClientInfo struct {
+	ID     int32  `json:"id"`
+	PoolID string `json:"poolId"`
+
+	// Ready - peer has sent his long term public key
+	Ready bool `json:"ready"`
+}
+
+// ClientsInfo - connections of peers which are not in a run
+type ClientsInfo struct {
+	// Pending - connections which have not joined yet
+	Pending int                 `json:"pending"`
+	Waiting []WaitingClientInfo `json:"waiting"`
+}
+
+// RunPeerInfo - peer of a run and his progress in current round
+type RunPeerInfo struct {
+	ID              int32 `json:"id"`
+	MessageReceived bool  `json:"messageReceived"`
+	Connected       bool  `json:"connected"`
+}
+
+// RunInfo - state of a run
+type RunInfo struct {
+	SessionID uint64        `json:"sessionId"`
+	Run       int           `json:"run"`
+	PoolID    string        `json:"poolId"`
+	NextState int           `json:"nextState"`
+	Resuming  bool          `json:"resuming"`
+	Peers     []RunPeerInfo `json:"peers"`
+}
+
+// PoolParams - parameters of pool adjustable at runtime
+// parameters which are not set are kept
+type PoolParams struct {
+	MinPeers *int `json:"minPeers,omitempty"`
+	MaxPeers *int `json:"maxPeers,omitempty"`
+}
+
+// AdminHandler returns handler of admin API
+// which should be served on a listener separate from /ws
+// requests require one of Admin.Tokens if configured
+func (s *connection) AdminHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/blame", s.blameReports)
+	mux.HandleFunc("/bans", s.bans)
+	mux.HandleFunc("/metrics", s.metrics)
+	mux.HandleFunc("/events", s.events)
+	mux.HandleFunc("/clients", s.clients)
+	mux.HandleFunc("/runs", s.runs)
+	mux.HandleFunc("/pools", s.pools)
+	return authorizeAdmin(s.hub.config.Admin.Tokens, mux)
+}
+
+// refuses requests without an accepted bearer token
+func authorizeAdmin(tokens []string, handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var token string
+		if authorization := r.Header.Get("Authorization"); strings.HasPrefix(authorization, "Bearer ") {
+			token = strings.TrimPrefix(authorization, "Bearer ")
+		}
+
+		if !validAuthToken(tokens, token) {
+			log.Info("ADMIN - Unauthorized request ", r.Method, " ", r.URL.Path)
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
+
+// lists pending connections and waiting peers on GET
+// disconnects peer ?id=<peer id> on DELETE
+func (s *connection) clients(w http.ResponseWriter, r *http.Request) {
+	h := s.hub
+	switch r.Method {
+	case http.MethodGet:
+		h.Lock()
+		info := ClientsInfo{Pending: len(h.pending), Waiting: make([]WaitingClientInfo, 0)}
+		for _, id := range h.poolOrder {
+			for _, waitingClient := range h.pools[id].waitingQueue {
+				info.Waiting = append(info.Waiting, WaitingClientInfo{
+					ID:     waitingClient.id,
+					PoolID: id,
+					Ready:  len(waitingClient.publicKey) > 0,
+				})
+			}
+		}
+		h.Unlock()
+		writeJSON(w, info)
+
+	case http.MethodDelete:
+		id, err := strconv.ParseInt(r.URL.Query().Get("id"), 10, 32)
+		if err != nil {
+			http.Error(w, "invalid peer id", http.StatusBadRequest)
+			return
+		}
+
+		h.Lock()
+		defer h.Unlock()
+		if !kickPeer(h, int32(id)) {
+			http.Error(w, "peer not found", http.StatusNotFound)
+			return
+		}
+		peerLog(h, 0, int32(id)).Info("ADMIN - Kicked peer")
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// disconnects peer, peer in a run is considered offline by his run
+// returns false if peer is not connected
+func kickPeer(h *hub, id int32) bool {
+	if _, ok := getClient(h.clients, id); !ok {
+		return false
+	}
+
+	leaveQueue(h, id)
+	removePeer(h, id)
+	return true
+}
+
+// lists runs on GET
+// aborts run ?session=<id> on DELETE
+func (s *connection) runs(w http.ResponseWriter, r *http.Request) {
+	h := s.hub
+	switch r.Method {
+	case http.MethodGet:
+		h.Lock()
+		runs := runsInfo(h)
+		h.Unlock()
+		writeJSON(w, runs)
+
+	case http.MethodDelete:
+		sessionID, err := strconv.ParseUint(r.URL.Query().Get("session"), 10, 64)
+		if err != nil {
+			http.Error(w, "invalid session id", http.StatusBadRequest)
+			return
+		}
+
+		h.Lock()
+		defer h.Unlock()
+		if _, ok := h.runs[sessionID]; !ok {
+			http.Error(w, "session not found", http.StatusNotFound)
+			return
+		}
+
+		// peers are informed that run will not continue
+		for _, peer := range h.runs[sessionID].peers {
+			if client, ok := getClient(h.clients, peer.Id); ok {
+				sendAbortResponse(client, sessionID, "Run aborted by operator")
+			}
+		}
+		emit(h, &events.Event{
+			Type:      events.RunTerminated,
+			SessionID: sessionID,
+			Peers:     peerIDs(h.runs[sessionID].peers),
+			Reason:    "aborted by operator",
+		})
+		terminate(h, sessionID)
+		runLog(h, sessionID).Info("ADMIN - Aborted run")
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// returns state of all runs ordered by session id
+func runsInfo(h *hub) []RunInfo {
+	runs := make([]RunInfo, 0, len(h.runs))
+	for sessionID, r := range h.runs {
+		info := RunInfo{
+			SessionID: sessionID,
+			Run:       r.run,
+			NextState: r.nextState,
+			Resuming:  r.resuming,
+			Peers:     make([]RunPeerInfo, 0, len(r.peers)),
+		}
+		if r.pool != nil {
+			info.PoolID = r.pool.ID
+		}
+
+		for _, peer := range r.peers {
+			_, connected := getClient(h.clients, peer.Id)
+			info.Peers = append(info.Peers, RunPeerInfo{ID: peer.Id, MessageReceived: peer.MessageReceived, Connected: connected})
+		}
+		runs = append(runs, info)
+	}
+
+	sort.Slice(runs, func(i, j int) bool {
+		return runs[i].SessionID < runs[j].SessionID
+	})
+	return runs
+}
+
+// lists pools on GET
+// adjusts PoolParams of pool ?id=<pool id> on PATCH
+func (s *connection) pools(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		s.PoolsInfo(w, r)
+
+	case http.MethodPatch:
+		params := &PoolParams{}
+		if err := json.NewDecoder(r.Body).Decode(params); err != nil {
+			http.Error(w, "invalid pool parameters", http.StatusBadRequest)
+			return
+		}
+
+		h := s.hub
+		h.Lock()
+		defer h.Unlock()
+
+		id := r.URL.Query().Get("id")
+		pool, ok := h.pools[id]
+		if !ok {
+			http.Error(w, "pool not found", http.StatusNotFound)
+			return
+		}
+
+		if err := adjustPool(h, pool, params); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		log.Info("ADMIN - Adjusted pool ", id, " MinPeers - ", pool.MinPeers, ", MaxPeers - ", pool.MaxPeers)
+		writeJSON(w, PoolInfo{PoolConfig: pool.PoolConfig, QueueDepth: len(pool.waitingQueue)})
+
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// applies params to pool and starts a run
+// if enough peers are ready with new params
+func adjustPool(h *hub, pool *pool, params *PoolParams) error {
+	minPeers, maxPeers := pool.MinPeers, pool.MaxPeers
+	if params.MinPeers != nil {
+		minPeers = *params.MinPeers
+	}
+	if params.MaxPeers != nil {
+		maxPeers = *params.MaxPeers
+	}
+
+	if minPeers < 2 || maxPeers < minPeers || maxPeers > utils.MaxPeers {
+		return errors.New("peers should satisfy 2 <= minPeers <= maxPeers <= " + strconv.Itoa(utils.MaxPeers))
+	}
+
+	pool.MinPeers, pool.MaxPeers = minPeers, maxPeers
+	checkPool(h, pool)
+	return nil
+}
+
+// writes counters of coordinator in prometheus text format
+func (s *connection) metrics(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	s.hub.metrics.WriteTo(w)
+}
+
+// streams protocol events as JSON lines till client disconnects
+// events are dropped if client does not keep up
+func (s *connection) events(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	subscription, cancel := s.hub.feed.Subscribe(utils.EventBuffer)
+	defer cancel()
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+	flusher, _ := w.(http.Flusher)
+	if flusher != nil {
+		flusher.Flush()
+	}
+
+	encoder := json.NewEncoder(w)
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case event := <-subscription:
+			if err := encoder.Encode(event); err != nil {
+				return
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+	}
+}
+
+// lists banned keys and addresses on GET
+// bans ?subject=<key:hex|ip:address> for optional ?duration=<1h> on POST
+// clears ban of ?subject=<key:hex|ip:address> on DELETE
+func (s *connection) bans(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, s.hub.reputation.Bans())
+	case http.MethodPost:
+		subject := r.URL.Query().Get("subject")
+		if !strings.HasPrefix(subject, reputation.KeySubject("")) && !strings.HasPrefix(subject, reputation.IPSubject("")) {
+			http.Error(w, "subject should be key:<hex> or ip:<address>", http.StatusBadRequest)
+			return
+		}
+
+		var duration time.Duration
+		if value := r.URL.Query().Get("duration"); value != "" {
+			var err error
+			if duration, err = time.ParseDuration(value); err != nil || duration < 0 {
+				http.Error(w, "invalid duration", http.StatusBadRequest)
+				return
+			}
+		}
+
+		until := s.hub.reputation.Ban(subject, duration)
+		subjectLog(s.hub, subject).Info("ADMIN - Banned till ", until)
+		for _, ban := range s.hub.reputation.Bans() {
+			if ban.Subject == subject {
+				writeJSON(w, ban)
+				return
+			}
+		}
+		writeJSON(w, reputation.Ban{Subject: subject, Until: until})
+	case http.MethodDelete:
+		subject := r.URL.Query().Get("subject")
+		if !s.hub.reputation.Clear(subject) {
+			http.Error(w, "subject not found", http.StatusNotFound)
+			return
+		}
+		subjectLog(s.hub, subject).Info("ADMIN - Cleared ban")
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// lists blame reports
+// reports of a single session if ?session=<id> is specified
+func (s *connection) blameReports(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	session := r.URL.Query().Get("session")
+	if session == "" {
+		reports, err := s.hub.evidence.List()
+		if checkError(err) {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, reports)
+		return
+	}
+
+	sessionID, err := strconv.ParseUint(session, 10, 64)
+	if err != nil {
+		http.Error(w, "invalid session id", http.StatusBadRequest)
+		return
+	}
+
+	reports, err := s.hub.evidence.Get(sessionID)
+	if checkError(err) {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, reports)
+}
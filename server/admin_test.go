@@ -0,0 +1,238 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/dev-appmonsters/dicemix-light-server/events"
+	"github.com/dev-appmonsters/dicemix-light-server/messages"
+
+	"github.com/golang/protobuf/proto"
+)
+
+// sends request to admin API of hub with bearer token if not empty
+func adminRequest(h *hub, method, target, body, token string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, target, strings.NewReader(body))
+	if token != "" {
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	recorder := httptest.NewRecorder()
+	s := &connection{hub: h}
+	s.AdminHandler().ServeHTTP(recorder, r)
+	return recorder
+}
+
+type adminAuthTestPair struct {
+	tokens []string
+	token  string
+	status int
+}
+
+var adminAuthTests = []adminAuthTestPair{
+	{nil, "", http.StatusOK},
+	{[]string{"secret"}, "", http.StatusUnauthorized},
+	{[]string{"secret"}, "wrong", http.StatusUnauthorized},
+	{[]string{"secret"}, "secret", http.StatusOK},
+	{[]string{"old", "secret"}, "secret", http.StatusOK},
+}
+
+func TestAdminAuthorization(t *testing.T) {
+	for _, pair := range adminAuthTests {
+		h, _ := newTestHub(messages.C_KEY_EXCHANGE)
+		h.config.Admin.Tokens = pair.tokens
+
+		if status := adminRequest(h, http.MethodGet, "/runs", "", pair.token).Code; status != pair.status {
+			t.Error("For", pair.tokens, pair.token, "expected", pair.status, "got", status)
+		}
+	}
+
+	// token is only accepted as bearer token
+	h, _ := newTestHub(messages.C_KEY_EXCHANGE)
+	h.config.Admin.Tokens = []string{"secret"}
+	r := httptest.NewRequest(http.MethodGet, "/runs", nil)
+	r.Header.Set("Authorization", "secret")
+	recorder := httptest.NewRecorder()
+	(&connection{hub: h}).AdminHandler().ServeHTTP(recorder, r)
+	if recorder.Code != http.StatusUnauthorized {
+		t.Error("For", "without Bearer", "expected", http.StatusUnauthorized, "got", recorder.Code)
+	}
+}
+
+func TestAdminRuns(t *testing.T) {
+	h, c := newTestHub(messages.C_EXP_DC_VECTOR)
+
+	runs := make([]RunInfo, 0)
+	json.Unmarshal(adminRequest(h, http.MethodGet, "/runs", "", "").Body.Bytes(), &runs)
+	if len(runs) != 1 || runs[0].SessionID != testSession || runs[0].NextState != messages.C_EXP_DC_VECTOR || len(runs[0].Peers) != testPeers {
+		t.Fatal("For", "GET /runs", "expected", "test run", "got", runs)
+	}
+	for i, peer := range runs[0].Peers {
+		expected := h.runs[testSession].peers[i]
+		if peer.ID != expected.Id || peer.MessageReceived != expected.MessageReceived || peer.Connected != (i == 0) {
+			t.Error("For", "peer", i, "expected", expected, "got", peer)
+		}
+	}
+
+	if status := adminRequest(h, http.MethodDelete, "/runs?session=2", "", "").Code; status != http.StatusNotFound {
+		t.Error("For", "unknown session", "expected", http.StatusNotFound, "got", status)
+	}
+	sink := recordEvents(h)
+	if status := adminRequest(h, http.MethodDelete, "/runs?session=1", "", "").Code; status != http.StatusNoContent {
+		t.Error("For", "abort", "expected", http.StatusNoContent, "got", status)
+	}
+	if got := sink.Events(); len(got) != 1 || got[0].Type != events.RunTerminated || len(got[0].Peers) != testPeers {
+		t.Error("For", "abort", "expected", "run_terminated", "got", got)
+	}
+
+	// peer is informed and disconnected
+	response := &messages.GenericResponse{}
+	if err := proto.Unmarshal(<-c.send, response); err != nil || response.Header.ErrorCode != messages.E_SESSION_ABORTED {
+		t.Error("For", "abort", "expected", messages.E_SESSION_ABORTED, "got", response.Header, err)
+	}
+	if _, ok := <-c.send; ok {
+		t.Error("For", "abort", "expected", "closed connection", "got", "message")
+	}
+	if _, ok := h.runs[testSession]; ok {
+		t.Error("For", "abort", "expected", "no run", "got", h.runs[testSession])
+	}
+}
+
+func TestAdminClients(t *testing.T) {
+	h, _ := newTestHub(messages.C_KEY_EXCHANGE)
+	ready := addWaitingPeer(h, 10, true)
+	addWaitingPeer(h, 11, false)
+	h.pending[&client{}] = true
+
+	info := &ClientsInfo{}
+	json.Unmarshal(adminRequest(h, http.MethodGet, "/clients", "", "").Body.Bytes(), info)
+	if info.Pending != 1 || len(info.Waiting) != 2 || !info.Waiting[0].Ready || info.Waiting[1].Ready || info.Waiting[0].PoolID != h.poolOrder[0] {
+		t.Error("For", "GET /clients", "expected", "1 pending and 2 waiting", "got", info)
+	}
+
+	if status := adminRequest(h, http.MethodDelete, "/clients?id=12", "", "").Code; status != http.StatusNotFound {
+		t.Error("For", "unknown peer", "expected", http.StatusNotFound, "got", status)
+	}
+	if status := adminRequest(h, http.MethodDelete, "/clients?id=10", "", "").Code; status != http.StatusNoContent {
+		t.Error("For", "kick", "expected", http.StatusNoContent, "got", status)
+	}
+
+	if _, ok := <-ready.send; ok {
+		t.Error("For", "kick", "expected", "closed connection", "got", "message")
+	}
+	if _, _, found := findWaitingClient(h, 10); found {
+		t.Error("For", "kick", "expected", "removed from queue", "got", "waiting")
+	}
+}
+
+type adjustPoolTestPair struct {
+	body   string
+	status int
+	min    int
+	max    int
+}
+
+var adjustPoolTests = []adjustPoolTestPair{
+	{`{"minPeers": 1}`, http.StatusBadRequest, 3, 50},
+	{`{"maxPeers": 51}`, http.StatusBadRequest, 3, 50},
+	{`{"minPeers": 4, "maxPeers": 3}`, http.StatusBadRequest, 3, 50},
+	{`{"minPeers"`, http.StatusBadRequest, 3, 50},
+	{`{"maxPeers": 10}`, http.StatusOK, 3, 10},
+	{`{"minPeers": 2, "maxPeers": 5}`, http.StatusOK, 2, 5},
+}
+
+func TestAdminPools(t *testing.T) {
+	for _, pair := range adjustPoolTests {
+		h, _ := newTestHub(messages.C_KEY_EXCHANGE)
+		pool := h.pools[h.poolOrder[0]]
+
+		recorder := adminRequest(h, http.MethodPatch, "/pools?id="+pool.ID, pair.body, "")
+		if recorder.Code != pair.status || pool.MinPeers != pair.min || pool.MaxPeers != pair.max {
+			t.Error("For", pair.body, "expected", pair.status, pair.min, pair.max, "got", recorder.Code, pool.MinPeers, pool.MaxPeers)
+		}
+	}
+
+	h, _ := newTestHub(messages.C_KEY_EXCHANGE)
+	if status := adminRequest(h, http.MethodPatch, "/pools?id=unknown", `{"minPeers": 2}`, "").Code; status != http.StatusNotFound {
+		t.Error("For", "unknown pool", "expected", http.StatusNotFound, "got", status)
+	}
+
+	// lowering MinPeers starts fill timer for ready peers
+	pool := h.pools[h.poolOrder[0]]
+	addWaitingPeer(h, 10, true)
+	addWaitingPeer(h, 11, true)
+	adminRequest(h, http.MethodPatch, "/pools?id="+pool.ID, `{"minPeers": 2}`, "")
+	if pool.fillStarted.IsZero() {
+		t.Error("For", "minPeers 2", "expected", "fill timer", "got", "none")
+	}
+
+	// lowering MaxPeers starts run at once
+	adminRequest(h, http.MethodPatch, "/pools?id="+pool.ID, `{"maxPeers": 2}`, "")
+	h.Lock()
+	defer h.Unlock()
+	if len(h.runs) != 2 || len(pool.waitingQueue) != 0 {
+		t.Error("For", "maxPeers 2", "expected", "new run", "got", len(h.runs), "runs")
+	}
+}
+
+type validateAdminTestPair struct {
+	tokens  []string
+	address string
+	valid   bool
+}
+
+var validateAdminTests = []validateAdminTestPair{
+	{nil, "127.0.0.1:8083", true},
+	{nil, "localhost:8083", true},
+	{nil, ":8083", false},
+	{nil, "10.0.0.1:8083", false},
+	{[]string{"secret"}, ":8083", false},
+	{[]string{"secret"}, "127.0.0.1:8083", true},
+}
+
+func TestValidateAdmin(t *testing.T) {
+	for _, pair := range validateAdminTests {
+		config := DefaultConfig()
+		config.Admin.Tokens = pair.tokens
+		if err := config.ValidateAdmin(pair.address); (err == nil) != pair.valid {
+			t.Error("For", pair.address, pair.tokens, "expected valid", pair.valid, "got", err)
+		}
+	}
+
+	// tokens are sent to admin API of other hosts only over TLS
+	config := DefaultConfig()
+	config.Admin.Tokens = []string{"secret"}
+	config.TLS = TLSConfig{CertFile: "server.crt", KeyFile: "server.key"}
+	if err := config.ValidateAdmin(":8083"); err != nil {
+		t.Error("For", ":8083", "TLS", "expected valid", true, "got", err)
+	}
+}
+
+func TestAdminBans(t *testing.T) {
+	h, _ := newTestHub(messages.C_KEY_EXCHANGE)
+
+	for target, status := range map[string]int{
+		"/bans?subject=02ab":                    http.StatusBadRequest,
+		"/bans?subject=key:02ab&duration=never": http.StatusBadRequest,
+		"/bans?subject=key:02ab&duration=-1h":   http.StatusBadRequest,
+		"/bans?subject=key:02ab&duration=1h":    http.StatusOK,
+		"/bans?subject=ip:10.0.0.1":             http.StatusOK,
+	} {
+		if code := adminRequest(h, http.MethodPost, target, "", "").Code; code != status {
+			t.Error("For", target, "expected", status, "got", code)
+		}
+	}
+
+	if _, banned := h.reputation.Banned("key:02ab"); !banned {
+		t.Error("For", "key:02ab", "expected", "banned", "got", banned)
+	}
+	if code := adminRequest(h, http.MethodDelete, "/bans?subject=key:02ab", "", "").Code; code != http.StatusNoContent {
+		t.Error("For", "unban", "expected", http.StatusNoContent, "got", code)
+	}
+	if bans := h.reputation.Bans(); len(bans) != 1 || bans[0].Subject != "ip:10.0.0.1" {
+		t.Error("For", "bans", "expected", "ip:10.0.0.1", "got", bans)
+	}
+}
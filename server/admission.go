@@ -0,0 +1,55 @@
+package server
+
+import (
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"io/ioutil"
+
+	"github.com/dev-appmonsters/dicemix-light-server/admission"
+	"github.com/dev-appmonsters/dicemix-light-server/clock"
+	"github.com/dev-appmonsters/dicemix-light-server/utils"
+)
+
+// creates admission of pools from configuration
+// freshness of proof of work and blind tokens is checked with clock
+func newAdmission(config AdmissionConfig, clock clock.Clock) (admission.Admission, error) {
+	switch config.Mode {
+	case AdmissionProofOfWork:
+		return admission.NewProofOfWork(config.Difficulty, utils.TicketWindow, clock), nil
+	case AdmissionBlindToken:
+		issuer, err := loadIssuerKey(config.IssuerKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		return admission.NewBlindToken(issuer, utils.TokenEpoch, clock), nil
+	}
+	return admission.NewOpen(), nil
+}
+
+// reads PEM encoded RSA public key in PKIX or PKCS1 form
+func loadIssuerKey(path string) (*rsa.PublicKey, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found in " + path)
+	}
+
+	if key, err := x509.ParsePKCS1PublicKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	if rsaKey, ok := key.(*rsa.PublicKey); ok {
+		return rsaKey, nil
+	}
+	return nil, errors.New("issuer key is not an RSA key")
+}
@@ -0,0 +1,103 @@
+package server_test
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dev-appmonsters/dicemix-light-server/admission"
+	"github.com/dev-appmonsters/dicemix-light-server/client"
+	"github.com/dev-appmonsters/dicemix-light-server/messages"
+	"github.com/dev-appmonsters/dicemix-light-server/server"
+	"github.com/dev-appmonsters/dicemix-light-server/utils"
+)
+
+// number of admitted peers in admission tests
+const admittedPeers = 3
+
+type admissionTestPair struct {
+	name string
+	// configures server and returns ticket source of admitted peers
+	setup func(t *testing.T, config *server.Config) func(poolID string) ([]byte, error)
+}
+
+var admissionTests = []admissionTestPair{
+	{"proof of work", func(t *testing.T, config *server.Config) func(string) ([]byte, error) {
+		config.Admission = server.AdmissionConfig{Mode: server.AdmissionProofOfWork, Difficulty: 8}
+		return func(poolID string) ([]byte, error) {
+			// server clock is fake, tickets are stamped with its time
+			return admission.SolveProofOfWork(poolID, 8, time.Unix(1500000000, 0))
+		}
+	}},
+	{"blind token", func(t *testing.T, config *server.Config) func(string) ([]byte, error) {
+		issuer, err := admission.NewMemoryIssuer(1024)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		dir, err := ioutil.TempDir("", "admission")
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { os.RemoveAll(dir) })
+
+		path := filepath.Join(dir, "issuer.pem")
+		key := pem.EncodeToMemory(&pem.Block{Type: "RSA PUBLIC KEY", Bytes: x509.MarshalPKCS1PublicKey(issuer.PublicKey())})
+		if err := ioutil.WriteFile(path, key, 0600); err != nil {
+			t.Fatal(err)
+		}
+
+		config.Admission = server.AdmissionConfig{Mode: server.AdmissionBlindToken, IssuerKeyFile: path}
+		return func(string) ([]byte, error) {
+			// server clock is fake, tokens are stamped with its epoch
+			epoch := admission.TokenEpoch(time.Unix(1500000000, 0), utils.TokenEpoch)
+			token, err := admission.NewBlindedToken(issuer.PublicKey(), epoch)
+			if err != nil {
+				return nil, err
+			}
+			signature, err := issuer.Sign(token.Blinded)
+			if err != nil {
+				return nil, err
+			}
+			return token.Unblind(signature)
+		}
+	}},
+}
+
+func TestAdmission(t *testing.T) {
+	for _, pair := range admissionTests {
+		t.Run(pair.name, func(t *testing.T) {
+			var ticket func(string) ([]byte, error)
+			h := newHarness(t, admittedPeers, func(config *server.Config) {
+				ticket = pair.setup(t, config)
+			})
+			defer h.close()
+
+			// last peer has no ticket
+			configs, msgs := honestPeers(admittedPeers+1, 1)
+			for _, config := range configs[:admittedPeers] {
+				config.Ticket = ticket
+			}
+
+			results := h.run(configs, msgs)
+
+			var serverErr *client.ServerError
+			if err := results[admittedPeers].err; !errors.As(err, &serverErr) || serverErr.Code != messages.E_ADMISSION_REFUSED {
+				t.Error("For", pair.name, "expected", messages.E_ADMISSION_REFUSED, "got", err)
+			}
+
+			included := make([][]byte, 0)
+			for _, res := range results[:admittedPeers] {
+				included = append(included, res.msgs...)
+			}
+			for _, res := range results[:admittedPeers] {
+				h.assertSuccessful(res, included)
+			}
+		})
+	}
+}
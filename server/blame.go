@@ -1,8 +1,12 @@
 package server
 
 import (
+	"encoding/hex"
+
 	"github.com/dev-appmonsters/dicemix-light-server/ecdh"
+	"github.com/dev-appmonsters/dicemix-light-server/evidence"
 	"github.com/dev-appmonsters/dicemix-light-server/field"
+	"github.com/dev-appmonsters/dicemix-light-server/messages"
 	"github.com/dev-appmonsters/dicemix-light-server/nike"
 	"github.com/dev-appmonsters/dicemix-light-server/rng"
 	"github.com/dev-appmonsters/dicemix-light-server/utils"
@@ -27,19 +31,47 @@ type participant struct {
 
 func startBlame(h *hub, sessionID uint64) {
 	var participants = make([]*participant, 0)
-	var roots = iDcNet.SolveDCExponential(h.runs[sessionID].peers)
+	var roots, err = h.dcNet.SolveDCExponential(h.runs[sessionID].peers)
+	if checkError(err) {
+		// peers can still be blamed for their DC-SIMPLE vectors
+		roots = nil
+	}
+	var report = newBlameReport(h, sessionID)
+
+	// peers which have not revealed their KESK
+	for _, peer := range h.runs[sessionID].peers {
+		if !peer.MessageReceived {
+			exclude(report, peer, evidence.MissingKESK, nil)
+		}
+	}
 
 	// identifies honest peers (who have expected protocol messages)
-	participants = initBlame(h, sessionID, participants, roots)
+	participants, err = initBlame(h, sessionID, participants, roots, report)
+	if checkError(err) {
+		terminateWithError(h, sessionID, err.Error())
+		return
+	}
 
 	// identify and exclude peers involved in slot collision
-	if collisions, found := slotCollision(h, sessionID, participants); found {
+	if collisions, found := slotCollision(h, sessionID, participants, report); found {
 		eliminatePeers(collisions, h, sessionID)
 	}
 
+	// persist evidence of excluded peers for audit of disputes
+	saveBlameReport(h, report)
+
+	// ban excluded peers so that they can not rejoin immediately
+	for _, exclusion := range report.Excluded {
+		h.reputation.RecordExclusion(peerSubjects(h, exclusion.PeerID, exclusion.LTPublicKey)...)
+	}
+
 	// removes malicious and offline peers
 	// i.e. those peers who have sent unexpected protocol messages
-	filterPeers(h, sessionID)
+	reasons := make(map[string][]int32)
+	for _, exclusion := range report.Excluded {
+		reasons[string(exclusion.Reason)] = append(reasons[string(exclusion.Reason)], exclusion.PeerID)
+	}
+	filterPeers(h, sessionID, reasons)
 
 	rotateKeys(h, sessionID)
 	broadcastKEResponse(h, sessionID)
@@ -47,7 +79,7 @@ func startBlame(h *hub, sessionID uint64) {
 
 // identifies honest peers (who have expected protocol messages)
 // Exclude peers in next run who have sent unexpected protocol messages
-func initBlame(h *hub, sessionID uint64, participants []*participant, roots []uint64) []*participant {
+func initBlame(h *hub, sessionID uint64, participants []*participant, roots []uint64, report *evidence.Report) ([]*participant, error) {
 	nike := nike.NewNike()
 
 	for i := 0; i < len(h.runs[sessionID].peers); i++ {
@@ -64,6 +96,9 @@ func initBlame(h *hub, sessionID uint64, participants []*participant, roots []ui
 		// if sent wrong keys exclude clients
 		ecdh := ecdh.NewCurve25519ECDH()
 		if ok := ecdh.ValidateKeypair(peer.PrivateKey, peer.PublicKey); !ok {
+			exclude(report, peer, evidence.BadKeypair, map[string]interface{}{
+				"publicKey": hex.EncodeToString(peer.PublicKey),
+			})
 			h.runs[sessionID].peers[i].MessageReceived = false
 			continue
 		}
@@ -86,8 +121,11 @@ func initBlame(h *hub, sessionID uint64, participants []*participant, roots []ui
 
 			// derive sharedSecret with otherPeers
 			var peer = &peerInfo{}
+			var err error
 			peer.ID = otherPeer.Id
-			peer.SharedKey, peer.Dicemix = nike.DeriveSharedKeys(privateKey, otherPeer.PublicKey)
+			if peer.SharedKey, peer.Dicemix, err = nike.DeriveSharedKeys(privateKey, otherPeer.PublicKey); err != nil {
+				return nil, err
+			}
 			totalMsgsCount += int(otherPeer.NumMsgs)
 
 			// append peer to peers of participant
@@ -100,6 +138,10 @@ func initBlame(h *hub, sessionID uint64, participants []*participant, roots []ui
 		// number of msg sent by client and number of msgs he promised to send are not equal
 		// then remove client
 		if uint32(len(messages)) != peer.NumMsgs {
+			exclude(report, peer, evidence.WrongMessageCount, map[string]interface{}{
+				"declared":  peer.NumMsgs,
+				"recovered": len(messages),
+			})
 			h.runs[sessionID].peers[i].MessageReceived = false
 			continue
 		}
@@ -118,7 +160,9 @@ func initBlame(h *hub, sessionID uint64, participants []*participant, roots []ui
 		// check validity of ok sent by client in DC-SIMPLE round
 		// case: if user has sent actual dc-simple-vector and ok=false
 		// then remove client
-		if !ok || (!peer.OK && utils.IsSubset(participant.MessagesHash, roots)) || (ok && utils.ContainBytes(messages, allMessages) && !peer.Confirmation) {
+		if reason, values, found := blameReason(peer, messages, allMessages, participant.MessagesHash, roots, ok); found {
+			exclude(report, peer, reason, values)
+
 			// set peer.MessageReceived to false
 			// so it would be removed by filterPeers()
 			h.runs[sessionID].peers[i].MessageReceived = false
@@ -129,13 +173,13 @@ func initBlame(h *hub, sessionID uint64, participants []*participant, roots []ui
 		participants = append(participants, participant)
 	}
 
-	return participants
+	return participants, nil
 }
 
 // to identify peers who are involved in slot collision
 // Exclude peers who are involved in a slot collision,
 // i.e., a message hash collision
-func slotCollision(h *hub, sessionID uint64, participants []*participant) ([]int32, bool) {
+func slotCollision(h *hub, sessionID uint64, participants []*participant, report *evidence.Report) ([]int32, bool) {
 	// store id's of peers involved in slot collision
 	var collisions = make([]int32, 0)
 
@@ -163,6 +207,15 @@ func slotCollision(h *hub, sessionID uint64, participants []*participant) ([]int
 			// P_exclude := P_exclude U {p1, p2}
 			collisions = append(collisions, p1.ID)
 			collisions = append(collisions, p2.ID)
+
+			for _, pair := range [][]int32{{p1.ID, p2.ID}, {p2.ID, p1.ID}} {
+				if peer, found := peerInfoByID(h.runs[sessionID].peers, pair[0]); found {
+					exclude(report, peer, evidence.SlotCollision, map[string]interface{}{
+						"collidingPeer": pair[1],
+						"hashes":        slice,
+					})
+				}
+			}
 		}
 	}
 
@@ -200,7 +253,7 @@ func decodeMessages(peers []*peerInfo, messages [][]byte) [][]byte {
 		for j := 0; j < len(messages); j++ {
 			// decodes messages
 			// xor operation - messages[j] = dc_simple_vector[j] + <randomness for chacha20>
-			utils.XorBytes(messages[j], messages[j], peers[i].Dicemix.GetBytes(20))
+			utils.XorBytes(messages[j], messages[j], peers[i].Dicemix.GetBytes(utils.MessageSize))
 		}
 	}
 	return messages
@@ -257,3 +310,89 @@ func rotateKeys(h *hub, sessionID uint64) {
 		h.runs[sessionID].peers[i].NextPublicKey = nil
 	}
 }
+
+// identifies reason for which a participant who has revealed valid KESK
+// and sent expected number of messages should be excluded
+// ok - whether DC-EXP vector corresponds to messages in DC-SIMPLE vector
+func blameReason(peer *messages.PeersInfo, messages, allMessages [][]byte, hashes, roots []uint64, ok bool) (evidence.Reason, map[string]interface{}, bool) {
+	if !ok {
+		return evidence.DCExpMismatch, map[string]interface{}{
+			"dcExpVector": peer.DCVector,
+		}, true
+	}
+
+	// case: if user has sent actual dc-simple-vector and ok=false
+	if !peer.OK && utils.IsSubset(hashes, roots) {
+		return evidence.FalseOK, map[string]interface{}{
+			"messageHashes": hashes,
+			"roots":         roots,
+		}, true
+	}
+
+	// case: user refused confirmation but his msg was in generated Dc-Simple vector
+	if utils.ContainBytes(messages, allMessages) && !peer.Confirmation {
+		return evidence.RefusedConfirmation, map[string]interface{}{
+			"messageHashes": hashes,
+		}, true
+	}
+
+	return "", nil, false
+}
+
+// creates report for BLAME stage of current run
+func newBlameReport(h *hub, sessionID uint64) *evidence.Report {
+	report := &evidence.Report{
+		SessionID: sessionID,
+		Run:       h.runs[sessionID].run,
+		Timestamp: utils.Timestamp(),
+		Peers:     make([]int32, 0),
+		Excluded:  make([]*evidence.Exclusion, 0),
+	}
+
+	if h.runs[sessionID].pool != nil {
+		report.PoolID = h.runs[sessionID].pool.ID
+	}
+
+	for _, peer := range h.runs[sessionID].peers {
+		report.Peers = append(report.Peers, peer.Id)
+	}
+	return report
+}
+
+// records exclusion of peer in report
+// a peer is recorded only once for a reason
+func exclude(report *evidence.Report, peer *messages.PeersInfo, reason evidence.Reason, values map[string]interface{}) {
+	for _, exclusion := range report.Excluded {
+		if exclusion.PeerID == peer.Id && exclusion.Reason == reason {
+			return
+		}
+	}
+
+	report.Excluded = append(report.Excluded, &evidence.Exclusion{
+		PeerID:      peer.Id,
+		LTPublicKey: hex.EncodeToString(peer.LTPublicKey),
+		Reason:      reason,
+		Values:      values,
+	})
+}
+
+// stores report in evidence store
+func saveBlameReport(h *hub, report *evidence.Report) {
+	if err := h.evidence.Save(report); checkError(err) {
+		return
+	}
+	for _, exclusion := range report.Excluded {
+		peerLog(h, report.SessionID, exclusion.PeerID).Info("BLAME - Excluding Peer Reason - ", exclusion.Reason)
+	}
+	runLog(h, report.SessionID).Info("BLAME - Report saved Run - ", report.Run, ", Excluded - ", len(report.Excluded))
+}
+
+// returns info of peer with specified id
+func peerInfoByID(peers []*messages.PeersInfo, id int32) (*messages.PeersInfo, bool) {
+	for _, peer := range peers {
+		if peer.Id == id {
+			return peer, true
+		}
+	}
+	return nil, false
+}
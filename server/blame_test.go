@@ -0,0 +1,110 @@
+package server_test
+
+import (
+	"testing"
+
+	"github.com/dev-appmonsters/dicemix-light-server/client"
+	"github.com/dev-appmonsters/dicemix-light-server/client/adversary"
+	"github.com/dev-appmonsters/dicemix-light-server/events"
+	"github.com/dev-appmonsters/dicemix-light-server/evidence"
+)
+
+// number of peers in blame tests
+const blamePeers = 4
+
+type blameTestPair struct {
+	name     string
+	strategy client.Strategy
+	culprits int
+	reason   evidence.Reason
+}
+
+var blameTests = []blameTestPair{
+	{"missing kesk", adversary.MissingKESK(), 1, evidence.MissingKESK},
+	{"bad keypair", adversary.BadKeypair(), 1, evidence.BadKeypair},
+	{"wrong message count", adversary.WrongMessageCount(), 1, evidence.WrongMessageCount},
+	{"dc-exp mismatch", adversary.DCExpMismatch(), 1, evidence.DCExpMismatch},
+	{"false ok", adversary.FalseOK(), 1, evidence.FalseOK},
+	{"refused confirmation", adversary.RefuseConfirmation(), 1, evidence.RefusedConfirmation},
+	// colluding peers send same message
+	{"slot collision", nil, 2, evidence.SlotCollision},
+}
+
+func TestBlame(t *testing.T) {
+	for _, pair := range blameTests {
+		t.Run(pair.name, func(t *testing.T) {
+			h := newHarness(t, blamePeers)
+			h.timeouts = true
+			defer h.close()
+
+			// last pair.culprits peers misbehave
+			configs, msgs := honestPeers(blamePeers, 1)
+			honest := blamePeers - pair.culprits
+			for i := honest; i < blamePeers; i++ {
+				// culprits can afford to announce an extra message
+				configs[i] = peerConfig(i, 2)
+				configs[i].Strategy = pair.strategy
+				if pair.reason == evidence.SlotCollision {
+					msgs[i] = msgs[honest]
+				}
+			}
+
+			results := h.run(configs, msgs)
+
+			culprits := make(map[int32]bool)
+			included, excluded := make([][]byte, 0), make([][]byte, 0)
+			for _, res := range results[honest:] {
+				if res.err == nil {
+					t.Errorf("peer %d: expected culprit to be removed", res.index)
+				}
+				culprits[res.id] = true
+				excluded = append(excluded, res.msgs...)
+			}
+			for _, res := range results[:honest] {
+				included = append(included, res.msgs...)
+			}
+
+			// honest peers complete in next run without culprits
+			for _, res := range results[:honest] {
+				h.assertSuccessful(res, included)
+				h.assertExcluded(res, excluded)
+			}
+
+			// exactly culprits are excluded for expected reason
+			exclusions := h.exclusions()
+			if len(exclusions) != pair.culprits {
+				t.Fatalf("expected %d exclusions got %d", pair.culprits, len(exclusions))
+			}
+			for _, exclusion := range exclusions {
+				if !culprits[exclusion.PeerID] {
+					t.Errorf("honest peer %d excluded", exclusion.PeerID)
+				}
+				if exclusion.Reason != pair.reason {
+					t.Errorf("peer %d: expected reason %s got %s", exclusion.PeerID, pair.reason, exclusion.Reason)
+				}
+			}
+
+			// events record blame and exclusion of culprits
+			if started := h.eventsOf(events.BlameStarted); len(started) != 1 {
+				t.Errorf("expected single blame got %d", len(started))
+			}
+			excludedPeers := make(map[int32]bool)
+			for _, event := range h.eventsOf(events.PeersExcluded) {
+				if event.Reason != string(pair.reason) {
+					continue
+				}
+				for _, id := range event.Peers {
+					excludedPeers[id] = true
+				}
+			}
+			for id := range culprits {
+				if !excludedPeers[id] {
+					t.Errorf("peer %d: expected peers_excluded event with reason %s", id, pair.reason)
+				}
+			}
+			if succeeded := h.eventsOf(events.RunSucceeded); len(succeeded) != 1 || len(succeeded[0].Peers) != honest {
+				t.Errorf("expected run of %d honest peers to succeed got %v", honest, succeeded)
+			}
+		})
+	}
+}
@@ -3,11 +3,11 @@ package server
 import (
 	"time"
 
+	"github.com/dev-appmonsters/dicemix-light-server/events"
 	"github.com/dev-appmonsters/dicemix-light-server/messages"
 	"github.com/dev-appmonsters/dicemix-light-server/utils"
 
 	"github.com/golang/protobuf/proto"
-	log "github.com/sirupsen/logrus"
 )
 
 // Removes offline peers
@@ -17,7 +17,7 @@ import (
 func broadcastDiceMixResponse(h *hub, sessionID uint64, state uint32, message string, errMessage string) {
 	// removes offline peers
 	// returns true if removed any offline peers
-	res := filterPeers(h, sessionID)
+	res := filterPeers(h, sessionID, nil)
 
 	if res {
 		// if any P_Excluded go back to KE Stage
@@ -27,11 +27,19 @@ func broadcastDiceMixResponse(h *hub, sessionID uint64, state uint32, message st
 		}
 	}
 
+	// peers excluded before KE is complete
+	// leave their share of miner fee to others
+	if state == messages.S_KEY_EXCHANGE {
+		excludeUnderfunded(h, sessionID)
+	}
+
 	// broadcast response to all active peers
+	// fee terms are announced so that peers can refuse them before KE
 	header := responseHeader(state, sessionID, message, errMessage)
 	peers, err := proto.Marshal(&messages.DiceMixResponse{
-		Header: header,
-		Peers:  h.runs[sessionID].peers,
+		Header:   header,
+		Peers:    h.runs[sessionID].peers,
+		FeeTerms: h.runs[sessionID].feeTerms,
 	})
 
 	broadcast(h, sessionID, peers, err, state)
@@ -40,7 +48,7 @@ func broadcastDiceMixResponse(h *hub, sessionID uint64, state uint32, message st
 func broadcastDCSimpleResponse(h *hub, sessionID uint64, state uint32, message string, errMessage string) {
 	// removes offline peers
 	// returns true if removed any offline peers
-	res := filterPeers(h, sessionID)
+	res := filterPeers(h, sessionID, nil)
 
 	if res {
 		// if any P_Excluded go back to KE Stage
@@ -52,14 +60,30 @@ func broadcastDCSimpleResponse(h *hub, sessionID uint64, state uint32, message s
 	}
 
 	count := int(totalMessageCount(h.runs[sessionID].peers))
-	h.runs[sessionID].messages = iDcNet.ResolveDCNet(h.runs[sessionID].peers, count)
+	allMessages, err := h.dcNet.ResolveDCNet(h.runs[sessionID].peers, count)
+	if checkError(err) {
+		terminateWithError(h, sessionID, err.Error())
+		return
+	}
+	h.runs[sessionID].messages = allMessages
+
+	// assemble transaction from resolved messages
+	// messages are validated to be of MessageSize so that
+	// transaction can not be assembled only due to fault of coordinator
+	transaction, err := h.tx.Assemble(h.runs[sessionID].peers, h.runs[sessionID].messages, h.runs[sessionID].feeTerms)
+	if checkError(err) {
+		terminateWithError(h, sessionID, "unable to assemble transaction")
+		return
+	}
+	h.runs[sessionID].transaction = transaction
 
 	// broadcast response to all active peers
 	header := responseHeader(state, sessionID, message, errMessage)
 	peers, err := proto.Marshal(&messages.DCSimpleResponse{
-		Header:   header,
-		Messages: h.runs[sessionID].messages,
-		Peers:    h.runs[sessionID].peers,
+		Header:      header,
+		Messages:    h.runs[sessionID].messages,
+		Peers:       h.runs[sessionID].peers,
+		Transaction: transaction,
 	})
 
 	broadcast(h, sessionID, peers, err, state)
@@ -71,7 +95,7 @@ func broadcastDCSimpleResponse(h *hub, sessionID uint64, state uint32, message s
 func broadcastDCExponentialResponse(h *hub, sessionID uint64, state uint32, message string, errMessage string) {
 	// removes offline peers
 	// returns true if removed any offline peers
-	res := filterPeers(h, sessionID)
+	res := filterPeers(h, sessionID, nil)
 
 	if res {
 		if state == messages.S_EXP_DC_VECTOR {
@@ -81,11 +105,17 @@ func broadcastDCExponentialResponse(h *hub, sessionID uint64, state uint32, mess
 		}
 	}
 
+	roots, err := h.dcNet.SolveDCExponential(h.runs[sessionID].peers)
+	if checkError(err) {
+		terminateWithError(h, sessionID, err.Error())
+		return
+	}
+
 	// broadcast response to all active peers
 	header := responseHeader(state, sessionID, message, errMessage)
 	peers, err := proto.Marshal(&messages.DCExpResponse{
 		Header: header,
-		Roots:  iDcNet.SolveDCExponential(h.runs[sessionID].peers),
+		Roots:  roots,
 	})
 
 	broadcast(h, sessionID, peers, err, state)
@@ -94,11 +124,15 @@ func broadcastDCExponentialResponse(h *hub, sessionID uint64, state uint32, mess
 // creates a new run by broadcast KE Exchange Respose to active peers
 // when previous run has been discarded due to some offline peers
 func broadcastKEResponse(h *hub, sessionID uint64) {
+	// remaining peers share miner fee of excluded peers
+	excludeUnderfunded(h, sessionID)
+
 	// broadcast response to all active peers
 	header := responseHeader(messages.S_KEY_EXCHANGE, sessionID, "Key Exchange Response", "")
 	peers, err := proto.Marshal(&messages.DiceMixResponse{
-		Header: header,
-		Peers:  h.runs[sessionID].peers,
+		Header:   header,
+		Peers:    h.runs[sessionID].peers,
+		FeeTerms: h.runs[sessionID].feeTerms,
 	})
 
 	broadcast(h, sessionID, peers, err, messages.S_KEY_EXCHANGE)
@@ -113,6 +147,13 @@ func broadcastTXDone(h *hub, sessionID uint64) {
 		Header: header,
 	})
 
+	// recorded before peers are informed
+	emit(h, &events.Event{
+		Type:      events.RunSucceeded,
+		SessionID: sessionID,
+		Peers:     peerIDs(h.runs[sessionID].peers),
+	})
+
 	broadcast(h, sessionID, peers, err, messages.S_TX_SUCCESSFUL)
 }
 
@@ -125,6 +166,12 @@ func broadcastKESKRequest(h *hub, sessionID uint64) {
 		Header: header,
 	})
 
+	emit(h, &events.Event{
+		Type:      events.BlameStarted,
+		SessionID: sessionID,
+		Peers:     peerIDs(h.runs[sessionID].peers),
+	})
+
 	broadcast(h, sessionID, peers, err, messages.S_KESK_REQUEST)
 }
 
@@ -138,14 +185,25 @@ func broadcast(h *hub, sessionID uint64, message []byte, err error, statusCode u
 
 	// minimum peer check
 	if len(h.runs[sessionID].peers) < 2 {
-		log.Warn("MinPeers: Less than two peers. SessionId - ", sessionID, ", Peers - ", len(h.runs[sessionID].peers))
+		runLog(h, sessionID).Warn("MinPeers: Less than two peers. Peers - ", len(h.runs[sessionID].peers))
+		emit(h, &events.Event{
+			Type:      events.RunTerminated,
+			SessionID: sessionID,
+			Peers:     peerIDs(h.runs[sessionID].peers),
+			Reason:    "less than two peers",
+		})
 		// terminate run
 		terminate(h, sessionID)
 		return
 	}
 
+	// number of messages of run is fixed once KE is complete
+	if statusCode == messages.S_KEY_EXCHANGE {
+		setReadLimits(h, sessionID)
+	}
+
 	// wait for 1 sec before broadcasting
-	time.Sleep(time.Second)
+	h.clock.Sleep(time.Second)
 
 	for _, peerInfo := range h.runs[sessionID].peers {
 		if client, ok := getClient(h.clients, peerInfo.Id); ok {
@@ -157,21 +215,25 @@ func broadcast(h *hub, sessionID uint64, message []byte, err error, statusCode u
 			}
 		}
 
-		log.Info("SENT: SessionId - ", sessionID, ", ResponseCode - ", statusCode, ", PeerId - ", peerInfo.Id)
+		peerLog(h, sessionID, peerInfo.Id).Info("SENT: ResponseCode - ", statusCode)
 	}
 
 	if statusCode == messages.S_TX_SUCCESSFUL {
 		// run is successful
 		// successfull termination
 		terminate(h, sessionID)
-		log.Info("RUN Successful ", sessionID)
+		runLog(h, sessionID).Info("RUN Successful")
 		return
 	}
 
 	// predict next expected RequestCode from client againts current ResponseCode
 	h.runs[sessionID].nextState = nextState(int(statusCode))
 
-	log.Info("SessionId - ", sessionID, ", Expected Next State - ", h.runs[sessionID].nextState)
+	runLog(h, sessionID).Info("Expected Next State - ", h.runs[sessionID].nextState, ", Peers - ", len(h.runs[sessionID].peers))
+
+	// run can be resumed from this state after restart
+	h.runs[sessionID].response = message
+	saveSnapshot(h, sessionID)
 
 	// registers a go-routine to handle offline peers
 	go registerWorker(h, sessionID, uint32(h.runs[sessionID].nextState), h.runs[sessionID].run)
@@ -181,7 +243,37 @@ func broadcast(h *hub, sessionID uint64, message []byte, err error, statusCode u
 func registerWorker(h *hub, sessionID uint64, statusCode uint32, run int) {
 	select {
 	// wait for responseWait seconds then run registerDelayHandler()
-	case <-time.After(utils.ResponseWait * time.Second):
+	case <-h.clock.After(utils.ResponseWait * time.Second):
 		registerDelayHandler(h, sessionID, int(statusCode), run)
 	}
 }
+
+// informs active peers that run can not be continued
+// and terminates run
+func terminateWithError(h *hub, sessionID uint64, errMessage string) {
+	header := responseHeader(messages.S_RUN_TERMINATED, sessionID, "Run terminated", errMessage)
+	header.ErrorCode = messages.E_INTERNAL
+	response, err := proto.Marshal(&messages.GenericResponse{
+		Header: header,
+	})
+
+	if !checkError(err) {
+		for _, peerInfo := range h.runs[sessionID].peers {
+			if client, ok := getClient(h.clients, peerInfo.Id); ok {
+				select {
+				case client.send <- response:
+				default:
+				}
+			}
+		}
+	}
+
+	runLog(h, sessionID).Warn("RUN Terminated Error - ", errMessage)
+	emit(h, &events.Event{
+		Type:      events.RunTerminated,
+		SessionID: sessionID,
+		Peers:     peerIDs(h.runs[sessionID].peers),
+		Reason:    errMessage,
+	})
+	terminate(h, sessionID)
+}
@@ -0,0 +1,438 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dev-appmonsters/dicemix-light-server/clock"
+	"github.com/dev-appmonsters/dicemix-light-server/events"
+	"github.com/dev-appmonsters/dicemix-light-server/logging"
+	"github.com/dev-appmonsters/dicemix-light-server/tx"
+	"github.com/dev-appmonsters/dicemix-light-server/utils"
+
+	"github.com/btcsuite/btcutil"
+)
+
+// Config - contains configurable parameters of coordinator
+type Config struct {
+	// Network - bitcoin network i.e. mainnet, testnet3, regtest or simnet
+	Network string `json:"network"`
+
+	// Pools - fixed denomination pools offered to clients
+	Pools []PoolConfig `json:"pools"`
+
+	// CoordinatorFee - fee charged by coordinator from every participant
+	CoordinatorFee CoordinatorFee `json:"coordinatorFee"`
+
+	// MinerFee - parameters of miner fee estimation
+	MinerFee MinerFee `json:"minerFee"`
+
+	// EvidencePath - file in which blame reports are persisted
+	// if empty reports are kept in memory
+	EvidencePath string `json:"evidencePath"`
+
+	// EventsPath - file to which protocol events are appended as JSON lines
+	// events are not persisted if empty
+	EventsPath string `json:"eventsPath"`
+
+	// RunStore - persistence of unfinished runs across restarts
+	RunStore RunStoreConfig `json:"runStore"`
+
+	// TLS - certificate of /ws endpoint, plain HTTP is served if not configured
+	TLS TLSConfig `json:"tls"`
+
+	// Tor - listener for onion service, disabled if not configured
+	Tor TorConfig `json:"tor"`
+
+	// Websocket - parameters of connections of peers
+	Websocket WebsocketConfig `json:"websocket"`
+
+	// RateLimits - admission control of connections and long term public keys
+	RateLimits RateLimits `json:"rateLimits"`
+
+	// Admission - tickets required to join pools
+	Admission AdmissionConfig `json:"admission"`
+
+	// Admin - authentication of admin API
+	Admin AdminConfig `json:"admin"`
+
+	// Logging - redaction and retention of logs
+	Logging LoggingConfig `json:"logging"`
+
+	// AuthTokens - tokens accepted in C_JOIN_REQUEST
+	// any peer may join if empty
+	AuthTokens []string `json:"authTokens"`
+
+	// Clock - time used for protocol timeouts
+	// system time if nil
+	Clock clock.Clock `json:"-"`
+
+	// EventSink - additional consumer of protocol events e.g. tests
+	EventSink events.Sink `json:"-"`
+}
+
+// CoordinatorFee - fee charged by coordinator from every participant
+// fee := Flat + (mixed amount * BasisPoints / 10000)
+type CoordinatorFee struct {
+	// Flat - fee (in satoshis) charged per participant
+	Flat uint64 `json:"flat"`
+
+	// BasisPoints - fee charged per participant in basis points of mixed amount
+	BasisPoints uint32 `json:"basisPoints"`
+
+	// Address - address which receives coordinator fee
+	Address string `json:"address"`
+}
+
+// MinerFee - parameters of miner fee estimation
+type MinerFee struct {
+	// BitcoindURL - RPC endpoint of bitcoind used for estimatesmartfee
+	// if empty StaticFeeRate is always used
+	BitcoindURL      string `json:"bitcoindUrl"`
+	BitcoindUser     string `json:"bitcoindUser"`
+	BitcoindPassword string `json:"bitcoindPassword"`
+
+	// ConfTarget - number of blocks within which transaction should confirm
+	ConfTarget uint32 `json:"confTarget"`
+
+	// StaticFeeRate - fee rate (sat/vB) used if bitcoind is not configured or fails
+	StaticFeeRate uint64 `json:"staticFeeRate"`
+}
+
+// TLSConfig - paths of PEM encoded TLS credentials
+// reloaded when server receives SIGHUP
+type TLSConfig struct {
+	CertFile string `json:"certFile"`
+	KeyFile  string `json:"keyFile"`
+
+	// ClientCAFile - CAs which sign certificates of peers
+	// peers are not required to present certificates if empty
+	ClientCAFile string `json:"clientCAFile"`
+
+	// HSTSMaxAge - seconds for which browsers should only use TLS
+	// Strict-Transport-Security is not sent if 0
+	HSTSMaxAge int `json:"hstsMaxAge"`
+}
+
+// TorConfig - listener to which local tor daemon forwards
+// connections of onion service, peers connected via it are anonymous
+type TorConfig struct {
+	// Listen - unix socket e.g. unix:/var/run/dicemix.sock
+	// or loopback address e.g. 127.0.0.1:8084
+	Listen string `json:"listen"`
+
+	// OnionAddress - advertised in server info e.g. xyz.onion
+	OnionAddress string `json:"onionAddress"`
+}
+
+// Enabled - reports if Tor listener is configured
+func (t TorConfig) Enabled() bool {
+	return t.Listen != ""
+}
+
+// AdminConfig - authentication of admin API
+type AdminConfig struct {
+	// Tokens - accepted in Authorization: Bearer <token> header
+	// API is open if empty which is allowed only on loopback addresses
+	Tokens []string `json:"tokens"`
+}
+
+// LoggingConfig - how much of identity of peers is logged
+// and for how long logs are kept
+type LoggingConfig struct {
+	// Level - one of full, pseudonymous or minimal
+	// pseudonymous if not configured, full if set to empty string
+	Level logging.Level `json:"level"`
+
+	// Path - logs are written to a file per day i.e. path.YYYY-MM-DD
+	// if empty logs are written to stderr
+	Path string `json:"path"`
+
+	// RetentionDays - files of days which ended before are removed
+	// files are kept forever if 0
+	RetentionDays int `json:"retentionDays"`
+}
+
+// Retention - age after which log files are removed
+func (l LoggingConfig) Retention() time.Duration {
+	return time.Duration(l.RetentionDays) * 24 * time.Hour
+}
+
+// RunStoreConfig - where snapshots of unfinished runs are kept
+// and how they are handled after a restart
+type RunStoreConfig struct {
+	// Path - file in which snapshots are persisted
+	// if empty snapshots are kept in memory and lost on restart
+	Path string `json:"path"`
+
+	// Resume - restored runs continue once all their peers reconnect
+	// within ResumeWait, otherwise restored runs are aborted at once
+	Resume bool `json:"resume"`
+}
+
+// WebsocketConfig - origin policy, size limits and compression of /ws
+type WebsocketConfig struct {
+	// AllowedOrigins - origins of pages which may connect, e.g. https://example.com
+	// "*" allows every origin, only pages of same host are allowed if empty
+	AllowedOrigins []string `json:"allowedOrigins"`
+
+	// BaseReadLimit - bytes allowed in a message before number of messages
+	// of run is known, vectors of run are allowed SlotReadLimit per message
+	BaseReadLimit int64 `json:"baseReadLimit"`
+
+	// Compression - negotiate per-message deflate with peers
+	Compression bool `json:"compression"`
+}
+
+// RateLimits - token buckets limiting actions of a single source
+// a limit is disabled if its rate or burst is 0
+type RateLimits struct {
+	// ConnectionsPerMinute, ConnectionBurst - /ws connections per remote address
+	ConnectionsPerMinute float64 `json:"connectionsPerMinute"`
+	ConnectionBurst      int     `json:"connectionBurst"`
+
+	// KeysPerMinute, KeyBurst - long term public keys submitted
+	// per remote address and per key
+	KeysPerMinute float64 `json:"keysPerMinute"`
+	KeyBurst      int     `json:"keyBurst"`
+
+	// QueueStatusPerMinute, QueueStatusBurst - C_QUEUE_STATUS requests per peer
+	QueueStatusPerMinute float64 `json:"queueStatusPerMinute"`
+	QueueStatusBurst     int     `json:"queueStatusBurst"`
+
+	// MaxClients - maximum number of concurrently connected peers
+	// unlimited if 0
+	MaxClients int `json:"maxClients"`
+}
+
+// modes of admission
+const (
+	// AdmissionOpen - every peer is admitted
+	AdmissionOpen = ""
+
+	// AdmissionProofOfWork - hashcash like ticket bound to pool id
+	AdmissionProofOfWork = "pow"
+
+	// AdmissionBlindToken - token blindly signed by an external issuer
+	AdmissionBlindToken = "blind"
+)
+
+// AdmissionConfig - sybil resistance of pools
+type AdmissionConfig struct {
+	// Mode - one of AdmissionOpen, AdmissionProofOfWork or AdmissionBlindToken
+	Mode string `json:"mode"`
+
+	// Difficulty - leading zero bits required in proof of work
+	Difficulty int `json:"difficulty"`
+
+	// IssuerKeyFile - PEM encoded RSA public key of blind token issuer
+	IssuerKeyFile string `json:"issuerKeyFile"`
+}
+
+// Enabled - reports if TLS is configured
+func (t TLSConfig) Enabled() bool {
+	return t.CertFile != ""
+}
+
+// PoolConfig - contains parameters of a pool of fixed
+// denomination and script type
+type PoolConfig struct {
+	ID string `json:"id"`
+
+	// Denomination - amount (in satoshis) mixed by every peer
+	Denomination uint64 `json:"denomination"`
+
+	// ScriptType - type of output script, e.g. P2WPKH
+	ScriptType string `json:"scriptType"`
+
+	// MinPeers - number of peers required to start DiceMix protocol
+	MinPeers int `json:"minPeers"`
+
+	// MaxPeers - maximum number of peers in a single run
+	MaxPeers int `json:"maxPeers"`
+}
+
+// ValidateAdmin - checks that admin API served at address
+// can not be reached without a token by other hosts
+// and that tokens are not sent to it in clear
+func (c *Config) ValidateAdmin(address string) error {
+	if !c.AdminTLS(address) {
+		return nil
+	}
+	if len(c.Admin.Tokens) == 0 {
+		return errors.New("admin API on non loopback address requires tokens")
+	}
+	if !c.TLS.Enabled() {
+		return errors.New("admin API on non loopback address requires TLS")
+	}
+	return nil
+}
+
+// AdminTLS - reports if admin API served at address
+// is reachable by other hosts and so is served over TLS
+func (c *Config) AdminTLS(address string) bool {
+	return !loopback(address)
+}
+
+// DefaultConfig returns configuration with default pools
+// of 0.01, 0.1 and 1 BTC with P2WPKH outputs
+func DefaultConfig() *Config {
+	return &Config{
+		Pools: []PoolConfig{
+			newPoolConfig(1000000, utils.P2WPKH),
+			newPoolConfig(10000000, utils.P2WPKH),
+			newPoolConfig(100000000, utils.P2WPKH),
+		},
+		MinerFee: MinerFee{
+			ConfTarget:    utils.ConfTarget,
+			StaticFeeRate: utils.StaticFeeRate,
+		},
+		Websocket: WebsocketConfig{
+			BaseReadLimit: utils.BaseReadLimit,
+		},
+		Logging: LoggingConfig{
+			Level: logging.Pseudonymous,
+		},
+		RateLimits: RateLimits{
+			ConnectionsPerMinute: utils.ConnectionsPerMinute,
+			ConnectionBurst:      utils.ConnectionBurst,
+			KeysPerMinute:        utils.KeysPerMinute,
+			KeyBurst:             utils.KeyBurst,
+			QueueStatusPerMinute: utils.QueueStatusPerMinute,
+			QueueStatusBurst:     utils.QueueStatusBurst,
+			MaxClients:           utils.MaxClients,
+		},
+	}
+}
+
+// LoadConfig reads configuration from a JSON file
+// parameters not present in file are set to their defaults
+func LoadConfig(path string) (*Config, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	config := DefaultConfig()
+	config.Pools = nil
+	if err := json.Unmarshal(data, config); err != nil {
+		return nil, err
+	}
+
+	// atleast one pool is required for clients to wait in
+	if len(config.Pools) == 0 {
+		config.Pools = DefaultConfig().Pools
+	}
+
+	for i := range config.Pools {
+		config.Pools[i].setDefaults()
+	}
+	return config, nil
+}
+
+// Validate checks if configuration is consistent
+func (c *Config) Validate() error {
+	params, err := tx.NetParams(c.Network)
+	if err != nil {
+		return err
+	}
+
+	if len(c.Pools) == 0 {
+		return errors.New("no pools configured")
+	}
+
+	ids := make(map[string]bool)
+	for _, pool := range c.Pools {
+		if _, err := tx.OutputScript(pool.ScriptType, make([]byte, 20)); err != nil {
+			return err
+		}
+
+		// peers choose pools by id
+		if ids[pool.ID] {
+			return errors.New("duplicate pool id " + pool.ID)
+		}
+		ids[pool.ID] = true
+
+		if pool.MinPeers < 2 || pool.MinPeers > pool.MaxPeers || pool.MaxPeers > utils.MaxPeers {
+			return errors.New("pool " + pool.ID + " requires 2 <= minPeers <= maxPeers <= " + strconv.Itoa(utils.MaxPeers))
+		}
+	}
+
+	if c.TLS.Enabled() != (c.TLS.KeyFile != "") {
+		return errors.New("TLS requires both certificate and key")
+	}
+	if c.TLS.ClientCAFile != "" && !c.TLS.Enabled() {
+		return errors.New("client certificates require TLS")
+	}
+
+	if c.Tor.Enabled() && !strings.HasPrefix(c.Tor.Listen, unixPrefix) && !loopback(c.Tor.Listen) {
+		return errors.New("tor listener should be a unix socket or loopback address")
+	}
+	if c.Tor.OnionAddress != "" && !strings.HasSuffix(c.Tor.OnionAddress, ".onion") {
+		return errors.New("invalid onion address " + c.Tor.OnionAddress)
+	}
+
+	switch c.Admission.Mode {
+	case AdmissionOpen:
+	case AdmissionProofOfWork:
+		if c.Admission.Difficulty < 1 || c.Admission.Difficulty > 64 {
+			return errors.New("proof of work difficulty should be between 1 and 64 bits")
+		}
+	case AdmissionBlindToken:
+		if c.Admission.IssuerKeyFile == "" {
+			return errors.New("blind tokens require issuer key")
+		}
+	default:
+		return errors.New("unknown admission mode " + c.Admission.Mode)
+	}
+
+	if _, err := logging.NewRedactor(c.Logging.Level); err != nil {
+		return err
+	}
+	if c.Logging.RetentionDays < 0 {
+		return errors.New("log retention can not be negative")
+	}
+	if c.Logging.RetentionDays > 0 && c.Logging.Path == "" {
+		return errors.New("log retention requires log path")
+	}
+
+	if c.Websocket.BaseReadLimit <= 0 {
+		return errors.New("websocket read limit should be positive")
+	}
+
+	if c.CoordinatorFee.Flat == 0 && c.CoordinatorFee.BasisPoints == 0 {
+		return nil
+	}
+
+	// coordinator fee requires an address to be paid to
+	if c.CoordinatorFee.BasisPoints > 10000 {
+		return errors.New("coordinator fee can not exceed 10000 basis points")
+	}
+	if _, err := btcutil.DecodeAddress(c.CoordinatorFee.Address, params); err != nil {
+		return errors.New("invalid coordinator fee address " + c.CoordinatorFee.Address)
+	}
+	return nil
+}
+
+func newPoolConfig(denomination uint64, scriptType string) PoolConfig {
+	config := PoolConfig{Denomination: denomination, ScriptType: scriptType}
+	config.setDefaults()
+	return config
+}
+
+// fills parameters which are not specified
+func (p *PoolConfig) setDefaults() {
+	if p.ID == "" {
+		p.ID = poolID(p.Denomination, p.ScriptType)
+	}
+	if p.MinPeers == 0 {
+		p.MinPeers = utils.MinPeers
+	}
+	if p.MaxPeers == 0 {
+		p.MaxPeers = utils.MaxPeers
+	}
+}
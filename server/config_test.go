@@ -0,0 +1,65 @@
+package server_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dev-appmonsters/dicemix-light-server/server"
+	"github.com/dev-appmonsters/dicemix-light-server/utils"
+)
+
+type poolsConfigTestPair struct {
+	name  string
+	pools []server.PoolConfig
+	valid bool
+}
+
+var poolsConfigTests = []poolsConfigTestPair{
+	{"default", server.DefaultConfig().Pools, true},
+	{"bounds", []server.PoolConfig{{ID: "a", ScriptType: utils.P2WPKH, MinPeers: 2, MaxPeers: utils.MaxPeers}}, true},
+	{"duplicate id", []server.PoolConfig{
+		{ID: "a", ScriptType: utils.P2WPKH, MinPeers: 3, MaxPeers: 5},
+		{ID: "a", ScriptType: utils.P2PKH, MinPeers: 3, MaxPeers: 5},
+	}, false},
+	{"single peer", []server.PoolConfig{{ID: "a", ScriptType: utils.P2WPKH, MinPeers: 1, MaxPeers: 5}}, false},
+	{"min above max", []server.PoolConfig{{ID: "a", ScriptType: utils.P2WPKH, MinPeers: 6, MaxPeers: 5}}, false},
+	{"max above limit", []server.PoolConfig{{ID: "a", ScriptType: utils.P2WPKH, MinPeers: 3, MaxPeers: utils.MaxPeers + 1}}, false},
+}
+
+func TestValidatePools(t *testing.T) {
+	for _, pair := range poolsConfigTests {
+		config := server.DefaultConfig()
+		config.Pools = pair.pools
+		if err := config.Validate(); (err == nil) != pair.valid {
+			t.Error("For", pair.name, "expected valid", pair.valid, "got", err)
+		}
+	}
+}
+
+func TestLoadConfigPools(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// unspecified bounds are defaulted, invalid ones are kept to be refused
+	path := filepath.Join(dir, "config.json")
+	data := `{"pools": [{"denomination": 1000000, "scriptType": "P2WPKH"}, {"denomination": 2000000, "scriptType": "P2WPKH", "minPeers": 1}]}`
+	if err := ioutil.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	config, err := server.LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pool := config.Pools[0]; pool.MinPeers != utils.MinPeers || pool.MaxPeers != utils.MaxPeers {
+		t.Error("For", "defaults", "expected", utils.MinPeers, utils.MaxPeers, "got", pool.MinPeers, pool.MaxPeers)
+	}
+	if err := config.Validate(); err == nil {
+		t.Error("For", "minPeers 1", "expected", "error", "got", nil)
+	}
+}
@@ -1,42 +1,137 @@
 package server
 
 import (
+	"io"
+	"io/ioutil"
+	"net"
 	"net/http"
+	"net/url"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
-	"github.com/dev-appmonsters/dicemix-light-server/dc"
+	"github.com/dev-appmonsters/dicemix-light-server/events"
+	"github.com/dev-appmonsters/dicemix-light-server/evidence"
+	"github.com/dev-appmonsters/dicemix-light-server/reputation"
+	"github.com/dev-appmonsters/dicemix-light-server/runstore"
 	"github.com/dev-appmonsters/dicemix-light-server/utils"
 
 	"github.com/gorilla/websocket"
 	log "github.com/sirupsen/logrus"
 )
 
-// using expose interfaces
-var iDcNet dc.DC
-
 type connection struct {
-	hub *hub
+	hub      *hub
+	upgrader *websocket.Upgrader
+	close    sync.Once
 	Server
 }
 
 // NewConnection creates a new Server instance
-func NewConnection() Server {
-	iDcNet = dc.NewDCNetwork()
+func NewConnection(config *Config) Server {
+	var err error
+	store := evidence.NewMemoryStore()
+	if config.EvidencePath != "" {
+		if store, err = evidence.NewFileStore(config.EvidencePath); checkError(err) {
+			log.Fatal("Unable to open evidence store ", config.EvidencePath)
+		}
+	}
+
+	hub := newHub(config, store)
+	if config.EventsPath != "" {
+		sink, err := events.NewFileSink(config.EventsPath)
+		if checkError(err) {
+			log.Fatal("Unable to open events log ", config.EventsPath)
+		}
+		hub.events = events.NewMultiSink(hub.events, redactEvents(hub, sink))
+	}
+	if config.RunStore.Path != "" {
+		runStore, err := runstore.NewFileStore(config.RunStore.Path)
+		if checkError(err) {
+			log.Fatal("Unable to open run store ", config.RunStore.Path)
+		}
+
+		// snapshots are written to disk without holding hub
+		hub.runStore = runstore.NewAsyncStore(runStore, utils.SnapshotBuffer)
+	}
+	restoreRuns(hub)
+
+	if hub.admission, err = newAdmission(config.Admission, hub.clock); checkError(err) {
+		log.Fatal("Unable to load admission issuer key ", config.Admission.IssuerKeyFile)
+	}
 
-	hub := newHub()
 	go hub.listener()
+	go feeRateWorker(hub)
 
-	return &connection{hub: hub}
+	return &connection{hub: hub, upgrader: newUpgrader(config)}
+}
+
+func newUpgrader(config *Config) *websocket.Upgrader {
+	return &websocket.Upgrader{
+		ReadBufferSize:    1024,
+		WriteBufferSize:   1024,
+		CheckOrigin:       checkOrigin(config),
+		EnableCompression: config.Websocket.Compression,
+	}
+}
+
+// accepts clients which do not send Origin, pages of same host
+// if no origins are configured and pages of allowed origins
+// pages loaded over plain HTTP are refused if TLS is enabled
+func checkOrigin(config *Config) func(r *http.Request) bool {
+	return func(r *http.Request) bool {
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			return true
+		}
+
+		u, err := url.Parse(origin)
+		if err != nil {
+			return false
+		}
+		if config.TLS.Enabled() && u.Scheme != "https" {
+			return false
+		}
+
+		if len(config.Websocket.AllowedOrigins) == 0 {
+			return strings.EqualFold(u.Host, r.Host)
+		}
+
+		for _, allowed := range config.Websocket.AllowedOrigins {
+			if allowed == "*" || strings.EqualFold(allowed, u.Scheme+"://"+u.Host) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// headers sent along with websocket handshake response
+func upgradeHeader(config *Config) http.Header {
+	header := http.Header{}
+	if config.TLS.Enabled() && config.TLS.HSTSMaxAge > 0 {
+		header.Set("Strict-Transport-Security", "max-age="+strconv.Itoa(config.TLS.HSTSMaxAge))
+	}
+	return header
 }
 
 // Register handles websocket requests from the peer.
 func (s *connection) Register(w http.ResponseWriter, r *http.Request) {
-	conn, err := upgrader.Upgrade(w, r, nil)
+	ip := remoteIP(r)
+	if status := s.hub.admit(ip); status != http.StatusOK {
+		http.Error(w, http.StatusText(status), status)
+		return
+	}
+
+	conn, err := s.upgrader.Upgrade(w, r, upgradeHeader(s.hub.config))
 	if err != nil {
 		log.Error("Error:- ", err)
 		return
 	}
-	client := &client{hub: s.hub, conn: conn, send: make(chan []byte, 256)}
+	client := &client{hub: s.hub, conn: conn, send: make(chan []byte, 256), ip: ip, readLimit: s.hub.config.Websocket.BaseReadLimit}
 	client.hub.register <- client
 
 	// Allow collection of memory referenced by the caller by doing all work in
@@ -45,6 +140,37 @@ func (s *connection) Register(w http.ResponseWriter, r *http.Request) {
 	go client.readMessage()
 }
 
+// Close stops background workers of server
+func (s *connection) Close() {
+	s.close.Do(func() {
+		close(s.hub.done)
+	})
+}
+
+// refuses connections of remote address which connects too often
+// and connections beyond MaxClients before websocket upgrade
+// returns http status of refusal or http.StatusOK
+func (h *hub) admit(ip string) int {
+	if ip != "" && !h.connections.Allow(reputation.IPSubject(ip)) {
+		subjectLog(h, reputation.IPSubject(ip)).Info("USER REGISTRATION - Rate Limited Address")
+		h.metrics.Inc("rejected_connections_rate_limit_total")
+		return http.StatusTooManyRequests
+	}
+
+	h.Lock()
+	connected := len(h.clients) + len(h.pending)
+	h.Unlock()
+
+	if max := h.config.RateLimits.MaxClients; max > 0 && connected >= max {
+		log.Info("USER REGISTRATION - Max Clients ", max)
+		h.metrics.Inc("rejected_connections_max_clients_total")
+		return http.StatusServiceUnavailable
+	}
+
+	h.metrics.Inc("connections_total")
+	return http.StatusOK
+}
+
 // readMessage pumps messages from the websocket connection to the hub.
 //
 // The application runs readMessage in a per-connection goroutine. The application
@@ -58,14 +184,50 @@ func (c *client) readMessage() {
 	c.conn.SetReadDeadline(time.Now().Add(utils.PongWait))
 	c.conn.SetPongHandler(func(string) error { c.conn.SetReadDeadline(time.Now().Add(utils.PongWait)); return nil })
 	for {
-		_, message, err := c.conn.ReadMessage()
+		message, err := c.nextMessage()
 		if err != nil {
 			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
 				log.Error("Error:- ", err)
 			}
 			break
 		}
-		c.hub.request <- message
+		c.hub.request <- &clientRequest{client: c, message: message}
+	}
+}
+
+// reads next message of peer within read limit of peer
+// limit applies to decompressed message as well
+// so that compressed messages can not exceed it
+func (c *client) nextMessage() ([]byte, error) {
+	limit := atomic.LoadInt64(&c.readLimit)
+	c.conn.SetReadLimit(limit)
+
+	_, reader, err := c.conn.NextReader()
+	if err != nil {
+		return nil, err
+	}
+
+	message, err := ioutil.ReadAll(io.LimitReader(reader, limit+1))
+	if err != nil {
+		return nil, err
+	}
+
+	if int64(len(message)) > limit {
+		log.Info("Recv: Message exceeds read limit ", limit)
+		c.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseMessageTooBig, ""), time.Now().Add(utils.WriteWait))
+		return nil, websocket.ErrReadLimit
+	}
+	return message, nil
+}
+
+// allows peers of run to send vectors of all messages of run
+// set once number of messages of run is known
+func setReadLimits(h *hub, sessionID uint64) {
+	limit := h.config.Websocket.BaseReadLimit + int64(totalMessageCount(h.runs[sessionID].peers))*utils.SlotReadLimit
+	for _, peer := range h.runs[sessionID].peers {
+		if client, ok := getClient(h.clients, peer.Id); ok {
+			atomic.StoreInt64(&client.readLimit, limit)
+		}
 	}
 }
 
@@ -90,20 +252,9 @@ func (c *client) writeMessage() {
 				return
 			}
 
-			w, err := c.conn.NextWriter(websocket.BinaryMessage)
-			if err != nil {
-				return
-			}
-			w.Write(message)
-
-			// Add queued chat messages to the current websocket message.
-			n := len(c.send)
-			for i := 0; i < n; i++ {
-				w.Write(utils.Newline)
-				w.Write(<-c.send)
-			}
-
-			if err := w.Close(); err != nil {
+			// every protobuf message is sent in its own websocket message
+			// as binary messages can not be delimited by newline
+			if err := c.conn.WriteMessage(websocket.BinaryMessage, message); err != nil {
 				return
 			}
 		case <-ticker.C:
@@ -129,6 +280,8 @@ func terminate(h *hub, sessionID uint64) {
 
 	// remove run info
 	delete(h.runs, sessionID)
+	checkError(h.runStore.Delete(sessionID))
+	h.redactor.Forget(sessionID)
 }
 
 // remove a peer from set of all peers
@@ -136,12 +289,42 @@ func removePeer(h *hub, id int32) {
 	// if client is offline and not submitted response
 	if client, ok := getClient(h.clients, id); ok {
 		// remove offline peers from clients
-		log.Info("USER UN-REGISTRATION - ", id)
+		peerLog(h, 0, id).Info("USER UN-REGISTRATION")
 		delete(h.clients, client)
 		close(client.send)
 	}
 }
 
+// returns ip address of peer which sent request
+// empty for anonymous peers connected via Tor
+// forwarding headers are never trusted
+func remoteIP(r *http.Request) string {
+	if anonymous, _ := r.Context().Value(torKey{}).(bool); anonymous {
+		return ""
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// aborts session whose handling has panicked
+// so that hub and other sessions keep running
+// deferred while holding lock of hub
+func recoverSession(h *hub, sessionID *uint64) {
+	r := recover()
+	if r == nil {
+		return
+	}
+
+	runLog(h, *sessionID).Error("Recovered: ", r, "\n", string(debug.Stack()))
+	if _, ok := h.runs[*sessionID]; ok {
+		terminateWithError(h, *sessionID, "internal error")
+	}
+}
+
 // checks for any potential errors
 func checkError(err error) bool {
 	if err != nil {
@@ -0,0 +1,171 @@
+package server
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dev-appmonsters/dicemix-light-server/messages"
+	"github.com/dev-appmonsters/dicemix-light-server/ratelimit"
+	"github.com/dev-appmonsters/dicemix-light-server/utils"
+
+	"github.com/gorilla/websocket"
+)
+
+type readLimitTestPair struct {
+	name        string
+	compression bool
+	size        int
+	closed      bool
+}
+
+var readLimitTests = []readLimitTestPair{
+	{"within limit", false, 1024, false},
+	{"oversized", false, 4096, true},
+	{"compressed within limit", true, 1024, false},
+	{"compressed oversized", true, 64 * 1024, true},
+}
+
+func TestReadLimit(t *testing.T) {
+	for _, pair := range readLimitTests {
+		config := DefaultConfig()
+		config.Websocket.BaseReadLimit = 2048
+		config.Websocket.Compression = pair.compression
+
+		connection := NewConnection(config)
+		s := httptest.NewServer(http.HandlerFunc(connection.Register))
+
+		dialer := *websocket.DefaultDialer
+		dialer.EnableCompression = pair.compression
+		conn, _, err := dialer.Dial("ws"+strings.TrimPrefix(s.URL, "http"), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// zeros are compressed well below limit
+		conn.WriteMessage(websocket.BinaryMessage, bytes.Repeat([]byte{0}, pair.size))
+
+		// messages within limit are rejected as malformed requests
+		// oversized messages close connection
+		_, _, err = conn.ReadMessage()
+		if closed := websocket.IsCloseError(err, websocket.CloseMessageTooBig); closed != pair.closed {
+			t.Error("For", pair.name, "expected closed", pair.closed, "got", err)
+		}
+
+		conn.Close()
+		s.Close()
+		connection.Close()
+	}
+}
+
+func TestClose(t *testing.T) {
+	h, _ := newTestHub(messages.C_KEY_EXCHANGE)
+	connection := &connection{hub: h}
+
+	stopped := make(chan struct{})
+	go func() {
+		feeRateWorker(h)
+		close(stopped)
+	}()
+
+	// closing twice does not panic
+	connection.Close()
+	connection.Close()
+
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Error("For", "feeRateWorker", "expected", "stopped", "got", "running")
+	}
+}
+
+func TestWriteMessage(t *testing.T) {
+	// protobuf messages may contain newline bytes
+	queued := [][]byte{{1, '\n', 2}, {'\n'}, {3}}
+
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := newUpgrader(DefaultConfig()).Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		c := &client{conn: conn, send: make(chan []byte, len(queued))}
+		for _, message := range queued {
+			c.send <- message
+		}
+		close(c.send)
+		c.writeMessage()
+	}))
+	defer s.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(s.URL, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	// queued messages are not batched into a single websocket message
+	for _, expected := range queued {
+		messageType, message, err := conn.ReadMessage()
+		if err != nil || messageType != websocket.BinaryMessage || !bytes.Equal(message, expected) {
+			t.Error("For", expected, "expected", expected, "got", message, err)
+		}
+	}
+}
+
+func TestSetReadLimits(t *testing.T) {
+	h, c := newTestHub(messages.C_EXP_DC_VECTOR)
+	c.readLimit = h.config.Websocket.BaseReadLimit
+	h.runs[testSession].peers[0].NumMsgs = 3
+
+	setReadLimits(h, testSession)
+
+	expected := h.config.Websocket.BaseReadLimit + (3+testPeers-1)*utils.SlotReadLimit
+	if limit := atomic.LoadInt64(&c.readLimit); limit != expected {
+		t.Error("For", "5 messages", "expected", expected, "got", limit)
+	}
+}
+
+func TestAdmit(t *testing.T) {
+	h, _ := newTestHub(messages.C_KEY_EXCHANGE)
+	h.config.RateLimits = RateLimits{ConnectionsPerMinute: 1, ConnectionBurst: 2, MaxClients: 3}
+	h.connections = ratelimit.NewTokenBucket(1, 2)
+
+	// burst of address is exhausted
+	for i, expected := range []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests} {
+		if status := h.admit("1.2.3.4"); status != expected {
+			t.Error("For", "connection", i, "expected", expected, "got", status)
+		}
+	}
+
+	// first peer of test hub and two pending connections fill server
+	h.pending[&client{}] = true
+	if status := h.admit("5.6.7.8"); status != http.StatusOK {
+		t.Error("For", "2 clients", "expected", http.StatusOK, "got", status)
+	}
+	h.pending[&client{}] = true
+	if status := h.admit("5.6.7.8"); status != http.StatusServiceUnavailable {
+		t.Error("For", "3 clients", "expected", http.StatusServiceUnavailable, "got", status)
+	}
+
+	counters := h.metrics.Counters()
+	if counters["rejected_connections_rate_limit_total"] != 1 || counters["rejected_connections_max_clients_total"] != 1 {
+		t.Error("For", "metrics", "expected", "1 rejection each", "got", counters)
+	}
+}
+
+func TestMetricsEndpoint(t *testing.T) {
+	h, _ := newTestHub(messages.C_KEY_EXCHANGE)
+	h.metrics.Inc("connections_total")
+
+	recorder := httptest.NewRecorder()
+	s := &connection{hub: h}
+	s.AdminHandler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	if !strings.Contains(recorder.Body.String(), "dicemix_connections_total 1\n") {
+		t.Error("For", "/metrics", "expected", "dicemix_connections_total 1", "got", recorder.Body.String())
+	}
+}
@@ -0,0 +1,57 @@
+package server_test
+
+import (
+	"testing"
+
+	"github.com/dev-appmonsters/dicemix-light-server/events"
+)
+
+type dicemixTestPair struct {
+	numPeers int
+	numMsgs  int
+}
+
+var dicemixTests = []dicemixTestPair{
+	{3, 1},
+	{5, 2},
+	{10, 1},
+	{50, 1},
+}
+
+func TestDiceMix(t *testing.T) {
+	for _, pair := range dicemixTests {
+		h := newHarness(t, pair.numPeers)
+		results := h.runHonest(pair.numPeers, pair.numMsgs)
+
+		// every peer sees messages of all peers in transaction
+		all := make([][]byte, 0)
+		for _, res := range results {
+			all = append(all, res.msgs...)
+		}
+
+		for _, res := range results {
+			h.assertSuccessful(res, all)
+		}
+
+		// a single run of all peers without rejections
+		started, succeeded := h.eventsOf(events.RunStarted), h.eventsOf(events.RunSucceeded)
+		if len(started) != 1 || len(started[0].Peers) != pair.numPeers {
+			t.Error("For", pair, "expected", "run of all peers", "got", started)
+		}
+		if len(succeeded) != 1 || len(started) == 1 && succeeded[0].SessionID != started[0].SessionID {
+			t.Error("For", pair, "expected", "run succeeded", "got", succeeded)
+		}
+		if joined := h.eventsOf(events.PeerJoined); len(joined) != pair.numPeers {
+			t.Error("For", pair, "expected", pair.numPeers, "joined", "got", len(joined))
+		}
+		if rejected := h.eventsOf(events.RequestRejected); len(rejected) != 0 {
+			t.Error("For", pair, "expected", "no rejections", "got", rejected)
+		}
+
+		// LTPK and 4 rounds of every peer
+		if accepted := h.eventsOf(events.RequestAccepted); len(accepted) != 5*pair.numPeers {
+			t.Error("For", pair, "expected", 5*pair.numPeers, "accepted", "got", len(accepted))
+		}
+		h.close()
+	}
+}
@@ -0,0 +1,123 @@
+package server
+
+import (
+	"sort"
+
+	"github.com/dev-appmonsters/dicemix-light-server/events"
+	"github.com/dev-appmonsters/dicemix-light-server/messages"
+	"github.com/dev-appmonsters/dicemix-light-server/metrics"
+)
+
+// reason of peers removed for not responding in time
+const offlineReason = "offline"
+
+// reason of peers removed as their inputs do not cover
+// their share of miner fee once other peers are excluded
+const underfundedReason = "insufficient_funds"
+
+// sink which counts events as <type>_total metrics
+func metricsSink(m metrics.Metrics) events.Sink {
+	return events.SinkFunc(func(event *events.Event) {
+		m.Inc(string(event.Type) + "_total")
+	})
+}
+
+// emits event of coordinator to metrics, subscribers of admin API
+// and configured sinks, fields common to events of a run are filled in
+func emit(h *hub, event *events.Event) {
+	event.Time = h.clock.Now()
+	if r, ok := h.runs[event.SessionID]; ok && event.SessionID != 0 {
+		event.Run = r.run
+		if r.pool != nil {
+			event.PoolID = r.pool.ID
+		}
+	}
+	h.events.Emit(event)
+}
+
+// records rejection of request of peer connected over c
+func emitRejected(h *hub, c *client, id int32, sessionID uint64, err error) {
+	header := &messages.ResponseHeader{}
+	setError(header, err)
+
+	if joined, ok := h.clients[c]; ok && id == 0 {
+		id = joined
+	}
+	emit(h, &events.Event{
+		Type:      events.RequestRejected,
+		SessionID: sessionID,
+		PeerID:    id,
+		ErrorCode: header.ErrorCode,
+		Reason:    header.Err,
+	})
+}
+
+// informs peer that he can not join and records rejection
+// connections which have not joined yet are closed
+// so that they can not retry within JoinWait
+func rejectJoin(h *hub, c *client, id int32, sessionID uint64, err error) {
+	emitRejected(h, c, id, sessionID, err)
+	if sendJoinResponse(h, c, id, "", 0, err) && h.pending[c] {
+		dropClient(h, c)
+	}
+}
+
+// records removal of peers from run
+// peers without reason have been removed for not responding
+func emitExcluded(h *hub, sessionID uint64, removed []int32, reasons map[string][]int32) {
+	grouped := make(map[string][]int32)
+	for reason, ids := range reasons {
+		for _, id := range ids {
+			if containsID(removed, id) {
+				grouped[reason] = append(grouped[reason], id)
+			}
+		}
+	}
+	for _, id := range removed {
+		if !excludedFor(reasons, id) {
+			grouped[offlineReason] = append(grouped[offlineReason], id)
+		}
+	}
+
+	order := make([]string, 0, len(grouped))
+	for reason := range grouped {
+		order = append(order, reason)
+	}
+	sort.Strings(order)
+
+	for _, reason := range order {
+		emit(h, &events.Event{
+			Type:      events.PeersExcluded,
+			SessionID: sessionID,
+			Peers:     grouped[reason],
+			Reason:    reason,
+		})
+	}
+}
+
+// returns ids of peers
+func peerIDs(peers []*messages.PeersInfo) []int32 {
+	ids := make([]int32, 0, len(peers))
+	for _, peer := range peers {
+		ids = append(ids, peer.Id)
+	}
+	return ids
+}
+
+func containsID(ids []int32, id int32) bool {
+	for _, other := range ids {
+		if other == id {
+			return true
+		}
+	}
+	return false
+}
+
+func excludedFor(reasons map[string][]int32, id int32) bool {
+	for _, ids := range reasons {
+		if containsID(ids, id) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,151 @@
+package server
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/dev-appmonsters/dicemix-light-server/events"
+	"github.com/dev-appmonsters/dicemix-light-server/messages"
+	"github.com/dev-appmonsters/dicemix-light-server/tx"
+
+	"github.com/golang/protobuf/proto"
+)
+
+type filterEventsTestPair struct {
+	reasons  map[string][]int32
+	expected []*events.Event
+}
+
+// peers 1 and 3 of test run have not responded
+var filterEventsTests = []filterEventsTestPair{
+	{nil, []*events.Event{
+		{Type: events.PeersExcluded, Peers: []int32{1, 3}, Reason: offlineReason},
+	}},
+	{map[string][]int32{"bad_keypair": {3}}, []*events.Event{
+		{Type: events.PeersExcluded, Peers: []int32{3}, Reason: "bad_keypair"},
+		{Type: events.PeersExcluded, Peers: []int32{1}, Reason: offlineReason},
+	}},
+	// peers which remain in run are not reported
+	{map[string][]int32{"false_ok": {2}, "slot_collision": {1, 3}}, []*events.Event{
+		{Type: events.PeersExcluded, Peers: []int32{1, 3}, Reason: "slot_collision"},
+	}},
+}
+
+// adds a memory sink to events of hub
+func recordEvents(h *hub) events.MemorySink {
+	sink := events.NewMemorySink()
+	h.events = events.NewMultiSink(h.events, sink)
+	return sink
+}
+
+func TestFilterPeersEvents(t *testing.T) {
+	for _, pair := range filterEventsTests {
+		h, _ := newTestHub(messages.C_EXP_DC_VECTOR)
+		sink := recordEvents(h)
+
+		filterPeers(h, testSession, pair.reasons)
+
+		got := sink.Events()
+		if len(got) != len(pair.expected) {
+			t.Error("For", pair.reasons, "expected", len(pair.expected), "events", "got", len(got))
+			continue
+		}
+		for i, event := range got {
+			expected := pair.expected[i]
+			if event.Type != expected.Type || event.SessionID != testSession || event.Reason != expected.Reason || !reflect.DeepEqual(event.Peers, expected.Peers) {
+				t.Error("For", pair.reasons, "expected", expected, "got", event)
+			}
+		}
+
+		if count := h.metrics.Counters()["peers_excluded_total"]; count != uint64(len(pair.expected)) {
+			t.Error("For", pair.reasons, "expected", len(pair.expected), "counted", "got", count)
+		}
+	}
+}
+
+type underfundedTestPair struct {
+	name     string
+	numPeers int
+	excluded []int32
+}
+
+// first peer declares exact change for numPeers peers
+// third peer has been excluded from run of three peers
+var underfundedTests = []underfundedTestPair{
+	{"exact for three peers", testPeers, []int32{1}},
+	{"exact for two peers", testPeers - 1, nil},
+}
+
+// returns input of amount paying to P2WPKH output
+func testInput(index uint32, amount uint64) []*messages.TxInput {
+	return []*messages.TxInput{{
+		TxHash:   bytes.Repeat([]byte{byte(index)}, 32),
+		Index:    index,
+		Amount:   amount,
+		PkScript: append([]byte{0, 20}, make([]byte, 20)...),
+	}}
+}
+
+func TestExcludeUnderfunded(t *testing.T) {
+	for _, pair := range underfundedTests {
+		h, c := newTestHub(messages.C_EXP_DC_VECTOR)
+		sink := recordEvents(h)
+		r := h.runs[testSession]
+
+		// inputs of second peer cover share of miner fee of a single peer
+		r.peers[0].Inputs = testInput(1, 0)
+		r.peers[1].Inputs = testInput(2, r.feeTerms.Denomination*2)
+		share := tx.MinerFeeShare(r.peers[0], r.feeTerms, pair.numPeers)
+		r.peers[0].Inputs[0].Amount = r.feeTerms.Denomination + tx.CoordinatorFee(r.feeTerms, 1) + share
+		r.peers = r.peers[:2]
+
+		excludeUnderfunded(h, testSession)
+
+		if ids := peerIDs(r.peers); len(ids) != 2-len(pair.excluded) || containsID(ids, 1) == (len(pair.excluded) > 0) {
+			t.Error("For", pair.name, "expected", pair.excluded, "excluded", "got", ids)
+		}
+
+		got := sink.Events()
+		if len(pair.excluded) == 0 {
+			if len(got) != 0 {
+				t.Error("For", pair.name, "expected", "no events", "got", got)
+			}
+			continue
+		}
+
+		// excluded peer is informed before being removed
+		response := &messages.GenericResponse{}
+		if err := proto.Unmarshal(<-c.send, response); err != nil || response.Header.ErrorCode != messages.E_INSUFFICIENT_FUNDS {
+			t.Error("For", pair.name, "expected", messages.E_INSUFFICIENT_FUNDS, "got", response.Header, err)
+		}
+		if _, ok := <-c.send; ok {
+			t.Error("For", pair.name, "expected", "closed send channel", "got", ok)
+		}
+
+		excluded := got[len(got)-1]
+		if excluded.Type != events.PeersExcluded || excluded.Reason != underfundedReason || !reflect.DeepEqual(excluded.Peers, pair.excluded) {
+			t.Error("For", pair.name, "expected", pair.excluded, underfundedReason, "got", excluded)
+		}
+	}
+}
+
+func TestRequestRejectedEvent(t *testing.T) {
+	h, c := newTestHub(messages.C_EXP_DC_VECTOR)
+	sink := recordEvents(h)
+
+	sendErrorResponse(h, c, testSession, newRequestError(messages.E_INVALID_VECTOR, "invalid DC-EXP vector length"))
+
+	got := sink.Events()
+	expected := &events.Event{
+		Type:      events.RequestRejected,
+		Time:      h.clock.Now(),
+		SessionID: testSession,
+		PeerID:    1,
+		ErrorCode: messages.E_INVALID_VECTOR,
+		Reason:    "invalid DC-EXP vector length",
+	}
+	if len(got) != 1 || !reflect.DeepEqual(got[0], expected) {
+		t.Error("For", "rejection", "expected", expected, "got", got)
+	}
+}
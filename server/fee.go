@@ -0,0 +1,56 @@
+package server
+
+import (
+	"github.com/dev-appmonsters/dicemix-light-server/fee"
+	"github.com/dev-appmonsters/dicemix-light-server/messages"
+	"github.com/dev-appmonsters/dicemix-light-server/utils"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// creates fee estimator from configuration
+// uses bitcoind if configured with static fee rate as fallback
+func newEstimator(config MinerFee) fee.Estimator {
+	static := fee.NewStaticEstimator(config.StaticFeeRate)
+	if config.BitcoindURL == "" {
+		return static
+	}
+
+	bitcoind := fee.NewBitcoindEstimator(config.BitcoindURL, config.BitcoindUser, config.BitcoindPassword, config.ConfTarget)
+	return fee.NewFallbackEstimator(bitcoind, static)
+}
+
+// periodically estimates fee rate used by newly started runs
+// estimation is done without holding hub lock
+// as it may involve RPC to bitcoind
+// stops once server is closed
+func feeRateWorker(h *hub) {
+	for {
+		feeRate, err := h.estimator.EstimateFeeRate()
+		if !checkError(err) {
+			h.Lock()
+			h.feeRate = feeRate
+			h.Unlock()
+
+			log.Info("Fee Rate - ", feeRate, " sat/vB")
+		}
+
+		select {
+		case <-h.clock.After(utils.FeeRateRefresh):
+		case <-h.done:
+			return
+		}
+	}
+}
+
+// terms announced to peers of a run started from pool
+func feeTerms(pool *pool, coordinatorFee CoordinatorFee, feeRate uint64) *messages.FeeTerms {
+	return &messages.FeeTerms{
+		Denomination:              pool.Denomination,
+		ScriptType:                pool.ScriptType,
+		CoordinatorFeeFlat:        coordinatorFee.Flat,
+		CoordinatorFeeBasisPoints: coordinatorFee.BasisPoints,
+		CoordinatorFeeAddress:     coordinatorFee.Address,
+		FeeRate:                   feeRate,
+	}
+}
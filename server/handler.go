@@ -1,48 +1,105 @@
 package server
 
 import (
+	"encoding/hex"
+	"strconv"
+
+	"github.com/dev-appmonsters/dicemix-light-server/ecdsa"
+	"github.com/dev-appmonsters/dicemix-light-server/events"
 	"github.com/dev-appmonsters/dicemix-light-server/messages"
+	"github.com/dev-appmonsters/dicemix-light-server/reputation"
 	"github.com/dev-appmonsters/dicemix-light-server/utils"
 
+	"github.com/btcsuite/btcd/btcec"
 	"github.com/golang/protobuf/proto"
-	log "github.com/sirupsen/logrus"
 )
 
 // handles any request message from peers
-func handleRequest(message []byte, h *hub) {
+func handleRequest(c *client, message []byte, h *hub) {
 	h.Lock()
 	defer h.Unlock()
 
+	// a panic aborts only session of request
+	var sessionID uint64
+	defer recoverSession(h, &sessionID)
+
 	// decode protobuf sent by peer via network
 	signedRequest := &messages.SignedRequest{}
 	if err := proto.Unmarshal(message, signedRequest); checkError(err) {
+		sendErrorResponse(h, c, 0, newRequestError(messages.E_MALFORMED_REQUEST, "malformed signed request"))
 		return
 	}
 
 	// used to obtain info about peerId, code and sessionID from signedRequest
 	r := &messages.GenericRequest{}
-	if err := proto.Unmarshal(signedRequest.RequestData, r); checkError(err) {
+	if err := proto.Unmarshal(signedRequest.RequestData, r); checkError(err) || r.Header == nil {
+		sendErrorResponse(h, c, 0, newRequestError(messages.E_MALFORMED_REQUEST, "malformed request header"))
 		return
 	}
 
-	// if client has sent his long term public key in message
-	if r.Header.Code == messages.C_LTPK_REQUEST {
-		handleLTSKRequest(signedRequest.RequestData, h)
+	// requests sent before joining a run are not signed
+	// only accept them from connection which owns the id
+	switch r.Header.Code {
+	case messages.C_JOIN_REQUEST, messages.C_LTPK_REQUEST, messages.C_LEAVE, messages.C_QUEUE_STATUS:
+		if !knownSender(h, c, r.Header) {
+			peerLog(h, 0, r.Header.Id).Info("Recv: Unknown Peer Code - ", r.Header.Code)
+			sendErrorResponse(h, c, 0, newRequestError(messages.E_UNKNOWN_PEER, "unknown peer id"))
+			return
+		}
+	}
+
+	switch r.Header.Code {
+	case messages.C_JOIN_REQUEST:
+		// peers of runs restored after restart join their session again
+		if r.Header.SessionId != 0 {
+			handleResumeRequest(c, signedRequest, h)
+			return
+		}
+
+		// if client wants to join a specific pool
+		handleJoinRequest(c, signedRequest.RequestData, h)
+		return
+	case messages.C_LTPK_REQUEST:
+		// if client has sent his long term public key in message
+		handleLTSKRequest(c, signedRequest, h)
+		return
+	case messages.C_LEAVE:
+		handleLeaveRequest(c, r.Header, h)
+		return
+	case messages.C_QUEUE_STATUS:
+		handleQueueStatusRequest(c, r.Header, h)
 		return
 	}
 
 	// checks if peer incorrectly signed message or not
 	// if incorrectly signed discard the message.
+	// checked first so that others learn nothing about sessions
 	if !validateMessage(signedRequest, h, r.Header.Id, r.Header.SessionId) {
-		log.Info("Recv: Wrong Signature Code - ", r.Header.Code, ", PeerId - ", r.Header.Id)
+		peerLog(h, r.Header.SessionId, r.Header.Id).Info("Recv: Wrong Signature Code - ", r.Header.Code)
+		sendErrorResponse(h, c, r.Header.SessionId, newRequestError(messages.E_INVALID_SIGNATURE, "invalid signature"))
+		return
+	}
+
+	runInfo, ok := h.runs[r.Header.SessionId]
+	if !ok {
+		peerLog(h, r.Header.SessionId, r.Header.Id).Info("Recv: Unknown Session Code - ", r.Header.Code)
+		sendErrorResponse(h, c, r.Header.SessionId, newRequestError(messages.E_UNKNOWN_SESSION, "unknown session"))
 		return
 	}
 
-	runInfo := h.runs[r.Header.SessionId]
+	sessionID = r.Header.SessionId
 
 	// check if request from client was one of
 	// the expected Requests or not
-	if runInfo.nextState != int(r.Header.Code) {
+	// restored runs expect requests only once resumed
+	if runInfo.resuming || runInfo.nextState != int(r.Header.Code) {
+		sendErrorResponse(h, c, sessionID, newRequestError(messages.E_UNEXPECTED_REQUEST, "unexpected request"))
+		return
+	}
+
+	// every peer responds once in a round
+	if peer, found := peerInfoByID(runInfo.peers, r.Header.Id); found && peer.MessageReceived {
+		sendErrorResponse(h, c, sessionID, newRequestError(messages.E_DUPLICATE_REQUEST, "duplicate request"))
 		return
 	}
 
@@ -54,69 +111,250 @@ func handleRequest(message []byte, h *hub) {
 		return
 	}
 
+	var request proto.Message
 	switch r.Header.Code {
 	case messages.C_KEY_EXCHANGE:
-		request := &messages.KeyExchangeRequest{}
-		if err := proto.Unmarshal(signedRequest.RequestData, request); checkError(err) {
-			return
-		}
-		handleKeyExchangeRequest(request, h, counter)
-
+		request = &messages.KeyExchangeRequest{}
 	case messages.C_EXP_DC_VECTOR:
-		request := &messages.DCExpRequest{}
-		if err := proto.Unmarshal(signedRequest.RequestData, request); checkError(err) {
-			return
-		}
-		handleDCExponentialRequest(request, h, counter)
-
+		request = &messages.DCExpRequest{}
 	case messages.C_SIMPLE_DC_VECTOR:
-		request := &messages.DCSimpleRequest{}
-		if err := proto.Unmarshal(signedRequest.RequestData, request); checkError(err) {
-			return
-		}
-		handleDCSimpleRequest(request, h, counter)
-
+		request = &messages.DCSimpleRequest{}
 	case messages.C_TX_CONFIRMATION:
-		request := &messages.ConfirmationRequest{}
-		if err := proto.Unmarshal(signedRequest.RequestData, request); checkError(err) {
-			return
-		}
+		request = &messages.ConfirmationRequest{}
+	case messages.C_KESK_RESPONSE:
+		request = &messages.InitiaiteKESKResponse{}
+	default:
+		return
+	}
+
+	if err := proto.Unmarshal(signedRequest.RequestData, request); checkError(err) {
+		sendErrorResponse(h, c, sessionID, newRequestError(messages.E_MALFORMED_REQUEST, "malformed request"))
+		return
+	}
+
+	// reject malformed keys and vectors
+	if err := validateRequest(request, runInfo); err != nil {
+		peerLog(h, sessionID, r.Header.Id).Info("Recv: Invalid Request Code - ", r.Header.Code, ", Error - ", err)
+		sendErrorResponse(h, c, sessionID, err)
+		return
+	}
+
+	emit(h, &events.Event{
+		Type:      events.RequestAccepted,
+		SessionID: sessionID,
+		PeerID:    r.Header.Id,
+		Code:      r.Header.Code,
+	})
+
+	switch request := request.(type) {
+	case *messages.KeyExchangeRequest:
+		handleKeyExchangeRequest(request, h, counter)
+	case *messages.DCExpRequest:
+		handleDCExponentialRequest(request, h, counter)
+	case *messages.DCSimpleRequest:
+		handleDCSimpleRequest(request, h, counter)
+	case *messages.ConfirmationRequest:
 		handleConfirmationRequest(request, h, counter)
+	case *messages.InitiaiteKESKResponse:
+		handleInitiateKESKResponse(request, h, counter)
+	}
+}
 
-	case messages.C_KESK_RESPONSE:
-		request := &messages.InitiaiteKESKResponse{}
-		if err := proto.Unmarshal(signedRequest.RequestData, request); checkError(err) {
+// checks if connection may send unsigned request with header
+// connections which have not joined yet may only join
+func knownSender(h *hub, c *client, header *messages.RequestHeader) bool {
+	if h.pending[c] {
+		return header.Code == messages.C_JOIN_REQUEST
+	}
+
+	id, ok := h.clients[c]
+	return ok && id == header.Id
+}
+
+// validates join request of peer and assigns him an id
+// in waiting queue of pool chosen by him
+// joined peers may use it to move to another pool
+func handleJoinRequest(c *client, message []byte, h *hub) {
+	request := &messages.JoinRequest{}
+	if err := proto.Unmarshal(message, request); checkError(err) {
+		rejectJoin(h, c, 0, 0, newRequestError(messages.E_MALFORMED_REQUEST, "malformed join request"))
+		return
+	}
+
+	if !validAuthToken(h.config.AuthTokens, request.AuthToken) {
+		peerLog(h, 0, request.Header.Id).Info("Recv: handleJoinRequest Unauthorized")
+		rejectJoin(h, c, request.Header.Id, 0, newRequestError(messages.E_UNAUTHORIZED, "invalid auth token"))
+		return
+	}
+
+	version, err := negotiateVersion(request)
+	if err != nil {
+		peerLog(h, 0, request.Header.Id).Info("Recv: handleJoinRequest Unsupported Versions - ", request.Versions)
+		rejectJoin(h, c, request.Header.Id, 0, err)
+		return
+	}
+
+	// peers which do not choose a pool wait in first configured pool
+	poolID := request.PoolId
+	if poolID == "" {
+		poolID = h.poolOrder[0]
+	}
+
+	pool, ok := h.pools[poolID]
+	if !ok {
+		peerLog(h, 0, request.Header.Id).Info("Recv: handleJoinRequest Unknown Pool - ", request.PoolId)
+		rejectJoin(h, c, request.Header.Id, 0, newRequestError(messages.E_UNKNOWN_POOL, "Unknown pool "+request.PoolId))
+		return
+	}
+
+	// tickets are bound to pool which peer waits in
+	// and spent only once pool is known
+	if err := h.admission.Admit(poolID, request.Ticket); err != nil {
+		peerLog(h, 0, request.Header.Id).Info("Recv: handleJoinRequest Admission Refused - ", err)
+		h.metrics.Inc("rejected_tickets_total")
+		rejectJoin(h, c, request.Header.Id, 0, newRequestError(messages.E_ADMISSION_REFUSED, err.Error()))
+		return
+	}
+
+	if h.pending[c] {
+		// generates a random user id for new client
+		userID := utils.RandInt31()
+		if !sendJoinResponse(h, c, userID, pool.ID, version, nil) {
 			return
 		}
-		handleInitiateKESKResponse(request, h, counter)
 
+		delete(h.pending, c)
+		h.clients[c] = userID
+		pool.waitingQueue = append(pool.waitingQueue, &waitingClient{id: userID, version: version})
+
+		peerLog(h, 0, userID).Info("Recv: handleJoinRequest New Peer PoolId - ", pool.ID, ", Version - ", version)
+		emit(h, &events.Event{Type: events.PeerJoined, PoolID: pool.ID, PeerID: userID})
+		return
+	}
+
+	current, waitingClient, found := findWaitingClient(h, request.Header.Id)
+	if !found {
+		// peer is already participating in a run
+		return
+	}
+
+	if current != pool {
+		current.remove(waitingClient.id)
+		pool.waitingQueue = append(pool.waitingQueue, waitingClient)
+	}
+	waitingClient.version = version
+
+	peerLog(h, 0, request.Header.Id).Info("Recv: handleJoinRequest PoolId - ", pool.ID, ", Version - ", version)
+	sendJoinResponse(h, c, request.Header.Id, pool.ID, version, nil)
+
+	// peer may have already sent his long term public key
+	checkPool(h, pool)
+}
+
+// removes peer from waiting queue before a run is started
+// peer is not considered offline and his connection is closed
+func handleLeaveRequest(c *client, header *messages.RequestHeader, h *hub) {
+	pool, _, found := findWaitingClient(h, header.Id)
+	if !found {
+		sendErrorResponse(h, c, 0, newRequestError(messages.E_UNEXPECTED_REQUEST, "not waiting in any pool"))
+		return
+	}
+
+	peerLog(h, 0, header.Id).Info("Recv: handleLeaveRequest PoolId - ", pool.ID)
+	pool.remove(header.Id)
+
+	response, err := proto.Marshal(&messages.GenericResponse{
+		Header: responseHeader(messages.S_LEAVE_RESPONSE, 0, "Left pool "+pool.ID, ""),
+	})
+	if !checkError(err) {
+		sendMessage(h, c, response)
+	}
+
+	removePeer(h, header.Id)
+}
+
+// informs peer about his position in waiting queue
+// and time at which his run is expected to start
+func handleQueueStatusRequest(c *client, header *messages.RequestHeader, h *hub) {
+	pool, _, found := findWaitingClient(h, header.Id)
+	if !found {
+		sendErrorResponse(h, c, 0, newRequestError(messages.E_UNEXPECTED_REQUEST, "not waiting in any pool"))
+		return
+	}
+
+	// status changes only as peers join, polling it faster is refused
+	if !h.queueStatus.Allow(strconv.FormatInt(int64(header.Id), 10)) {
+		h.metrics.Inc("rejected_queue_status_rate_limit_total")
+		sendErrorResponse(h, c, 0, newRequestError(messages.E_RATE_LIMITED, "too many queue status requests"))
+		return
+	}
+
+	response, err := proto.Marshal(queueStatus(h, pool, header.Id))
+	if checkError(err) {
+		return
 	}
+
+	sendMessage(h, c, response)
 }
 
 // obtains PublicKeys and NumberOfMsgs sent by peers
-func handleLTSKRequest(message []byte, h *hub) {
+func handleLTSKRequest(c *client, signedRequest *messages.SignedRequest, h *hub) {
 	request := &messages.LtpkExchangeRequest{}
-	if err := proto.Unmarshal(message, request); checkError(err) {
+	if err := proto.Unmarshal(signedRequest.RequestData, request); checkError(err) {
 		return
 	}
 
-	// TODO: check if public key is valid or not
-	counter := 0
-	for i := 0; i < len(h.waitingQueue); i++ {
-		if len(h.waitingQueue[i].publicKey) > 0 {
-			counter++
-		} else if h.waitingQueue[i].id == request.Header.Id && len(request.PublicKey) > 0 {
-			log.Info("Recv: handleLTSKRequest PeerId - ", request.Header.Id)
-			h.waitingQueue[i].publicKey = request.PublicKey
-			counter++
-		}
+	pool, waitingClient, found := findWaitingClient(h, request.Header.Id)
+	if !found || len(waitingClient.publicKey) > 0 {
+		sendErrorResponse(h, c, 0, newRequestError(messages.E_UNEXPECTED_REQUEST, "long term public key already sent"))
+		return
 	}
 
+	if _, err := btcec.ParsePubKey(request.PublicKey, btcec.S256()); err != nil {
+		sendErrorResponse(h, c, 0, newRequestError(messages.E_INVALID_KEY, "invalid long term public key"))
+		return
+	}
+
+	// request is signed by submitted key to prove its possession
+	// so that tokens of a key are not charged by others
+	if !ecdsa.NewCurveECDSA().Verify(request.PublicKey, signedRequest.RequestData, signedRequest.Signature) {
+		sendErrorResponse(h, c, 0, newRequestError(messages.E_INVALID_SIGNATURE, "invalid signature"))
+		return
+	}
+
+	// refuse keys of addresses and keys submitted too often
+	// so that a single source can not fill waiting queue with sybils
+	key := hex.EncodeToString(request.PublicKey)
+	if (c.ip != "" && !h.keys.Allow(reputation.IPSubject(c.ip))) || !h.keys.Allow(reputation.KeySubject(key)) {
+		peerLog(h, 0, request.Header.Id).Info("Recv: handleLTSKRequest Rate Limited")
+		h.metrics.Inc("rejected_keys_rate_limit_total")
+		pool.remove(request.Header.Id)
+		sendErrorResponse(h, c, 0, newRequestError(messages.E_RATE_LIMITED, "too many keys submitted"))
+		removePeer(h, request.Header.Id)
+		return
+	}
+
+	// refuse peers whose long term public key is banned
+	if until, banned := h.reputation.Banned(reputation.KeySubject(key)); banned {
+		peerLog(h, 0, request.Header.Id).Info("Recv: handleLTSKRequest Banned till ", until)
+		pool.remove(request.Header.Id)
+		rejectJoin(h, c, request.Header.Id, 0, newRequestError(messages.E_BANNED, "Banned till "+until.String()))
+		removePeer(h, request.Header.Id)
+		return
+	}
+
+	peerLog(h, 0, request.Header.Id).Info("Recv: handleLTSKRequest")
+	waitingClient.publicKey = request.PublicKey
+	emit(h, &events.Event{
+		Type:   events.RequestAccepted,
+		PoolID: pool.ID,
+		PeerID: request.Header.Id,
+		Code:   messages.C_LTPK_REQUEST,
+	})
+
 	// if MinPeers have registered and sent their long term public key
 	// create a new dicemix run
-	if counter == utils.MinPeers {
-		h.startDicemix()
-	}
+	checkPool(h, pool)
 }
 
 // obtains PublicKeys and NumberOfMsgs sent by peers
@@ -124,16 +362,14 @@ func handleKeyExchangeRequest(request *messages.KeyExchangeRequest, h *hub, coun
 	sessionID := request.Header.SessionId
 	for i := 0; i < len(h.runs[sessionID].peers); i++ {
 		if h.runs[sessionID].peers[i].Id == request.Header.Id {
-			// TODO: check if public key is valid or not
-			if request.NumMsgs < 1 {
-				return
-			}
-
 			h.runs[sessionID].peers[i].PublicKey = request.PublicKey
 			h.runs[sessionID].peers[i].NumMsgs = request.NumMsgs
+			h.runs[sessionID].peers[i].Inputs = request.Inputs
+			h.runs[sessionID].peers[i].ChangeScript = request.ChangeScript
+			h.runs[sessionID].peers[i].ChangeAmount = request.ChangeAmount
 			h.runs[sessionID].peers[i].MessageReceived = true
 
-			log.Info("Recv: handleKeyExchangeRequest PeerId - ", request.Header.Id)
+			peerLog(h, sessionID, request.Header.Id).Info("Recv: handleKeyExchangeRequest")
 			counter++
 			break
 		}
@@ -148,17 +384,12 @@ func handleKeyExchangeRequest(request *messages.KeyExchangeRequest, h *hub, coun
 // obtains DC-EXP vector sent by peers
 func handleDCExponentialRequest(request *messages.DCExpRequest, h *hub, counter int) {
 	sessionID := request.Header.SessionId
-	msgCount := int(totalMessageCount(h.runs[sessionID].peers))
 	for i := 0; i < len(h.runs[sessionID].peers); i++ {
 		if h.runs[sessionID].peers[i].Id == request.Header.Id {
-			if len(request.DCExpVector) != msgCount {
-				return
-			}
-
 			h.runs[sessionID].peers[i].DCVector = request.DCExpVector
 			h.runs[sessionID].peers[i].MessageReceived = true
 
-			log.Info("Recv: handleDCExponentialRequest PeerId - ", request.Header.Id)
+			peerLog(h, sessionID, request.Header.Id).Info("Recv: handleDCExponentialRequest")
 			counter++
 			break
 		}
@@ -173,19 +404,14 @@ func handleDCExponentialRequest(request *messages.DCExpRequest, h *hub, counter
 // obtains DC-SIMPLE vector sent by peers
 func handleDCSimpleRequest(request *messages.DCSimpleRequest, h *hub, counter int) {
 	sessionID := request.Header.SessionId
-	msgCount := int(totalMessageCount(h.runs[sessionID].peers))
 	for i := 0; i < len(h.runs[sessionID].peers); i++ {
 		if h.runs[sessionID].peers[i].Id == request.Header.Id {
-			if len(request.DCSimpleVector) != msgCount {
-				return
-			}
-
 			h.runs[sessionID].peers[i].DCSimpleVector = request.DCSimpleVector
 			h.runs[sessionID].peers[i].OK = request.MyOk
 			h.runs[sessionID].peers[i].MessageReceived = true
 			h.runs[sessionID].peers[i].NextPublicKey = request.NextPublicKey
 
-			log.Info("Recv: handleDCSimpleRequest PeerId - ", request.Header.Id)
+			peerLog(h, sessionID, request.Header.Id).Info("Recv: handleDCSimpleRequest")
 			counter++
 			break
 		}
@@ -207,7 +433,7 @@ func handleConfirmationRequest(request *messages.ConfirmationRequest, h *hub, co
 			h.runs[sessionID].peers[i].Confirmation = request.Confirmation
 			h.runs[sessionID].peers[i].MessageReceived = true
 
-			log.Info("Recv: Confirmation Request PeerId - ", request.Header.Id)
+			peerLog(h, sessionID, request.Header.Id).Info("Recv: Confirmation Request")
 			counter++
 			break
 		}
@@ -228,7 +454,7 @@ func handleInitiateKESKResponse(request *messages.InitiaiteKESKResponse, h *hub,
 			h.runs[sessionID].peers[i].PrivateKey = request.PrivateKey
 			h.runs[sessionID].peers[i].MessageReceived = true
 
-			log.Info("Recv: handleInitiateKESKResponse PeerId - ", request.Header.Id)
+			peerLog(h, sessionID, request.Header.Id).Info("Recv: handleInitiateKESKResponse")
 			counter++
 			break
 		}
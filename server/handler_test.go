@@ -0,0 +1,616 @@
+package server
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/dev-appmonsters/dicemix-light-server/admission"
+	"github.com/dev-appmonsters/dicemix-light-server/clock"
+	"github.com/dev-appmonsters/dicemix-light-server/dc"
+	"github.com/dev-appmonsters/dicemix-light-server/messages"
+	"github.com/dev-appmonsters/dicemix-light-server/ratelimit"
+	"github.com/dev-appmonsters/dicemix-light-server/tx"
+	"github.com/dev-appmonsters/dicemix-light-server/utils"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/golang/protobuf/proto"
+)
+
+// creates hub with run used by fuzz targets
+func newFuzzHub(nextState int) (*hub, *client) {
+	return newTestHub(nextState)
+}
+
+func FuzzHandleRequest(f *testing.F) {
+	_, kepk := testKeypair(0)
+	seeds := []proto.Message{
+		&messages.KeyExchangeRequest{Header: testHeader(messages.C_KEY_EXCHANGE), PublicKey: kepk, NumMsgs: 1},
+		&messages.KeyExchangeRequest{Header: testHeader(messages.C_KEY_EXCHANGE), PublicKey: kepk[:8], NumMsgs: 1000},
+		&messages.DCExpRequest{Header: testHeader(messages.C_EXP_DC_VECTOR), DCExpVector: []uint64{1, 2, 3}},
+		&messages.DCExpRequest{Header: testHeader(messages.C_EXP_DC_VECTOR), DCExpVector: []uint64{1}},
+		&messages.DCSimpleRequest{Header: testHeader(messages.C_SIMPLE_DC_VECTOR), DCSimpleVector: [][]byte{make([]byte, 20), make([]byte, 20), make([]byte, 20)}, MyOk: true, NextPublicKey: kepk},
+		&messages.DCSimpleRequest{Header: testHeader(messages.C_SIMPLE_DC_VECTOR), DCSimpleVector: [][]byte{{1}, nil, make([]byte, 40)}},
+		&messages.ConfirmationRequest{Header: testHeader(messages.C_TX_CONFIRMATION), Confirmation: true},
+		&messages.InitiaiteKESKResponse{Header: testHeader(messages.C_KESK_RESPONSE), PrivateKey: []byte{1, 2, 3}},
+		&messages.JoinRequest{Header: testHeader(messages.C_JOIN_REQUEST), PoolId: "unknown"},
+		&messages.LtpkExchangeRequest{Header: testHeader(messages.C_LTPK_REQUEST), PublicKey: []byte{2, 3}},
+		&messages.GenericRequest{},
+	}
+
+	for i, seed := range seeds {
+		data, _ := proto.Marshal(seed)
+		f.Add(uint8(i), data, true)
+		f.Add(uint8(i), data, false)
+	}
+
+	f.Fuzz(func(t *testing.T, state uint8, data []byte, signed bool) {
+		h, c := newFuzzHub(messages.C_KEY_EXCHANGE + int(state%5))
+
+		message := data
+		if signed {
+			message = signTestRequest(data)
+		}
+		handleRequest(c, message, h)
+	})
+}
+
+// splits data into DC-EXP vector elements
+func fuzzDCExpVector(data []byte) []uint64 {
+	vector := make([]uint64, 0)
+	for len(data) > 0 {
+		var buf [8]byte
+		data = data[copy(buf[:], data):]
+		vector = append(vector, binary.LittleEndian.Uint64(buf[:]))
+	}
+	return vector
+}
+
+// splits data into DC-SIMPLE slots
+func fuzzDCSimpleVector(data []byte) [][]byte {
+	vector := make([][]byte, 0)
+	for len(data) > 0 {
+		n := utils.MessageSize
+		if len(data) < n {
+			n = len(data)
+		}
+		vector = append(vector, data[:n])
+		data = data[n:]
+	}
+	return vector
+}
+
+func FuzzInitBlame(f *testing.F) {
+	privateKey, _ := testKeypair(0)
+	f.Add(uint8(1), privateKey, make([]byte, 24), make([]byte, 60), true, false)
+	f.Add(uint8(1), privateKey, make([]byte, 8), make([]byte, 19), false, false)
+	f.Add(uint8(3), []byte{1}, []byte{}, []byte{}, true, true)
+	f.Add(uint8(0), privateKey, make([]byte, 100), make([]byte, 7), false, true)
+
+	f.Fuzz(func(t *testing.T, numMsgs uint8, privateKey, dcExp, dcSimple []byte, ok, confirmation bool) {
+		h, _ := newFuzzHub(messages.C_KESK_RESPONSE)
+		r := h.runs[testSession]
+
+		// first peer reveals fuzzed keys and vectors
+		r.peers[0].NumMsgs = uint32(numMsgs % 4)
+		r.peers[0].PrivateKey = privateKey
+		r.peers[0].DCVector = fuzzDCExpVector(dcExp)
+		r.peers[0].DCSimpleVector = fuzzDCSimpleVector(dcSimple)
+		r.peers[0].OK = ok
+		r.peers[0].Confirmation = confirmation
+		for _, peer := range r.peers {
+			peer.MessageReceived = true
+		}
+
+		roots, _ := h.dcNet.SolveDCExponential(r.peers)
+		report := newBlameReport(h, testSession)
+		participants, err := initBlame(h, testSession, make([]*participant, 0), roots, report)
+		if err != nil {
+			return
+		}
+		slotCollision(h, testSession, participants, report)
+	})
+}
+
+// DC-net which fails while solving DC-EXP vectors
+type panicDC struct {
+	dc.DC
+}
+
+func (d *panicDC) SolveDCExponential([]*messages.PeersInfo) ([]uint64, error) {
+	panic("solver failure")
+}
+
+func TestRecoverSession(t *testing.T) {
+	h, c := newTestHub(messages.C_EXP_DC_VECTOR)
+	h.dcNet = &panicDC{}
+
+	// request of first peer completes DC-EXP round
+	h.runs[testSession].peers[2].MessageReceived = true
+	data, _ := proto.Marshal(&messages.DCExpRequest{
+		Header:      testHeader(messages.C_EXP_DC_VECTOR),
+		DCExpVector: make([]uint64, testPeers),
+	})
+	handleRequest(c, signTestRequest(data), h)
+
+	if _, ok := h.runs[testSession]; ok {
+		t.Error("expected session to be terminated")
+	}
+
+	response := &messages.GenericResponse{}
+	if err := proto.Unmarshal(<-c.send, response); err != nil || response.Header.Code != messages.S_RUN_TERMINATED {
+		t.Error("expected S_RUN_TERMINATED got", response.Header, err)
+	}
+
+	// hub is still usable
+	h.Lock()
+	h.Unlock()
+}
+
+// transaction assembler which fails to assemble transaction
+type failingTX struct {
+	assembled bool
+	tx.TX
+}
+
+func (t *failingTX) Assemble([]*messages.PeersInfo, [][]byte, *messages.FeeTerms) ([]byte, error) {
+	t.assembled = true
+	return nil, errors.New("miner fee below fee rate of run")
+}
+
+func TestAssembleFailure(t *testing.T) {
+	h, c := newTestHub(messages.C_SIMPLE_DC_VECTOR)
+	assembler := &failingTX{}
+	h.tx = assembler
+
+	// request of first peer completes DC-SIMPLE round
+	h.runs[testSession].peers[2].MessageReceived = true
+	handleRequest(c, signedTestRequest(&messages.DCSimpleRequest{
+		Header:         testHeader(messages.C_SIMPLE_DC_VECTOR),
+		DCSimpleVector: [][]byte{make([]byte, 20), make([]byte, 20), make([]byte, 20)},
+		MyOk:           true,
+		NextPublicKey:  make([]byte, utils.KeySize),
+	})(), h)
+
+	if !assembler.assembled {
+		t.Fatal("expected transaction to be assembled")
+	}
+	if _, ok := h.runs[testSession]; ok {
+		t.Error("expected session to be terminated")
+	}
+
+	response := &messages.GenericResponse{}
+	if err := proto.Unmarshal(<-c.send, response); err != nil || response.Header.Code != messages.S_RUN_TERMINATED {
+		t.Error("expected S_RUN_TERMINATED got", response.Header, err)
+	}
+}
+
+func TestConfirmMissingTransaction(t *testing.T) {
+	h, c := newTestHub(messages.C_TX_CONFIRMATION)
+	for _, peer := range h.runs[testSession].peers {
+		peer.MessageReceived = true
+	}
+
+	// run without transaction is never reported successful
+	checkConfirmations(h, testSession)
+
+	response := &messages.GenericResponse{}
+	if err := proto.Unmarshal(<-c.send, response); err != nil || response.Header.Code != messages.S_RUN_TERMINATED {
+		t.Error("expected S_RUN_TERMINATED got", response.Header, err)
+	}
+	if _, ok := h.runs[testSession]; ok {
+		t.Error("expected session to be terminated")
+	}
+}
+
+type errorTestPair struct {
+	name      string
+	nextState int
+	// first peer has already responded in current round
+	responded bool
+	message   func() []byte
+	errorCode uint32
+}
+
+var errorTests = []errorTestPair{
+	{"malformed", messages.C_KEY_EXCHANGE, false, func() []byte { return []byte{0xff, 0xff} }, messages.E_MALFORMED_REQUEST},
+	{"unknown session", messages.C_TX_CONFIRMATION, false, signedTestRequest(&messages.ConfirmationRequest{
+		Header: &messages.RequestHeader{Code: messages.C_TX_CONFIRMATION, SessionId: 2, Id: 1},
+	}), messages.E_UNKNOWN_SESSION},
+	{"unknown session unsigned", messages.C_TX_CONFIRMATION, false, func() []byte {
+		data, _ := proto.Marshal(&messages.ConfirmationRequest{
+			Header: &messages.RequestHeader{Code: messages.C_TX_CONFIRMATION, SessionId: 2, Id: 1},
+		})
+		signature, _ := testLTSK(1).Sign(chainhash.DoubleHashB(data))
+		message, _ := proto.Marshal(&messages.SignedRequest{RequestData: data, Signature: signature.Serialize()})
+		return message
+	}, messages.E_INVALID_SIGNATURE},
+	{"unknown session unknown peer", messages.C_TX_CONFIRMATION, false, signedTestRequest(&messages.ConfirmationRequest{
+		Header: &messages.RequestHeader{Code: messages.C_TX_CONFIRMATION, SessionId: 2, Id: 99},
+	}), messages.E_INVALID_SIGNATURE},
+	{"invalid signature", messages.C_TX_CONFIRMATION, false, func() []byte {
+		data, _ := proto.Marshal(&messages.ConfirmationRequest{Header: testHeader(messages.C_TX_CONFIRMATION)})
+		signature, _ := testLTSK(1).Sign(chainhash.DoubleHashB(data))
+		message, _ := proto.Marshal(&messages.SignedRequest{RequestData: data, Signature: signature.Serialize()})
+		return message
+	}, messages.E_INVALID_SIGNATURE},
+	{"unexpected", messages.C_KESK_RESPONSE, false, signedTestRequest(&messages.ConfirmationRequest{
+		Header: testHeader(messages.C_TX_CONFIRMATION),
+	}), messages.E_UNEXPECTED_REQUEST},
+	{"duplicate", messages.C_TX_CONFIRMATION, true, signedTestRequest(&messages.ConfirmationRequest{
+		Header: testHeader(messages.C_TX_CONFIRMATION),
+	}), messages.E_DUPLICATE_REQUEST},
+	{"message count", messages.C_KEY_EXCHANGE, false, signedTestRequest(&messages.KeyExchangeRequest{
+		Header:    testHeader(messages.C_KEY_EXCHANGE),
+		PublicKey: make([]byte, utils.KeySize),
+	}), messages.E_INVALID_MSG_COUNT},
+	{"key", messages.C_KEY_EXCHANGE, false, signedTestRequest(&messages.KeyExchangeRequest{
+		Header:  testHeader(messages.C_KEY_EXCHANGE),
+		NumMsgs: 1,
+	}), messages.E_INVALID_KEY},
+	{"funds", messages.C_KEY_EXCHANGE, false, signedTestRequest(&messages.KeyExchangeRequest{
+		Header:    testHeader(messages.C_KEY_EXCHANGE),
+		PublicKey: make([]byte, utils.KeySize),
+		NumMsgs:   1,
+	}), messages.E_INSUFFICIENT_FUNDS},
+	{"vector", messages.C_EXP_DC_VECTOR, false, signedTestRequest(&messages.DCExpRequest{
+		Header:      testHeader(messages.C_EXP_DC_VECTOR),
+		DCExpVector: []uint64{1},
+	}), messages.E_INVALID_VECTOR},
+}
+
+func TestErrorResponses(t *testing.T) {
+	for _, pair := range errorTests {
+		h, c := newTestHub(pair.nextState)
+		h.runs[testSession].peers[0].MessageReceived = pair.responded
+
+		handleRequest(c, pair.message(), h)
+
+		response := &messages.GenericResponse{}
+		select {
+		case data := <-c.send:
+			if err := proto.Unmarshal(data, response); err != nil || response.Header.ErrorCode != pair.errorCode {
+				t.Error("For", pair.name, "expected", pair.errorCode, "got", response.Header, err)
+			}
+		default:
+			t.Error("For", pair.name, "expected error response")
+		}
+	}
+}
+
+type joinTestPair struct {
+	name      string
+	request   *messages.JoinRequest
+	errorCode uint32
+}
+
+var joinTests = []joinTestPair{
+	{"default pool", &messages.JoinRequest{AuthToken: "token"}, 0},
+	{"pool", &messages.JoinRequest{PoolId: "10000000-p2wpkh", AuthToken: "token"}, 0},
+	{"unknown pool", &messages.JoinRequest{PoolId: "1-p2pkh", AuthToken: "token"}, messages.E_UNKNOWN_POOL},
+	{"unauthorized", &messages.JoinRequest{}, messages.E_UNAUTHORIZED},
+	{"version", &messages.JoinRequest{Versions: []uint32{7}, AuthToken: "token"}, messages.E_UNSUPPORTED_VERSION},
+}
+
+func TestJoinRequest(t *testing.T) {
+	for _, pair := range joinTests {
+		h, _ := newTestHub(messages.C_KEY_EXCHANGE)
+		h.config.AuthTokens = []string{"other", "token"}
+		c := &client{hub: h, send: make(chan []byte, 256)}
+		h.pending[c] = true
+
+		pair.request.Header = &messages.RequestHeader{Code: messages.C_JOIN_REQUEST}
+		handleRequest(c, signedTestRequest(pair.request)(), h)
+
+		response := &messages.RegisterResponse{}
+		if err := proto.Unmarshal(<-c.send, response); err != nil || response.Header.ErrorCode != pair.errorCode {
+			t.Error("For", pair.name, "expected", pair.errorCode, "got", response.Header, err)
+			continue
+		}
+
+		// rejected peers are neither assigned an id nor enqueued
+		// and their connections are closed after the response
+		pool, _, joined := findWaitingClient(h, response.Id)
+		if joined != (pair.errorCode == 0) || h.pending[c] {
+			t.Error("For", pair.name, "expected joined", pair.errorCode == 0, "got", joined)
+		}
+		if !joined {
+			if _, open := <-c.send; open {
+				t.Error("For", pair.name, "expected closed connection")
+			}
+		}
+		if joined && (h.clients[c] != response.Id || pool.ID != response.PoolId || response.Version != messages.VERSION_1) {
+			t.Error("For", pair.name, "expected", response.Id, response.PoolId, "got", h.clients[c], pool.ID, response.Version)
+		}
+	}
+}
+
+func TestDuplicateInputs(t *testing.T) {
+	h, c := newTestHub(messages.C_KEY_EXCHANGE)
+	input := &messages.TxInput{
+		TxHash:   bytes.Repeat([]byte{1}, 32),
+		Amount:   1 << 40,
+		PkScript: append([]byte{0, 20}, make([]byte, 20)...),
+	}
+	h.runs[testSession].peers[1].Inputs = []*messages.TxInput{input}
+
+	// output declared by another peer can not be spent again
+	handleRequest(c, signedTestRequest(&messages.KeyExchangeRequest{
+		Header:    testHeader(messages.C_KEY_EXCHANGE),
+		PublicKey: make([]byte, utils.KeySize),
+		NumMsgs:   1,
+		Inputs:    []*messages.TxInput{input},
+	})(), h)
+
+	response := &messages.GenericResponse{}
+	select {
+	case data := <-c.send:
+		if err := proto.Unmarshal(data, response); err != nil || response.Header.ErrorCode != messages.E_INSUFFICIENT_FUNDS {
+			t.Error("For duplicate input expected", messages.E_INSUFFICIENT_FUNDS, "got", response.Header, err)
+		}
+	default:
+		t.Error("For duplicate input expected error response")
+	}
+	if h.runs[testSession].peers[0].MessageReceived {
+		t.Error("For duplicate input expected key exchange to be rejected")
+	}
+}
+
+func TestJoinTicket(t *testing.T) {
+	h, _ := newTestHub(messages.C_KEY_EXCHANGE)
+	h.admission = admission.NewProofOfWork(8, utils.TicketWindow, h.clock)
+
+	// peers which do not choose a pool solve tickets for default pool
+	for _, resource := range []string{h.poolOrder[0], ""} {
+		ticket, _ := admission.SolveProofOfWork(resource, 8, h.clock.Now())
+		c := &client{hub: h, send: make(chan []byte, 256)}
+		h.pending[c] = true
+
+		handleRequest(c, signedTestRequest(&messages.JoinRequest{
+			Header: &messages.RequestHeader{Code: messages.C_JOIN_REQUEST},
+			Ticket: ticket,
+		})(), h)
+
+		expected := uint32(0)
+		if resource == "" {
+			expected = messages.E_ADMISSION_REFUSED
+		}
+		response := &messages.RegisterResponse{}
+		if err := proto.Unmarshal(<-c.send, response); err != nil || response.Header.ErrorCode != expected {
+			t.Error("For", "ticket of", resource, "expected", expected, "got", response.Header, err)
+		}
+	}
+}
+
+func TestJoinTimeout(t *testing.T) {
+	h, _ := newTestHub(messages.C_KEY_EXCHANGE)
+	fake := h.clock.(clock.Fake)
+	c := &client{hub: h, send: make(chan []byte, 256)}
+
+	if !h.registration(c) {
+		t.Fatal("expected registration")
+	}
+	for fake.Waiters() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	fake.Advance(utils.JoinWait)
+
+	if _, ok := <-c.send; ok {
+		t.Error("For", "join timeout", "expected", "closed connection", "got", "message")
+	}
+
+	h.Lock()
+	defer h.Unlock()
+	if h.pending[c] {
+		t.Error("For", "join timeout", "expected", false, "got", h.pending[c])
+	}
+}
+
+// adds peer with specified id to waiting queue of first pool
+// peer is ready if he has sent his long term public key
+func addWaitingPeer(h *hub, id int32, ready bool) *client {
+	c := &client{hub: h, send: make(chan []byte, 256)}
+	h.clients[c] = id
+
+	waiting := &waitingClient{id: id, version: messages.VERSION_1}
+	if ready {
+		waiting.publicKey = testLTSK(int(id)).PubKey().SerializeCompressed()
+	}
+
+	pool := h.pools[h.poolOrder[0]]
+	pool.waitingQueue = append(pool.waitingQueue, waiting)
+	return c
+}
+
+func TestLeaveRequest(t *testing.T) {
+	h, running := newTestHub(messages.C_KEY_EXCHANGE)
+	c := addWaitingPeer(h, 10, true)
+
+	handleRequest(c, signedTestRequest(&messages.LeaveRequest{
+		Header: &messages.RequestHeader{Code: messages.C_LEAVE, Id: 10},
+	})(), h)
+
+	response := &messages.GenericResponse{}
+	if err := proto.Unmarshal(<-c.send, response); err != nil || response.Header.Code != messages.S_LEAVE_RESPONSE {
+		t.Error("For", "leave", "expected", messages.S_LEAVE_RESPONSE, "got", response.Header, err)
+	}
+	if _, ok := <-c.send; ok {
+		t.Error("For", "leave", "expected", "closed connection", "got", "message")
+	}
+	if _, _, found := findWaitingClient(h, 10); found {
+		t.Error("For", "leave", "expected", "removed from queue", "got", "waiting")
+	}
+
+	// peers participating in a run can not leave
+	handleRequest(running, signedTestRequest(&messages.LeaveRequest{
+		Header: &messages.RequestHeader{Code: messages.C_LEAVE, Id: 1},
+	})(), h)
+
+	if err := proto.Unmarshal(<-running.send, response); err != nil || response.Header.ErrorCode != messages.E_UNEXPECTED_REQUEST {
+		t.Error("For", "leave run", "expected", messages.E_UNEXPECTED_REQUEST, "got", response.Header, err)
+	}
+}
+
+type queueStatusTestPair struct {
+	name     string
+	id       int32
+	fill     bool
+	position uint32
+	estimate bool
+}
+
+var queueStatusTests = []queueStatusTestPair{
+	{"first", 10, true, 1, true},
+	{"not ready", 11, true, 2, false},
+	{"last", 12, true, 3, true},
+	{"fill not started", 10, false, 1, false},
+}
+
+func TestQueueStatus(t *testing.T) {
+	for _, pair := range queueStatusTests {
+		h, _ := newTestHub(messages.C_KEY_EXCHANGE)
+		clients := map[int32]*client{
+			10: addWaitingPeer(h, 10, true),
+			11: addWaitingPeer(h, 11, false),
+			12: addWaitingPeer(h, 12, true),
+		}
+
+		pool := h.pools[h.poolOrder[0]]
+		if pair.fill {
+			pool.fillStarted = h.clock.Now()
+		}
+
+		c := clients[pair.id]
+		handleRequest(c, signedTestRequest(&messages.QueueStatusRequest{
+			Header: &messages.RequestHeader{Code: messages.C_QUEUE_STATUS, Id: pair.id},
+		})(), h)
+
+		response := &messages.QueueStatusResponse{}
+		if err := proto.Unmarshal(<-c.send, response); err != nil || response.Header.Code != messages.S_QUEUE_STATUS {
+			t.Error("For", pair.name, "expected", messages.S_QUEUE_STATUS, "got", response.Header, err)
+			continue
+		}
+
+		var estimate int64
+		if pair.estimate {
+			estimate = h.clock.Now().Add(utils.PoolFillWait).Unix()
+		}
+		if response.Position != pair.position || response.QueueDepth != 3 || response.ReadyPeers != 2 || response.EstimatedStart != estimate {
+			t.Error("For", pair.name, "expected", pair.position, estimate, "got", response.Position, response.EstimatedStart)
+		}
+	}
+}
+
+func TestQueueStatusRateLimit(t *testing.T) {
+	h, _ := newTestHub(messages.C_KEY_EXCHANGE)
+	h.queueStatus = ratelimit.NewTokenBucket(1, 2)
+	c := addWaitingPeer(h, 10, true)
+
+	// third request in a burst is refused
+	for i, expected := range []uint32{messages.S_QUEUE_STATUS, messages.S_QUEUE_STATUS, messages.S_REQUEST_REJECTED} {
+		handleRequest(c, signedTestRequest(&messages.QueueStatusRequest{
+			Header: &messages.RequestHeader{Code: messages.C_QUEUE_STATUS, Id: 10},
+		})(), h)
+
+		response := &messages.GenericResponse{}
+		if err := proto.Unmarshal(<-c.send, response); err != nil || response.Header.Code != expected {
+			t.Error("For", "request", i, "expected", expected, "got", response.Header, err)
+		}
+	}
+}
+
+func TestSendBufferFull(t *testing.T) {
+	h, _ := newTestHub(messages.C_KEY_EXCHANGE)
+	c := addWaitingPeer(h, 10, true)
+
+	// peer which does not read his messages
+	c.send = make(chan []byte)
+
+	done := make(chan struct{})
+	go func() {
+		handleRequest(c, signedTestRequest(&messages.QueueStatusRequest{
+			Header: &messages.RequestHeader{Code: messages.C_QUEUE_STATUS, Id: 10},
+		})(), h)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("For", "full buffer", "expected", "hub not blocked", "got", "blocked")
+	}
+
+	if _, ok := h.clients[c]; ok {
+		t.Error("For", "full buffer", "expected", "disconnected", "got", "connected")
+	}
+	if _, _, found := findWaitingClient(h, 10); found {
+		t.Error("For", "full buffer", "expected", "removed from queue", "got", "waiting")
+	}
+	if _, ok := <-c.send; ok {
+		t.Error("For", "full buffer", "expected", "closed connection", "got", "message")
+	}
+}
+
+func TestUnregistration(t *testing.T) {
+	h, _ := newTestHub(messages.C_KEY_EXCHANGE)
+	c := addWaitingPeer(h, 10, true)
+
+	h.unregistration(c)
+
+	if _, _, found := findWaitingClient(h, 10); found {
+		t.Error("For", "disconnect", "expected", "removed from queue", "got", "waiting")
+	}
+	if _, ok := h.clients[c]; ok {
+		t.Error("For", "disconnect", "expected", "removed from clients", "got", h.clients[c])
+	}
+}
+
+func TestLTPKRateLimit(t *testing.T) {
+	h, _ := newTestHub(messages.C_KEY_EXCHANGE)
+	h.keys = ratelimit.NewTokenBucket(1, 2)
+
+	// key not proven by signature is refused without being charged
+	c := addWaitingPeer(h, 9, false)
+	handleRequest(c, signedTestRequest(&messages.LtpkExchangeRequest{
+		Header:    &messages.RequestHeader{Code: messages.C_LTPK_REQUEST, Id: 9},
+		PublicKey: testLTSK(1).PubKey().SerializeCompressed(),
+	})(), h)
+	response := &messages.GenericResponse{}
+	if err := proto.Unmarshal(<-c.send, response); err != nil || response.Header.ErrorCode != messages.E_INVALID_SIGNATURE {
+		t.Error("For", "unsigned key", "expected", messages.E_INVALID_SIGNATURE, "got", response.Header, err)
+	}
+
+	// third key of same address is refused
+	for i, expected := range []uint32{0, 0, messages.E_RATE_LIMITED} {
+		id := int32(10 + i)
+		c := addWaitingPeer(h, id, false)
+		c.ip = "1.2.3.4"
+
+		data, _ := proto.Marshal(&messages.LtpkExchangeRequest{
+			Header:    &messages.RequestHeader{Code: messages.C_LTPK_REQUEST, Id: id},
+			PublicKey: testLTSK(i + 1).PubKey().SerializeCompressed(),
+		})
+		signature, _ := testLTSK(i + 1).Sign(chainhash.DoubleHashB(data))
+		message, _ := proto.Marshal(&messages.SignedRequest{RequestData: data, Signature: signature.Serialize()})
+		handleRequest(c, message, h)
+
+		_, _, waiting := findWaitingClient(h, id)
+		if expected == 0 {
+			if !waiting || len(c.send) != 0 {
+				t.Error("For", "key", i, "expected", "accepted", "got", waiting, len(c.send))
+			}
+			continue
+		}
+
+		response := &messages.GenericResponse{}
+		if err := proto.Unmarshal(<-c.send, response); err != nil || response.Header.ErrorCode != expected || waiting {
+			t.Error("For", "key", i, "expected", expected, "got", response.Header, err, waiting)
+		}
+	}
+
+	if h.metrics.Counters()["rejected_keys_rate_limit_total"] != 1 {
+		t.Error("For", "metrics", "expected", 1, "got", h.metrics.Counters())
+	}
+}
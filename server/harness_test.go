@@ -0,0 +1,283 @@
+package server_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dev-appmonsters/dicemix-light-server/client"
+	"github.com/dev-appmonsters/dicemix-light-server/clock"
+	"github.com/dev-appmonsters/dicemix-light-server/events"
+	"github.com/dev-appmonsters/dicemix-light-server/evidence"
+	"github.com/dev-appmonsters/dicemix-light-server/messages"
+	"github.com/dev-appmonsters/dicemix-light-server/server"
+	"github.com/dev-appmonsters/dicemix-light-server/tx"
+	"github.com/dev-appmonsters/dicemix-light-server/utils"
+
+	"github.com/btcsuite/btcutil/psbt"
+	"github.com/golang/protobuf/proto"
+	log "github.com/sirupsen/logrus"
+)
+
+// denomination of pool used by harness
+const denomination = 1000000
+
+// real time after which an idle server is considered
+// to be waiting for offline peers
+const idleWait = 100 * time.Millisecond
+
+// harness runs server in-process and drives simulated peers against it
+type harness struct {
+	t          *testing.T
+	clock      clock.Fake
+	connection server.Server
+	server     *httptest.Server
+	url        string
+
+	// protocol events emitted by server
+	events events.MemorySink
+
+	// expire timeouts of server once it is idle
+	// required when some peers do not respond
+	timeouts bool
+}
+
+// outcome of DiceMix execution of a simulated peer
+type result struct {
+	index       int
+	id          int32
+	msgs        [][]byte
+	transaction []byte
+	err         error
+}
+
+func init() {
+	log.SetLevel(log.WarnLevel)
+}
+
+// starts server with a single pool which starts a run
+// as soon as numPeers have joined, configure alters configuration
+func newHarness(t *testing.T, numPeers int, configure ...func(*server.Config)) *harness {
+	config := server.DefaultConfig()
+	config.Pools = []server.PoolConfig{{
+		ID:           "test",
+		Denomination: denomination,
+		ScriptType:   utils.P2WPKH,
+		MinPeers:     numPeers,
+		MaxPeers:     numPeers,
+	}}
+
+	config.Clock = clock.NewFakeClock(time.Unix(1500000000, 0))
+
+	// all simulated peers connect from same address
+	config.RateLimits = server.RateLimits{}
+
+	sink := events.NewMemorySink()
+	config.EventSink = sink
+
+	for _, c := range configure {
+		c(config)
+	}
+
+	connection := server.NewConnection(config)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ws", connection.Register)
+	s := httptest.NewServer(mux)
+
+	return &harness{t: t, clock: config.Clock.(clock.Fake), connection: connection, server: s, url: "ws" + strings.TrimPrefix(s.URL, "http") + "/ws", events: sink}
+}
+
+func (h *harness) close() {
+	h.server.Close()
+	h.connection.Close()
+}
+
+// returns distinct anonymous message of peer
+func peerMessage(index, j int) []byte {
+	message := bytes.Repeat([]byte{byte(j + 1)}, utils.MessageSize)
+	message[0], message[1] = byte(index), byte(index>>8)
+	return message
+}
+
+// returns configuration of peer with a single input
+// sufficient to pay for his outputs and fees
+func peerConfig(index int, numMsgs int) *client.Config {
+	txHash := bytes.Repeat([]byte{0xab}, 32)
+	txHash[0], txHash[1] = byte(index), byte(index>>8)
+
+	return &client.Config{
+		PoolID: "test",
+		Inputs: []*messages.TxInput{{
+			TxHash:   txHash,
+			Index:    uint32(index),
+			Amount:   uint64(numMsgs)*denomination + 100000,
+			PkScript: append([]byte{0, 20}, make([]byte, 20)...),
+		}},
+	}
+}
+
+// runs peers concurrently till all of them are done
+// configs[i] and msgs[i] are configuration and messages of i-th peer
+func (h *harness) run(configs []*client.Config, msgs [][][]byte) []*result {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	if h.timeouts {
+		done := make(chan struct{})
+		defer close(done)
+		go h.expireTimeouts(done)
+	}
+
+	results := make([]*result, len(configs))
+	var wg sync.WaitGroup
+	for i := range configs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			res := &result{index: i, msgs: msgs[i]}
+			config := *configs[i]
+			config.Strategy = recordID(res, config.Strategy)
+			res.transaction, res.err = client.NewClient(&config).Join(ctx, h.url, msgs[i])
+			results[i] = res
+		}(i)
+	}
+	wg.Wait()
+	return results
+}
+
+// advances clock to earliest timeout of server
+// once clock has not been used for idleWait
+func (h *harness) expireTimeouts(done <-chan struct{}) {
+	now, waiters := h.clock.Now(), h.clock.Waiters()
+	for {
+		select {
+		case <-done:
+			return
+		case <-time.After(idleWait):
+		}
+
+		if h.clock.Now().Equal(now) && h.clock.Waiters() == waiters {
+			if next, ok := h.clock.Next(); ok {
+				h.clock.Advance(next.Sub(now))
+			}
+		}
+		now, waiters = h.clock.Now(), h.clock.Waiters()
+	}
+}
+
+// records id assigned to peer by server from headers of requests
+// and applies strategy of peer
+func recordID(res *result, strategy client.Strategy) client.Strategy {
+	return func(request proto.Message) proto.Message {
+		if r, ok := request.(interface {
+			GetHeader() *messages.RequestHeader
+		}); ok && r.GetHeader() != nil {
+			res.id = r.GetHeader().Id
+		}
+		if strategy != nil {
+			return strategy(request)
+		}
+		return request
+	}
+}
+
+// returns configurations and messages of numPeers
+// honest peers sending numMsgs messages each
+func honestPeers(numPeers, numMsgs int) ([]*client.Config, [][][]byte) {
+	configs := make([]*client.Config, numPeers)
+	msgs := make([][][]byte, numPeers)
+	for i := 0; i < numPeers; i++ {
+		configs[i] = peerConfig(i, numMsgs)
+		for j := 0; j < numMsgs; j++ {
+			msgs[i] = append(msgs[i], peerMessage(i, j))
+		}
+	}
+	return configs, msgs
+}
+
+// runs numPeers honest peers sending numMsgs messages each
+func (h *harness) runHonest(numPeers, numMsgs int) []*result {
+	configs, msgs := honestPeers(numPeers, numMsgs)
+	return h.run(configs, msgs)
+}
+
+// returns exclusions recorded in blame reports through admin API
+func (h *harness) exclusions() []*evidence.Exclusion {
+	recorder := httptest.NewRecorder()
+	h.connection.AdminHandler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/blame", nil))
+
+	reports := make([]*evidence.Report, 0)
+	if err := json.Unmarshal(recorder.Body.Bytes(), &reports); err != nil {
+		h.t.Fatalf("invalid blame reports %v", err)
+	}
+
+	exclusions := make([]*evidence.Exclusion, 0)
+	for _, report := range reports {
+		exclusions = append(exclusions, report.Excluded...)
+	}
+	return exclusions
+}
+
+// returns events of type emitted by server so far
+func (h *harness) eventsOf(eventType events.Type) []*events.Event {
+	matching := make([]*events.Event, 0)
+	for _, event := range h.events.Events() {
+		if event.Type == eventType {
+			matching = append(matching, event)
+		}
+	}
+	return matching
+}
+
+// asserts that peer completed DiceMix and transaction
+// pays denomination to all expected messages
+func (h *harness) assertSuccessful(res *result, expected [][]byte) {
+	if res.err != nil {
+		h.t.Errorf("peer %d: expected S_TX_SUCCESSFUL got %v", res.index, res.err)
+		return
+	}
+
+	packet, err := psbt.NewFromRawBytes(bytes.NewReader(res.transaction), false)
+	if err != nil {
+		h.t.Errorf("peer %d: invalid transaction %v", res.index, err)
+		return
+	}
+
+	for _, message := range expected {
+		script, _ := tx.OutputScript(utils.P2WPKH, message)
+		found := false
+		for _, txOut := range packet.UnsignedTx.TxOut {
+			if bytes.Equal(txOut.PkScript, script) && txOut.Value == denomination {
+				found = true
+				break
+			}
+		}
+		if !found {
+			h.t.Errorf("peer %d: output for message %x missing", res.index, message)
+		}
+	}
+}
+
+// asserts that transaction received by peer
+// does not pay to any of excluded messages
+func (h *harness) assertExcluded(res *result, excluded [][]byte) {
+	packet, err := psbt.NewFromRawBytes(bytes.NewReader(res.transaction), false)
+	if err != nil {
+		return
+	}
+
+	for _, message := range excluded {
+		script, _ := tx.OutputScript(utils.P2WPKH, message)
+		for _, txOut := range packet.UnsignedTx.TxOut {
+			if bytes.Equal(txOut.PkScript, script) {
+				h.t.Errorf("peer %d: output for excluded message %x found", res.index, message)
+			}
+		}
+	}
+}
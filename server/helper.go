@@ -1,8 +1,15 @@
 package server
 
 import (
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+
 	"github.com/dev-appmonsters/dicemix-light-server/ecdsa"
+	"github.com/dev-appmonsters/dicemix-light-server/events"
 	"github.com/dev-appmonsters/dicemix-light-server/messages"
+	"github.com/dev-appmonsters/dicemix-light-server/reputation"
+	"github.com/dev-appmonsters/dicemix-light-server/tx"
 	"github.com/dev-appmonsters/dicemix-light-server/utils"
 
 	"github.com/jinzhu/copier"
@@ -16,6 +23,7 @@ import (
 func registerDelayHandler(h *hub, sessionID uint64, state int, run int) {
 	h.Lock()
 	defer h.Unlock()
+	defer recoverSession(h, &sessionID)
 
 	// if session exists
 	if _, ok := h.runs[sessionID]; !ok {
@@ -27,7 +35,26 @@ func registerDelayHandler(h *hub, sessionID uint64, state int, run int) {
 		return
 	}
 
-	log.Info("Round has not done ", state, ", SessionId - ", sessionID)
+	runLog(h, sessionID).Info("Round has not done ", state)
+
+	// record peers which have not responded in time
+	// peers not revealing KESK are recorded as excluded by BLAME
+	timedOut := make([]int32, 0)
+	for _, peer := range h.runs[sessionID].peers {
+		if peer.MessageReceived {
+			continue
+		}
+		timedOut = append(timedOut, peer.Id)
+		if state != messages.C_KESK_RESPONSE {
+			h.reputation.RecordTimeout(peerSubjects(h, peer.Id, hex.EncodeToString(peer.LTPublicKey))...)
+		}
+	}
+	emit(h, &events.Event{
+		Type:      events.PhaseTimedOut,
+		SessionID: sessionID,
+		Peers:     timedOut,
+		Code:      uint32(state),
+	})
 
 	switch state {
 	case messages.C_KEY_EXCHANGE:
@@ -50,11 +77,13 @@ func registerDelayHandler(h *hub, sessionID uint64, state int, run int) {
 }
 
 // removes offline peers from h.runs[sessionID].peers
+// reasons - ids of peers excluded by BLAME, others are offline
 // returns true if removed any offline peer
-func filterPeers(h *hub, sessionID uint64) bool {
+func filterPeers(h *hub, sessionID uint64, reasons map[string][]int32) bool {
 	var allPeers []*messages.PeersInfo
 	copier.Copy(&allPeers, &h.runs[sessionID].peers)
 	h.runs[sessionID].peers = make([]*messages.PeersInfo, 0)
+	removed := make([]int32, 0)
 
 	for _, peer := range allPeers {
 		// check if client is active and has submitted response
@@ -66,9 +95,46 @@ func filterPeers(h *hub, sessionID uint64) bool {
 
 		// if client is offline and not submitted response
 		removePeer(h, peer.Id)
+		removed = append(removed, peer.Id)
 	}
+
+	if len(removed) > 0 {
+		emitExcluded(h, sessionID, removed, reasons)
+	}
+
 	// removed any offline peer?
-	return len(allPeers) != len(h.runs[sessionID].peers)
+	return len(removed) > 0
+}
+
+// removes peers whose inputs no longer cover their share of miner fee
+// overhead of transaction is shared by fewer peers once others are excluded
+// repeated till inputs of all remaining peers are sufficient
+func excludeUnderfunded(h *hub, sessionID uint64) {
+	r := h.runs[sessionID]
+	for {
+		peers := make([]*messages.PeersInfo, 0, len(r.peers))
+		removed := make([]int32, 0)
+
+		for _, peer := range r.peers {
+			err := tx.ValidateFunds(peer, r.feeTerms, len(r.peers))
+			if err == nil {
+				peers = append(peers, peer)
+				continue
+			}
+
+			if client, ok := getClient(h.clients, peer.Id); ok {
+				sendErrorResponse(h, client, sessionID, newRequestError(messages.E_INSUFFICIENT_FUNDS, err.Error()))
+			}
+			removePeer(h, peer.Id)
+			removed = append(removed, peer.Id)
+		}
+
+		if len(removed) == 0 {
+			return
+		}
+		r.peers = peers
+		emitExcluded(h, sessionID, removed, map[string][]int32{underfundedReason: removed})
+	}
 }
 
 // checks if all peers have submitted a valid confirmation for msgs
@@ -77,7 +143,7 @@ func filterPeers(h *hub, sessionID uint64) bool {
 func checkConfirmations(h *hub, sessionID uint64) {
 	// removes offline peers
 	// returns true if removed any offline peers
-	if res := filterPeers(h, sessionID); res {
+	if res := filterPeers(h, sessionID, nil); res {
 		// if any P_Excluded trace back to KE Stage
 		h.runs[sessionID].run++
 		broadcastKEResponse(h, sessionID)
@@ -91,16 +157,22 @@ func checkConfirmations(h *hub, sessionID uint64) {
 	for _, peer := range h.runs[sessionID].peers {
 		if !peer.Confirmation {
 			// Blame stage - INIT KESK
-			log.Info("BLAME - Peer ", peer.Id, " does'nt provide correct corfirmation")
+			peerLog(h, sessionID, peer.Id).Info("BLAME - Peer does'nt provide correct corfirmation")
 			h.runs[sessionID].run++
 			broadcastKESKRequest(h, sessionID)
 			return
 		}
 
-		log.Info("CONFIRMATION - Peer ", peer.Id, " sent correct confirmation")
+		peerLog(h, sessionID, peer.Id).Info("CONFIRMATION - Peer sent correct confirmation")
 
 	}
 
+	// peers can not have confirmed a transaction which is missing
+	if len(h.runs[sessionID].transaction) == 0 {
+		terminateWithError(h, sessionID, "transaction missing")
+		return
+	}
+
 	// DiceMix is successful
 	broadcastTXDone(h, sessionID)
 }
@@ -125,15 +197,24 @@ func nextState(responseCode int) int {
 
 // checks if peer incorrectly signed message or not
 // if incorrectly signed discard the message.
+// key of peer is looked up in every run if session is unknown
+// so that signature is checked before session of request
 func validateMessage(message *messages.SignedRequest, h *hub, id int32, sessionID uint64) bool {
-	// id session id is valid
-	if _, ok := h.runs[sessionID]; !ok {
-		return false
+	var publicKey []byte
+	var found bool
+	if r, ok := h.runs[sessionID]; ok {
+		publicKey, found = peerPublicKey(r.peers, id)
+	} else {
+		for _, r := range h.runs {
+			if publicKey, found = peerPublicKey(r.peers, id); found {
+				break
+			}
+		}
 	}
 
 	// get long term public key of peer to verify signed message
 	// if publickey found verify message
-	if publicKey, found := publicKey(h.runs[sessionID].peers, id); found {
+	if found {
 		ecdsa := ecdsa.NewCurveECDSA()
 		return ecdsa.Verify(publicKey, message.RequestData, message.Signature)
 	}
@@ -143,7 +224,7 @@ func validateMessage(message *messages.SignedRequest, h *hub, id int32, sessionI
 // return long term public key of peer with specified id
 // if found -> returns publickey, true
 // else -> returns nil, false
-func publicKey(peers []*messages.PeersInfo, id int32) ([]byte, bool) {
+func peerPublicKey(peers []*messages.PeersInfo, id int32) ([]byte, bool) {
 	for _, peer := range peers {
 		if peer.Id == id && len(peer.LTPublicKey) > 0 {
 			return peer.LTPublicKey, true
@@ -184,6 +265,17 @@ func totalMessageCount(peers []*messages.PeersInfo) uint32 {
 	return count
 }
 
+// returns subjects of reputation for peer
+// i.e. his long term public key and remote address
+// (if connected and not anonymous)
+func peerSubjects(h *hub, id int32, publicKey string) []string {
+	subjects := []string{reputation.KeySubject(publicKey)}
+	if client, ok := getClient(h.clients, id); ok && client.ip != "" {
+		subjects = append(subjects, reputation.IPSubject(client.ip))
+	}
+	return subjects
+}
+
 // returns client connection object from client id
 func getClient(m map[*client]int32, data int32) (*client, bool) {
 	for key, value := range m {
@@ -193,3 +285,11 @@ func getClient(m map[*client]int32, data int32) (*client, bool) {
 	}
 	return nil, false
 }
+
+// writes value as JSON response
+func writeJSON(w http.ResponseWriter, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(value); err != nil {
+		log.Error("Error:- ", err)
+	}
+}
@@ -0,0 +1,52 @@
+package server
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/dev-appmonsters/dicemix-light-server/messages"
+	"github.com/dev-appmonsters/dicemix-light-server/utils"
+)
+
+// ServerInfo - information about coordinator exposed via discovery endpoint
+// so that peers can choose how to connect and join
+type ServerInfo struct {
+	// Versions - supported protocol versions
+	Versions []uint32 `json:"versions"`
+
+	// OnionAddress - address of onion service if served via Tor
+	OnionAddress string `json:"onionAddress,omitempty"`
+
+	// TLS - reports if /ws is served over TLS
+	TLS bool `json:"tls"`
+
+	// Admission - mode and difficulty of admission tickets
+	Admission AdmissionInfo `json:"admission"`
+}
+
+// AdmissionInfo - tickets required by coordinator
+// TokenEpoch - length in seconds of epoch stamped in blind tokens
+type AdmissionInfo struct {
+	Mode       string `json:"mode"`
+	Difficulty int    `json:"difficulty,omitempty"`
+	TokenEpoch int64  `json:"tokenEpoch,omitempty"`
+}
+
+// describes coordinator and how to join it
+func (s *connection) Info(w http.ResponseWriter, r *http.Request) {
+	config := s.hub.config
+	info := ServerInfo{
+		Versions:     messages.SupportedVersions,
+		OnionAddress: config.Tor.OnionAddress,
+		TLS:          config.TLS.Enabled(),
+		Admission: AdmissionInfo{
+			Mode:       config.Admission.Mode,
+			Difficulty: config.Admission.Difficulty,
+		},
+	}
+	if config.Admission.Mode == AdmissionBlindToken {
+		info.Admission.TokenEpoch = int64(utils.TokenEpoch / time.Second)
+	}
+
+	writeJSON(w, info)
+}
@@ -0,0 +1,63 @@
+package server
+
+import (
+	"io/ioutil"
+
+	"github.com/dev-appmonsters/dicemix-light-server/events"
+	"github.com/dev-appmonsters/dicemix-light-server/logging"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// logger of lines about single peers at minimal level
+// i.e. lines which are not written at all
+var discard = &log.Logger{
+	Out:       ioutil.Discard,
+	Formatter: new(log.TextFormatter),
+	Hooks:     make(log.LevelHooks),
+	Level:     log.PanicLevel,
+}
+
+// returns entry for log line about run
+// log lines never contain anonymous messages, vectors or KESK of peers
+// identifiers are added only through these helpers so that
+// they are redacted as per configured level
+func runLog(h *hub, sessionID uint64) *log.Entry {
+	entry := log.NewEntry(log.StandardLogger())
+	if session := h.redactor.Session(sessionID); session != "" && sessionID != 0 {
+		entry = entry.WithField("session", session)
+	}
+	return entry
+}
+
+// returns entry for log line about peer of run
+// or peer waiting in pool if sessionID is 0
+func peerLog(h *hub, sessionID uint64, id int32) *log.Entry {
+	if h.redactor.Level() == logging.Minimal {
+		return log.NewEntry(discard)
+	}
+	return runLog(h, sessionID).WithField("peer", h.redactor.Peer(sessionID, id))
+}
+
+// returns entry for log line about address or key of peer
+func subjectLog(h *hub, subject string) *log.Entry {
+	if h.redactor.Level() == logging.Minimal {
+		return log.NewEntry(discard)
+	}
+	return log.WithField("subject", h.redactor.Subject(subject))
+}
+
+// persisted events carry identifiers of runs and peers only at full level
+// other levels keep type, outcome and number of peers
+func redactEvents(h *hub, sink events.Sink) events.Sink {
+	if h.redactor.Level() == logging.Full {
+		return sink
+	}
+
+	return events.SinkFunc(func(event *events.Event) {
+		redacted := *event
+		redacted.SessionID, redacted.PeerID = 0, 0
+		redacted.Count, redacted.Peers = len(event.Peers), nil
+		sink.Emit(&redacted)
+	})
+}
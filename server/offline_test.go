@@ -0,0 +1,61 @@
+package server_test
+
+import (
+	"testing"
+
+	"github.com/dev-appmonsters/dicemix-light-server/client/adversary"
+	"github.com/dev-appmonsters/dicemix-light-server/messages"
+
+	"github.com/golang/protobuf/proto"
+)
+
+// number of peers in offline tests
+const offlinePeers = 4
+
+type offlineTestPair struct {
+	name    string
+	request proto.Message
+}
+
+// rounds in which a peer goes offline
+var offlineTests = []offlineTestPair{
+	{"key exchange", &messages.KeyExchangeRequest{}},
+	{"dc-exp", &messages.DCExpRequest{}},
+	{"dc-simple", &messages.DCSimpleRequest{}},
+	{"confirmation", &messages.ConfirmationRequest{}},
+}
+
+func TestOffline(t *testing.T) {
+	for _, pair := range offlineTests {
+		t.Run(pair.name, func(t *testing.T) {
+			h := newHarness(t, offlinePeers)
+			h.timeouts = true
+			defer h.close()
+
+			// last peer goes offline
+			configs, msgs := honestPeers(offlinePeers, 1)
+			configs[offlinePeers-1].Strategy = adversary.Offline(pair.request)
+
+			results := h.run(configs, msgs)
+
+			if results[offlinePeers-1].err == nil {
+				t.Error("expected offline peer to be removed")
+			}
+
+			// remaining peers complete in next run
+			included := make([][]byte, 0)
+			for _, res := range results[:offlinePeers-1] {
+				included = append(included, res.msgs...)
+			}
+			for _, res := range results[:offlinePeers-1] {
+				h.assertSuccessful(res, included)
+				h.assertExcluded(res, results[offlinePeers-1].msgs)
+			}
+
+			// offline peers are not blamed
+			if exclusions := h.exclusions(); len(exclusions) != 0 {
+				t.Errorf("expected no exclusions got %d", len(exclusions))
+			}
+		})
+	}
+}
@@ -0,0 +1,190 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/dev-appmonsters/dicemix-light-server/messages"
+	"github.com/dev-appmonsters/dicemix-light-server/utils"
+)
+
+// pool - peers willing to mix same denomination
+// with same script type wait in its queue
+type pool struct {
+	PoolConfig
+	waitingQueue []*waitingClient
+
+	// incremented every time a run is started from pool
+	// used to discard stale fill timers
+	epoch int
+
+	// time at which fill timer has been started
+	// zero if MinPeers are not ready yet
+	fillStarted time.Time
+}
+
+// PoolInfo - information about a pool exposed via discovery endpoint
+type PoolInfo struct {
+	PoolConfig
+	QueueDepth int `json:"queueDepth"`
+}
+
+func newPool(config PoolConfig) *pool {
+	return &pool{
+		PoolConfig:   config,
+		waitingQueue: make([]*waitingClient, 0),
+	}
+}
+
+// generates pool id from denomination and script type
+// example - 1000000-p2wpkh
+func poolID(denomination uint64, scriptType string) string {
+	return fmt.Sprintf("%d-%s", denomination, strings.ToLower(scriptType))
+}
+
+// returns number of clients in waiting queue speaking version
+// which have sent their long term public key
+func (p *pool) readyCount(version uint32) (counter int) {
+	for _, waitingClient := range p.waitingQueue {
+		if len(waitingClient.publicKey) > 0 && waitingClient.version == version {
+			counter++
+		}
+	}
+	return
+}
+
+// returns protocol version spoken by most ready clients and their number
+// only clients speaking same version can be mixed in a run
+// ties are resolved in order of preference of versions
+func (p *pool) readyVersion() (version uint32, counter int) {
+	for _, supported := range messages.SupportedVersions {
+		if ready := p.readyCount(supported); ready > counter {
+			version, counter = supported, ready
+		}
+	}
+	return
+}
+
+// removes client with specified id from waiting queue
+// returns true if client was found
+func (p *pool) remove(id int32) bool {
+	for i, waitingClient := range p.waitingQueue {
+		if waitingClient.id == id {
+			p.waitingQueue = append(p.waitingQueue[:i], p.waitingQueue[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// returns pool in whose waiting queue client with specified id is present
+func findWaitingClient(h *hub, id int32) (*pool, *waitingClient, bool) {
+	for _, pool := range h.pools {
+		for _, waitingClient := range pool.waitingQueue {
+			if waitingClient.id == id {
+				return pool, waitingClient, true
+			}
+		}
+	}
+	return nil, nil, false
+}
+
+// removes peer with specified id from waiting queue of his pool
+func leaveQueue(h *hub, id int32) {
+	if pool, _, found := findWaitingClient(h, id); found {
+		pool.remove(id)
+	}
+}
+
+// position of peer with specified id in waiting queue of pool
+// run is expected to start once fill timer expires
+// if peer is among MaxPeers ready peers speaking his version
+func queueStatus(h *hub, pool *pool, id int32) *messages.QueueStatusResponse {
+	var version uint32
+	if _, waitingClient, found := findWaitingClient(h, id); found {
+		version = waitingClient.version
+	}
+
+	status := &messages.QueueStatusResponse{
+		Header:     responseHeader(messages.S_QUEUE_STATUS, 0, "Waiting for other peers to join ...", ""),
+		PoolId:     pool.ID,
+		QueueDepth: uint32(len(pool.waitingQueue)),
+		ReadyPeers: uint32(pool.readyCount(version)),
+		MinPeers:   uint32(pool.MinPeers),
+	}
+
+	ready := 0
+	for i, waitingClient := range pool.waitingQueue {
+		if len(waitingClient.publicKey) > 0 && waitingClient.version == version {
+			ready++
+		}
+
+		if waitingClient.id != id {
+			continue
+		}
+
+		status.Position = uint32(i + 1)
+		if len(waitingClient.publicKey) > 0 && ready <= pool.MaxPeers && !pool.fillStarted.IsZero() {
+			status.EstimatedStart = pool.fillStarted.Add(utils.PoolFillWait).Unix()
+		}
+		break
+	}
+	return status
+}
+
+// starts a run if MaxPeers speaking same version are ready
+// if MinPeers are ready waits PoolFillWait for other peers to join
+func checkPool(h *hub, pool *pool) {
+	_, counter := pool.readyVersion()
+
+	if counter >= pool.MaxPeers {
+		h.startDicemix(pool)
+		return
+	}
+
+	if counter >= pool.MinPeers && pool.fillStarted.IsZero() {
+		pool.fillStarted = h.clock.Now()
+		go poolWorker(h, pool, pool.epoch)
+	}
+}
+
+// waits for PoolFillWait then starts a run with peers ready till then
+func poolWorker(h *hub, pool *pool, epoch int) {
+	select {
+	case <-h.clock.After(utils.PoolFillWait):
+		h.Lock()
+		defer h.Unlock()
+
+		// no session exists before run is started
+		var sessionID uint64
+		defer recoverSession(h, &sessionID)
+
+		// if run has already been started from pool
+		if pool.epoch != epoch {
+			return
+		}
+
+		if _, counter := pool.readyVersion(); counter >= pool.MinPeers {
+			h.startDicemix(pool)
+			return
+		}
+
+		// peers left while waiting, wait for MinPeers again
+		pool.fillStarted = time.Time{}
+	}
+}
+
+// lists pools along with their current queue depth
+func (s *connection) PoolsInfo(w http.ResponseWriter, r *http.Request) {
+	s.hub.Lock()
+	pools := make([]PoolInfo, 0, len(s.hub.poolOrder))
+	for _, id := range s.hub.poolOrder {
+		pool := s.hub.pools[id]
+		pools = append(pools, PoolInfo{PoolConfig: pool.PoolConfig, QueueDepth: len(pool.waitingQueue)})
+	}
+	s.hub.Unlock()
+
+	writeJSON(w, pools)
+}
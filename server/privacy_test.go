@@ -0,0 +1,196 @@
+package server_test
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/dev-appmonsters/dicemix-light-server/client"
+	"github.com/dev-appmonsters/dicemix-light-server/client/adversary"
+	"github.com/dev-appmonsters/dicemix-light-server/logging"
+	"github.com/dev-appmonsters/dicemix-light-server/messages"
+	"github.com/dev-appmonsters/dicemix-light-server/server"
+
+	"github.com/golang/protobuf/proto"
+	log "github.com/sirupsen/logrus"
+)
+
+// buffer written by logger and read by test
+type logBuffer struct {
+	buffer bytes.Buffer
+	sync.Mutex
+}
+
+func (b *logBuffer) Write(p []byte) (int, error) {
+	b.Lock()
+	defer b.Unlock()
+	return b.buffer.Write(p)
+}
+
+func (b *logBuffer) String() string {
+	b.Lock()
+	defer b.Unlock()
+	return b.buffer.String()
+}
+
+// records KESK revealed by peer in BLAME
+func recordKESK(keys *[][]byte, lock *sync.Mutex, strategy client.Strategy) client.Strategy {
+	return func(request proto.Message) proto.Message {
+		if r, ok := request.(*messages.InitiaiteKESKResponse); ok {
+			lock.Lock()
+			*keys = append(*keys, r.PrivateKey)
+			lock.Unlock()
+		}
+		if strategy != nil {
+			return strategy(request)
+		}
+		return request
+	}
+}
+
+// returns encoding of secret found in text if any
+func leaked(text string, secret []byte) (string, bool) {
+	for _, encoded := range []string{
+		string(secret),
+		hex.EncodeToString(secret),
+		base64.StdEncoding.EncodeToString(secret),
+		fmt.Sprint(secret),
+	} {
+		if strings.Contains(text, encoded) {
+			return encoded, true
+		}
+	}
+	return "", false
+}
+
+type privacyTestPair struct {
+	level logging.Level
+
+	// identifiers of peers and runs are written
+	identifiers bool
+
+	// fields of identifiers are written
+	fields bool
+}
+
+var privacyTests = []privacyTestPair{
+	{logging.Full, true, true},
+	{logging.Pseudonymous, false, true},
+	{logging.Minimal, false, false},
+}
+
+func TestLogPrivacy(t *testing.T) {
+	defer log.SetLevel(log.WarnLevel)
+	defer log.SetOutput(os.Stderr)
+
+	for _, pair := range privacyTests {
+		t.Run(string(pair.level), func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "privacy")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(dir)
+			eventsPath := filepath.Join(dir, "events.jsonl")
+
+			output := &logBuffer{}
+			log.SetOutput(output)
+			log.SetLevel(log.InfoLevel)
+
+			h := newHarness(t, blamePeers, func(config *server.Config) {
+				config.Logging.Level = pair.level
+				config.EventsPath = eventsPath
+			})
+			h.timeouts = true
+			defer h.close()
+
+			// last peer falsely reports missing messages
+			// so that all peers reveal their KESK in BLAME
+			var keys [][]byte
+			var lock sync.Mutex
+			configs, msgs := honestPeers(blamePeers, 1)
+			configs[blamePeers-1] = peerConfig(blamePeers-1, 2)
+			configs[blamePeers-1].Strategy = adversary.FalseOK()
+			for _, config := range configs {
+				config.Strategy = recordKESK(&keys, &lock, config.Strategy)
+			}
+
+			results := h.run(configs, msgs)
+			h.assertSuccessful(results[0], nil)
+			log.SetOutput(ioutil.Discard)
+
+			logs := output.String()
+			data, _ := ioutil.ReadFile(eventsPath)
+			persisted := string(data)
+
+			// anonymous messages and KESK are never written
+			if len(keys) == 0 {
+				t.Fatal("For", pair.level, "expected", "revealed KESK", "got", "none")
+			}
+			secrets := append([][]byte{}, keys...)
+			for _, peerMsgs := range msgs {
+				secrets = append(secrets, peerMsgs...)
+			}
+			for _, secret := range secrets {
+				if encoded, found := leaked(logs+persisted, secret); found {
+					t.Error("For", pair.level, "expected", "no secrets", "got", encoded)
+				}
+			}
+
+			found := false
+			for _, res := range results {
+				id := strconv.FormatInt(int64(res.id), 10)
+				found = found || strings.Contains(logs, "peer="+id)
+			}
+			if found != pair.identifiers {
+				t.Error("For", pair.level, "expected identifiers", pair.identifiers, "got", found)
+			}
+			if fields := strings.Contains(logs, "peer=") && strings.Contains(logs, "session="); fields != pair.fields {
+				t.Error("For", pair.level, "expected fields", pair.fields, "got", fields)
+			}
+
+			// persisted events carry identifiers only at full level
+			if identifiers := strings.Contains(persisted, `"peerId"`) || strings.Contains(persisted, `"sessionId"`); identifiers != (pair.level == logging.Full) {
+				t.Error("For", pair.level, "expected persisted identifiers", pair.level == logging.Full, "got", identifiers)
+			}
+			if !strings.Contains(persisted, `"run_succeeded"`) {
+				t.Error("For", pair.level, "expected", "run_succeeded", "got", persisted)
+			}
+		})
+	}
+}
+
+type loggingConfigTestPair struct {
+	config server.LoggingConfig
+	valid  bool
+}
+
+var loggingConfigTests = []loggingConfigTestPair{
+	{server.LoggingConfig{}, true},
+	{server.LoggingConfig{Level: logging.Minimal, Path: "server.log", RetentionDays: 7}, true},
+	{server.LoggingConfig{Level: "verbose"}, false},
+	{server.LoggingConfig{RetentionDays: 7}, false},
+	{server.LoggingConfig{Path: "server.log", RetentionDays: -1}, false},
+}
+
+func TestValidateLogging(t *testing.T) {
+	for _, pair := range loggingConfigTests {
+		config := server.DefaultConfig()
+		config.Logging = pair.config
+		if err := config.Validate(); (err == nil) != pair.valid {
+			t.Error("For", pair.config, "expected valid", pair.valid, "got", err)
+		}
+	}
+
+	// identities are pseudonymous unless configured otherwise
+	if level := server.DefaultConfig().Logging.Level; level != logging.Pseudonymous {
+		t.Error("For", "default", "expected", logging.Pseudonymous, "got", level)
+	}
+}
@@ -0,0 +1,176 @@
+package server_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dev-appmonsters/dicemix-light-server/client"
+	"github.com/dev-appmonsters/dicemix-light-server/clock"
+	"github.com/dev-appmonsters/dicemix-light-server/messages"
+	"github.com/dev-appmonsters/dicemix-light-server/runstore"
+	"github.com/dev-appmonsters/dicemix-light-server/server"
+	"github.com/dev-appmonsters/dicemix-light-server/utils"
+
+	"github.com/golang/protobuf/proto"
+)
+
+// listener which can drop all of its connections
+// including hijacked websocket connections
+type trackingListener struct {
+	net.Listener
+	conns []net.Conn
+	sync.Mutex
+}
+
+func (l *trackingListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err == nil {
+		l.Lock()
+		l.conns = append(l.conns, conn)
+		l.Unlock()
+	}
+	return conn, err
+}
+
+func (l *trackingListener) dropAll() {
+	l.Lock()
+	defer l.Unlock()
+	for _, conn := range l.conns {
+		conn.Close()
+	}
+	l.conns = nil
+}
+
+// serves /ws of current server so that a restarted
+// server is reachable at same url
+type restartableServer struct {
+	current server.Server
+	sync.Mutex
+}
+
+func (s *restartableServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.Lock()
+	current := s.current
+	s.Unlock()
+	current.Register(w, r)
+}
+
+func restartConfig(path string) *server.Config {
+	config := server.DefaultConfig()
+	config.Pools = []server.PoolConfig{{
+		ID:           "test",
+		Denomination: denomination,
+		ScriptType:   utils.P2WPKH,
+		MinPeers:     3,
+		MaxPeers:     3,
+	}}
+	config.Clock = clock.NewFakeClock(time.Unix(1500000000, 0))
+	config.RateLimits = server.RateLimits{}
+	config.RunStore = server.RunStoreConfig{Path: path, Resume: true}
+	return config
+}
+
+// waits till run store at path holds run waiting for state
+// and copies it to a new path
+func copyRunStore(t *testing.T, path string, state int) string {
+	restored := path + ".restored"
+	for i := 0; i < 1000; i++ {
+		data, _ := ioutil.ReadFile(path)
+		ioutil.WriteFile(restored, data, 0600)
+
+		store, err := runstore.NewFileStore(restored)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if snapshots, _ := store.List(); len(snapshots) == 1 && snapshots[0].NextState == state {
+			return restored
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("run store does not hold run waiting for", state)
+	return ""
+}
+
+func TestRestartResumesRun(t *testing.T) {
+	dir, err := ioutil.TempDir("", "runstore")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "runs.jsonl")
+	handler := &restartableServer{current: server.NewConnection(restartConfig(path))}
+
+	s := httptest.NewUnstartedServer(handler)
+	listener := &trackingListener{Listener: s.Listener}
+	s.Listener = listener
+	s.Start()
+	defer s.Close()
+	defer func() {
+		handler.Lock()
+		handler.current.Close()
+		handler.Unlock()
+	}()
+	url := "ws" + strings.TrimPrefix(s.URL, "http")
+
+	// server crashes once all peers have received S_KEY_EXCHANGE
+	// their DC-EXP vectors are lost along with it
+	var once sync.Once
+	var dropped sync.WaitGroup
+	dropped.Add(3)
+	crash := func() {
+		handler.Lock()
+		defer handler.Unlock()
+		handler.current.Close()
+		handler.current = server.NewConnection(restartConfig(copyRunStore(t, path, messages.C_EXP_DC_VECTOR)))
+		listener.dropAll()
+	}
+
+	configs, msgs := honestPeers(3, 1)
+	results := make([]*result, 3)
+	var wg sync.WaitGroup
+	for i := range configs {
+		var first sync.Once
+		configs[i].ResumeWait = 30 * time.Second
+		configs[i].Strategy = func(request proto.Message) proto.Message {
+			if _, ok := request.(*messages.DCExpRequest); ok {
+				drop := false
+				first.Do(func() { drop = true })
+				if drop {
+					dropped.Done()
+					go func() {
+						dropped.Wait()
+						once.Do(crash)
+					}()
+					return nil
+				}
+			}
+			return request
+		}
+
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+			defer cancel()
+			res := &result{index: i, msgs: msgs[i]}
+			res.transaction, res.err = client.NewClient(configs[i]).Join(ctx, url, msgs[i])
+			results[i] = res
+		}(i)
+	}
+	wg.Wait()
+
+	h := &harness{t: t}
+	for _, res := range results {
+		h.assertSuccessful(res, [][]byte{msgs[0][0], msgs[1][0], msgs[2][0]})
+	}
+}
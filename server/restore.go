@@ -0,0 +1,248 @@
+package server
+
+import (
+	"github.com/dev-appmonsters/dicemix-light-server/events"
+	"github.com/dev-appmonsters/dicemix-light-server/messages"
+	"github.com/dev-appmonsters/dicemix-light-server/runstore"
+	"github.com/dev-appmonsters/dicemix-light-server/utils"
+
+	"github.com/golang/protobuf/proto"
+)
+
+// persists state of run after a state transition
+// so that run can be resumed or aborted after restart
+// peers are copied as run store may write them after hub is unlocked
+func saveSnapshot(h *hub, sessionID uint64) {
+	r := h.runs[sessionID]
+	peers := make([]*messages.PeersInfo, len(r.peers))
+	for i, peer := range r.peers {
+		peers[i] = proto.Clone(peer).(*messages.PeersInfo)
+	}
+
+	snapshot := &runstore.Snapshot{
+		SessionID:   sessionID,
+		Run:         r.run,
+		FeeTerms:    r.feeTerms,
+		Peers:       peers,
+		NextState:   r.nextState,
+		Version:     r.version,
+		Messages:    r.messages,
+		Transaction: r.transaction,
+		Response:    r.response,
+		Timestamp:   utils.Timestamp(),
+	}
+	if r.pool != nil {
+		snapshot.PoolID = r.pool.ID
+	}
+
+	checkError(h.runStore.Save(snapshot))
+}
+
+// restores runs which were unfinished when server stopped
+// runs are resumed once all of their peers reconnect within ResumeWait
+// otherwise they are aborted and reconnecting peers are informed
+func restoreRuns(h *hub) {
+	snapshots, err := h.runStore.List()
+	if checkError(err) {
+		return
+	}
+
+	h.Lock()
+	defer h.Unlock()
+
+	for _, snapshot := range snapshots {
+		pool, ok := h.pools[snapshot.PoolID]
+		if !h.config.RunStore.Resume || (snapshot.PoolID != "" && !ok) {
+			abortRun(h, snapshot.SessionID)
+			continue
+		}
+
+		r := newRun()
+		r.sessionID = snapshot.SessionID
+		r.run = snapshot.Run
+		r.pool = pool
+		r.feeTerms = snapshot.FeeTerms
+		r.peers = snapshot.Peers
+		r.nextState = snapshot.NextState
+		r.version = snapshot.Version
+		r.messages = snapshot.Messages
+		r.transaction = snapshot.Transaction
+		r.response = snapshot.Response
+		r.resuming = true
+		h.runs[r.sessionID] = r
+
+		runLog(h, r.sessionID).Info("RUN Restored Expected Next State - ", r.nextState, ", Peers - ", len(r.peers))
+		go resumeWorker(h, r.sessionID)
+	}
+}
+
+// aborts run whose peers have not reconnected in time
+func resumeWorker(h *hub, sessionID uint64) {
+	<-h.clock.After(utils.ResumeWait)
+
+	h.Lock()
+	defer h.Unlock()
+
+	r, ok := h.runs[sessionID]
+	if !ok || !r.resuming {
+		return
+	}
+
+	for _, peer := range r.peers {
+		if client, ok := getClient(h.clients, peer.Id); ok {
+			sendAbortResponse(client, sessionID, "Run aborted after server restart")
+			removePeer(h, peer.Id)
+		}
+	}
+	abortRun(h, sessionID)
+}
+
+// forgets run known before restart
+// peers reconnecting later are informed that it will not continue
+func abortRun(h *hub, sessionID uint64) {
+	runLog(h, sessionID).Warn("RUN Aborted after restart")
+	h.metrics.Inc("aborted_runs_total")
+
+	event := &events.Event{
+		Type:      events.RunTerminated,
+		SessionID: sessionID,
+		Reason:    "aborted after restart",
+	}
+	if r, ok := h.runs[sessionID]; ok {
+		event.Peers = peerIDs(r.peers)
+	}
+	emit(h, event)
+
+	h.aborted[sessionID] = true
+	delete(h.runs, sessionID)
+	checkError(h.runStore.Delete(sessionID))
+	h.redactor.Forget(sessionID)
+	go forgetAbortedWorker(h, sessionID)
+}
+
+// forgets aborted run once its peers have had ResumeWait to reconnect
+func forgetAbortedWorker(h *hub, sessionID uint64) {
+	<-h.clock.After(utils.ResumeWait)
+
+	h.Lock()
+	defer h.Unlock()
+	delete(h.aborted, sessionID)
+}
+
+// sends definitive S_RUN_TERMINATED for session which will not continue
+// e.g. aborted after restart or by operator
+func sendAbortResponse(client *client, sessionID uint64, reason string) {
+	header := responseHeader(messages.S_RUN_TERMINATED, sessionID, "Run terminated", reason)
+	header.ErrorCode = messages.E_SESSION_ABORTED
+	response, err := proto.Marshal(&messages.GenericResponse{
+		Header: header,
+	})
+
+	if !checkError(err) {
+		select {
+		case client.send <- response:
+		default:
+		}
+	}
+}
+
+// lets peer of run restored after restart take his seat again
+// peer proves his id by signing C_JOIN_REQUEST with his long term key
+// run continues from its last state once all of its peers are back
+func handleResumeRequest(c *client, signedRequest *messages.SignedRequest, h *hub) {
+	request := &messages.JoinRequest{}
+	if err := proto.Unmarshal(signedRequest.RequestData, request); checkError(err) {
+		rejectJoin(h, c, 0, 0, newRequestError(messages.E_MALFORMED_REQUEST, "malformed join request"))
+		return
+	}
+
+	id, sessionID := request.Header.Id, request.Header.SessionId
+	if h.aborted[sessionID] {
+		peerLog(h, sessionID, id).Info("Recv: handleResumeRequest Aborted")
+		emitRejected(h, c, id, sessionID, newRequestError(messages.E_SESSION_ABORTED, "Run aborted after server restart"))
+		sendAbortResponse(c, sessionID, "Run aborted after server restart")
+		if h.pending[c] {
+			dropClient(h, c)
+		}
+		return
+	}
+
+	r, ok := h.runs[sessionID]
+	if !ok {
+		rejectJoin(h, c, id, sessionID, newRequestError(messages.E_UNKNOWN_SESSION, "unknown session"))
+		return
+	}
+
+	if !h.pending[c] || !r.resuming {
+		rejectJoin(h, c, id, sessionID, newRequestError(messages.E_UNEXPECTED_REQUEST, "run is not resuming"))
+		return
+	}
+
+	if !validateMessage(signedRequest, h, id, sessionID) {
+		peerLog(h, sessionID, id).Info("Recv: handleResumeRequest Wrong Signature")
+		rejectJoin(h, c, id, sessionID, newRequestError(messages.E_INVALID_SIGNATURE, "invalid signature"))
+		return
+	}
+
+	if _, ok := getClient(h.clients, id); ok {
+		rejectJoin(h, c, id, sessionID, newRequestError(messages.E_DUPLICATE_REQUEST, "peer has already resumed"))
+		return
+	}
+
+	// run continues in version its peers have been speaking
+	version, err := negotiateVersion(request)
+	if r.version != 0 {
+		version = r.version
+		if !announcesVersion(request, version) {
+			err = newRequestError(messages.E_UNSUPPORTED_VERSION, "protocol version of run not supported")
+		}
+	}
+	if err != nil {
+		rejectJoin(h, c, id, sessionID, err)
+		return
+	}
+
+	var poolID string
+	if r.pool != nil {
+		poolID = r.pool.ID
+	}
+	if !sendJoinResponse(h, c, id, poolID, version, nil) {
+		return
+	}
+
+	delete(h.pending, c)
+	h.clients[c] = id
+	peerLog(h, sessionID, id).Info("Recv: handleResumeRequest")
+	emit(h, &events.Event{Type: events.PeerJoined, SessionID: sessionID, PeerID: id})
+
+	for _, peer := range r.peers {
+		if _, ok := getClient(h.clients, peer.Id); !ok {
+			return
+		}
+	}
+	resumeRun(h, sessionID)
+}
+
+// sends last response of run again to its reconnected peers
+// and waits for their requests as if it has just been broadcasted
+func resumeRun(h *hub, sessionID uint64) {
+	r := h.runs[sessionID]
+	r.resuming = false
+	for _, peer := range r.peers {
+		peer.MessageReceived = false
+	}
+	setReadLimits(h, sessionID)
+
+	for _, peer := range r.peers {
+		if client, ok := getClient(h.clients, peer.Id); ok {
+			select {
+			case client.send <- r.response:
+			default:
+			}
+		}
+	}
+
+	runLog(h, sessionID).Info("RUN Resumed Expected Next State - ", r.nextState, ", Peers - ", len(r.peers))
+	h.metrics.Inc("resumed_runs_total")
+	go registerWorker(h, sessionID, uint32(r.nextState), r.run)
+}
@@ -0,0 +1,196 @@
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dev-appmonsters/dicemix-light-server/clock"
+	"github.com/dev-appmonsters/dicemix-light-server/evidence"
+	"github.com/dev-appmonsters/dicemix-light-server/messages"
+	"github.com/dev-appmonsters/dicemix-light-server/utils"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/golang/protobuf/proto"
+)
+
+// last response of test run before restart
+var lastResponse = []byte("last response")
+
+// creates hub restarted while test run was waiting for DC-EXP vectors
+func newRestartedHub(resume bool) *hub {
+	old, _ := newTestHub(messages.C_EXP_DC_VECTOR)
+	old.runs[testSession].response = lastResponse
+	saveSnapshot(old, testSession)
+
+	config := DefaultConfig()
+	config.Clock = clock.NewFakeClock(time.Unix(1500000000, 0))
+	config.Network = "regtest"
+	config.RunStore.Resume = resume
+	h := newHub(config, evidence.NewMemoryStore())
+	h.runStore = old.runStore
+	restoreRuns(h)
+	return h
+}
+
+// connects a new client which sends C_JOIN_REQUEST for test run
+// as i-th peer signed with long term key of signer
+func resumeFuzzPeer(h *hub, i, signer int) *client {
+	c := &client{hub: h, send: make(chan []byte, 256)}
+	h.pending[c] = true
+
+	data, _ := proto.Marshal(&messages.JoinRequest{
+		Header:   &messages.RequestHeader{Code: messages.C_JOIN_REQUEST, SessionId: testSession, Id: int32(i + 1)},
+		Versions: messages.SupportedVersions,
+	})
+	signature, _ := testLTSK(signer).Sign(chainhash.DoubleHashB(data))
+	message, _ := proto.Marshal(&messages.SignedRequest{RequestData: data, Signature: signature.Serialize()})
+
+	handleRequest(c, message, h)
+	return c
+}
+
+// returns header of next response sent to client
+func nextHeader(c *client) *messages.ResponseHeader {
+	select {
+	case data := <-c.send:
+		response := &messages.GenericResponse{}
+		if err := proto.Unmarshal(data, response); err != nil || response.Header == nil {
+			return &messages.ResponseHeader{}
+		}
+		return response.Header
+	default:
+		return &messages.ResponseHeader{}
+	}
+}
+
+func TestResumeRun(t *testing.T) {
+	h := newRestartedHub(true)
+	if r, ok := h.runs[testSession]; !ok || !r.resuming || r.nextState != messages.C_EXP_DC_VECTOR {
+		t.Fatal("For", "restore", "expected", "resuming run", "got", h.runs[testSession])
+	}
+
+	// requests of run are refused till it is resumed
+	handleRequest(&client{hub: h, send: make(chan []byte, 256)}, signedTestRequest(&messages.DCExpRequest{
+		Header:      testHeader(messages.C_EXP_DC_VECTOR),
+		DCExpVector: make([]uint64, testPeers),
+	})(), h)
+	if h.runs[testSession].peers[0].MessageReceived {
+		t.Error("For", "request while resuming", "expected", "refused", "got", "accepted")
+	}
+
+	// peer can not take seat of another peer
+	forged := resumeFuzzPeer(h, 1, 0)
+	if header := nextHeader(forged); header.ErrorCode != messages.E_INVALID_SIGNATURE {
+		t.Error("For", "forged", "expected", messages.E_INVALID_SIGNATURE, "got", header.ErrorCode)
+	}
+
+	clients := make([]*client, testPeers)
+	for i := range clients {
+		clients[i] = resumeFuzzPeer(h, i, i)
+		if header := nextHeader(clients[i]); header.Code != messages.S_JOIN_RESPONSE || header.Err != "" {
+			t.Error("For", "peer", i, "expected", messages.S_JOIN_RESPONSE, "got", header)
+		}
+
+		// seat is taken only once
+		if i == 0 {
+			duplicate := resumeFuzzPeer(h, 0, 0)
+			if header := nextHeader(duplicate); header.ErrorCode != messages.E_DUPLICATE_REQUEST {
+				t.Error("For", "duplicate", "expected", messages.E_DUPLICATE_REQUEST, "got", header.ErrorCode)
+			}
+		}
+	}
+
+	// last response is sent again once all peers are back
+	for i, c := range clients {
+		select {
+		case data := <-c.send:
+			if string(data) != string(lastResponse) {
+				t.Error("For", "peer", i, "expected", lastResponse, "got", data)
+			}
+		default:
+			t.Error("For", "peer", i, "expected", lastResponse, "got", "nothing")
+		}
+	}
+
+	if h.runs[testSession].resuming || h.metrics.Counters()["resumed_runs_total"] != 1 {
+		t.Error("For", "resumed run", "expected", "resumed", "got", h.runs[testSession].resuming)
+	}
+}
+
+func TestResumeTimeout(t *testing.T) {
+	h := newRestartedHub(true)
+	fake := h.clock.(clock.Fake)
+
+	c := resumeFuzzPeer(h, 0, 0)
+	nextHeader(c)
+
+	for fake.Waiters() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	fake.Advance(utils.ResumeWait)
+
+	// reconnected peer is informed and disconnected
+	data, ok := <-c.send
+	response := &messages.GenericResponse{}
+	if !ok || proto.Unmarshal(data, response) != nil || response.Header.ErrorCode != messages.E_SESSION_ABORTED {
+		t.Error("For", "timeout", "expected", messages.E_SESSION_ABORTED, "got", response.Header)
+	}
+	if _, ok := <-c.send; ok {
+		t.Error("For", "timeout", "expected", "closed connection", "got", "message")
+	}
+
+	h.Lock()
+	defer h.Unlock()
+	if _, ok := h.runs[testSession]; ok || !h.aborted[testSession] {
+		t.Error("For", "timeout", "expected", "aborted run", "got", h.runs[testSession])
+	}
+	if snapshots, _ := h.runStore.List(); len(snapshots) != 0 {
+		t.Error("For", "timeout", "expected", 0, "got", len(snapshots))
+	}
+}
+
+func TestAbortRestoredRuns(t *testing.T) {
+	h := newRestartedHub(false)
+	if _, ok := h.runs[testSession]; ok {
+		t.Error("For", "abort", "expected", "no run", "got", h.runs[testSession])
+	}
+
+	// peers reconnecting later learn that run will not continue
+	c := resumeFuzzPeer(h, 0, 0)
+	if header := nextHeader(c); header.Code != messages.S_RUN_TERMINATED || header.ErrorCode != messages.E_SESSION_ABORTED {
+		t.Error("For", "abort", "expected", messages.E_SESSION_ABORTED, "got", header)
+	}
+
+	// aborted runs are forgotten after ResumeWait
+	fake := h.clock.(clock.Fake)
+	for fake.Waiters() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	fake.Advance(utils.ResumeWait)
+	for aborted := true; aborted; {
+		h.Lock()
+		aborted = h.aborted[testSession]
+		h.Unlock()
+		time.Sleep(time.Millisecond)
+	}
+
+	// unknown sessions are not aborted ones
+	c = resumeFuzzPeer(h, 0, 0)
+	if header := nextHeader(c); header.ErrorCode != messages.E_UNKNOWN_SESSION {
+		t.Error("For", "unknown", "expected", messages.E_UNKNOWN_SESSION, "got", header.ErrorCode)
+	}
+}
+
+func TestSnapshotLifecycle(t *testing.T) {
+	h, _ := newTestHub(messages.C_EXP_DC_VECTOR)
+	saveSnapshot(h, testSession)
+	if snapshots, _ := h.runStore.List(); len(snapshots) != 1 || snapshots[0].NextState != messages.C_EXP_DC_VECTOR {
+		t.Error("For", "save", "expected", 1, "got", snapshots)
+	}
+
+	// finished runs are forgotten
+	terminate(h, testSession)
+	if snapshots, _ := h.runStore.List(); len(snapshots) != 0 {
+		t.Error("For", "terminate", "expected", 0, "got", len(snapshots))
+	}
+}
@@ -2,8 +2,21 @@ package server
 
 import (
 	"sync"
+	"time"
 
+	"github.com/dev-appmonsters/dicemix-light-server/admission"
+	"github.com/dev-appmonsters/dicemix-light-server/clock"
+	"github.com/dev-appmonsters/dicemix-light-server/dc"
+	"github.com/dev-appmonsters/dicemix-light-server/events"
+	"github.com/dev-appmonsters/dicemix-light-server/evidence"
+	"github.com/dev-appmonsters/dicemix-light-server/fee"
+	"github.com/dev-appmonsters/dicemix-light-server/logging"
 	"github.com/dev-appmonsters/dicemix-light-server/messages"
+	"github.com/dev-appmonsters/dicemix-light-server/metrics"
+	"github.com/dev-appmonsters/dicemix-light-server/ratelimit"
+	"github.com/dev-appmonsters/dicemix-light-server/reputation"
+	"github.com/dev-appmonsters/dicemix-light-server/runstore"
+	"github.com/dev-appmonsters/dicemix-light-server/tx"
 	"github.com/dev-appmonsters/dicemix-light-server/utils"
 
 	"github.com/golang/protobuf/proto"
@@ -13,11 +26,19 @@ import (
 
 // Client is a middleman between the websocket connection and the hub.
 type client struct {
+	// maximum size of message read from peer
+	// accessed atomically as it is updated by hub
+	// first field so that it is 64 bit aligned
+	readLimit int64
+
 	hub *hub
 
 	// The websocket connection.
 	conn *websocket.Conn
 
+	// remote address of connection
+	ip string
+
 	// Buffered channel of outbound messages.
 	send chan []byte
 }
@@ -26,50 +47,153 @@ type client struct {
 type run struct {
 	sessionID uint64
 	run       int
+	pool      *pool
+	feeTerms  *messages.FeeTerms
 	peers     []*messages.PeersInfo
 	nextState int
 	messages  [][]byte
+
+	// serialized PSBT assembled in DC-SIMPLE round
+	transaction []byte
+
+	// last response broadcasted to peers
+	response []byte
+
+	// protocol version spoken by every peer of run
+	version uint32
+
+	// run has been restored after restart
+	// and waits for its peers to reconnect
+	resuming bool
 	sync.Mutex
 }
 
 type waitingClient struct {
 	id        int32
 	publicKey []byte
+
+	// protocol version negotiated while joining
+	version uint32
+}
+
+// request sent by a client over its websocket connection
+type clientRequest struct {
+	client  *client
+	message []byte
 }
 
 // hub maintains the set of active clients and broadcasts messages to the
 // clients.
 type hub struct {
-	clients      map[*client]int32
-	runs         map[uint64]*run
-	waitingQueue []*waitingClient
-	request      chan []byte
-	register     chan *client
-	unregister   chan *client
+	config     *Config
+	clock      clock.Clock
+	dcNet      dc.DC
+	tx         tx.TX
+	estimator  fee.Estimator
+	feeRate    uint64
+	evidence   evidence.Store
+	runStore   runstore.Store
+	reputation reputation.Reputation
+	metrics    metrics.Metrics
+	redactor   logging.Redactor
+	events     events.Sink
+	feed       events.Feed
+	clients    map[*client]int32
+	// connections which have not sent C_JOIN_REQUEST yet
+	pending    map[*client]bool
+	runs       map[uint64]*run
+	pools      map[string]*pool
+	poolOrder  []string
+	request    chan *clientRequest
+	register   chan *client
+	unregister chan *client
+
+	// admission control of connections and long term public keys
+	connections ratelimit.Limiter
+	keys        ratelimit.Limiter
+	queueStatus ratelimit.Limiter
+
+	// tickets required to join pools
+	admission admission.Admission
+
+	// sessions known before restart which have been aborted
+	aborted map[uint64]bool
+
+	// closed once server is closed to stop its workers
+	done chan struct{}
 	sync.Mutex
 }
 
-var upgrader = websocket.Upgrader{
-	ReadBufferSize:  1024,
-	WriteBufferSize: 1024,
-}
+func newHub(config *Config, store evidence.Store) *hub {
+	h := &hub{
+		config:     config,
+		clock:      config.Clock,
+		dcNet:      dc.NewDCNetwork(),
+		evidence:   store,
+		metrics:    metrics.NewMetrics(),
+		feed:       events.NewFeed(),
+		estimator:  newEstimator(config.MinerFee),
+		feeRate:    config.MinerFee.StaticFeeRate,
+		clients:    make(map[*client]int32),
+		pending:    make(map[*client]bool),
+		runStore:   runstore.NewMemoryStore(),
+		runs:       make(map[uint64]*run),
+		aborted:    make(map[uint64]bool),
+		pools:      make(map[string]*pool),
+		poolOrder:  make([]string, 0),
+		request:    make(chan *clientRequest),
+		register:   make(chan *client),
+		unregister: make(chan *client),
+		done:       make(chan struct{}),
+	}
 
-func newHub() *hub {
-	return &hub{
-		clients:      make(map[*client]int32),
-		runs:         make(map[uint64]*run),
-		waitingQueue: make([]*waitingClient, 0),
-		request:      make(chan []byte),
-		register:     make(chan *client),
-		unregister:   make(chan *client),
+	if h.clock == nil {
+		h.clock = clock.NewClock()
 	}
+
+	// transactions are assembled for configured network
+	params, err := tx.NetParams(config.Network)
+	if err != nil {
+		log.Fatal("Unknown network ", config.Network)
+	}
+	h.tx = tx.NewPSBT(params)
+
+	// bans are measured with clock of hub
+	h.reputation, err = reputation.NewBackoffReputation(utils.BanDuration, utils.MaxBanDuration, utils.TimeoutsPerOffence, h.clock)
+	if err != nil {
+		log.Fatal("Invalid ban parameters ", err)
+	}
+
+	// identifiers are left out of logs if level is invalid
+	if h.redactor, err = logging.NewRedactor(config.Logging.Level); err != nil {
+		h.redactor, _ = logging.NewRedactor(logging.Minimal)
+	}
+
+	// events are counted and forwarded to subscribers of admin API
+	sinks := []events.Sink{metricsSink(h.metrics), h.feed}
+	if config.EventSink != nil {
+		sinks = append(sinks, config.EventSink)
+	}
+	h.events = events.NewMultiSink(sinks...)
+
+	limits := config.RateLimits
+	h.connections = ratelimit.NewTokenBucket(limits.ConnectionsPerMinute, limits.ConnectionBurst)
+	h.keys = ratelimit.NewTokenBucket(limits.KeysPerMinute, limits.KeyBurst)
+	h.queueStatus = ratelimit.NewTokenBucket(limits.QueueStatusPerMinute, limits.QueueStatusBurst)
+	h.admission = admission.NewOpen()
+
+	for _, poolConfig := range config.Pools {
+		h.pools[poolConfig.ID] = newPool(poolConfig)
+		h.poolOrder = append(h.poolOrder, poolConfig.ID)
+	}
+	return h
 }
 
 func newRun() *run {
 	return &run{
 		sessionID: 0,
 		run:       -1,
-		peers:     make([]*messages.PeersInfo, utils.MinPeers),
+		peers:     make([]*messages.PeersInfo, 0),
 		nextState: 0,
 		messages:  make([][]byte, 0),
 	}
@@ -84,73 +208,137 @@ func (h *hub) listener() {
 		select {
 		case client := <-h.register:
 			if h.registration(client) {
-				log.Info("INCOMING C_JOIN_REQUEST - SUCCESSFUL")
+				log.Info("INCOMING - CONNECTION ACCEPTED")
 			} else {
-				log.Info("INCOMING C_JOIN_REQUEST - FAILED")
+				log.Info("INCOMING - CONNECTION REFUSED")
 			}
 
 		case client := <-h.unregister:
-			if _, ok := h.clients[client]; ok {
-				log.Info("INCOMING - USER UN-REGISTRATION - ", h.clients[client])
-				delete(h.clients, client)
-				close(client.send)
-			}
-		case message := <-h.request:
-			handleRequest(message, h)
+			h.unregistration(client)
+		case request := <-h.request:
+			handleRequest(request.client, request.message, h)
 		}
 	}
 }
 
-// adds a peer in h.peers if |h.peers| < MaxPeers
-// send a failure message response to other peers
+// accepts newly connected peer till he sends C_JOIN_REQUEST
+// peer is assigned an id and enqueued only after joining
 func (h *hub) registration(client *client) bool {
 	h.Lock()
 	defer h.Unlock()
 
-	// generates a random user id for new client
-	userID := utils.RandInt31()
+	// refuse peers whose address is banned
+	if until, banned := h.reputation.Banned(reputation.IPSubject(client.ip)); client.ip != "" && banned {
+		log.Info("USER REGISTRATION - Banned Address till ", until)
+		rejectJoin(h, client, 0, 0, newRequestError(messages.E_BANNED, "Banned till "+until.String()))
+		close(client.send)
+		return false
+	}
+
+	h.pending[client] = true
+	go joinWorker(h, client)
+	return true
+}
+
+// removes disconnected peer from set of all peers
+func (h *hub) unregistration(client *client) {
+	h.Lock()
+	defer h.Unlock()
+
+	dropClient(h, client)
+}
+
+// closes connection of peer and removes him from set of all peers
+// peer would be considered offline only if he
+// disconnects during a run, not while waiting
+func dropClient(h *hub, client *client) {
+	if h.pending[client] {
+		log.Info("INCOMING - CONNECTION CLOSED BEFORE JOINING")
+		delete(h.pending, client)
+		close(client.send)
+		return
+	}
+
+	if id, ok := h.clients[client]; ok {
+		peerLog(h, 0, id).Info("INCOMING - USER UN-REGISTRATION")
+		delete(h.clients, client)
+		close(client.send)
+		leaveQueue(h, id)
+	}
+}
+
+// queues message for client without blocking hub
+// client which does not read his messages is disconnected
+// returns false if message could not be queued
+func sendMessage(h *hub, client *client, message []byte) bool {
+	select {
+	case client.send <- message:
+		return true
+	default:
+		log.Warn("Send buffer full, closing connection")
+		dropClient(h, client)
+		return false
+	}
+}
+
+// closes connection of peer which has not joined within JoinWait
+func joinWorker(h *hub, client *client) {
+	<-h.clock.After(utils.JoinWait)
+
+	h.Lock()
+	defer h.Unlock()
+
+	if h.pending[client] {
+		log.Info("USER REGISTRATION - Join Timeout")
+		delete(h.pending, client)
+		close(client.send)
+	}
+}
 
-	// send registration response to client
+// sends S_JOIN_RESPONSE to client
+// conveys pool in which client is waiting and protocol version
+// or reason for which client can not join
+func sendJoinResponse(h *hub, client *client, id int32, poolID string, version uint32, joinErr error) bool {
 	header := responseHeader(messages.S_JOIN_RESPONSE, 0, "Welcome to CoinShuffle++. Waiting for other peers to join ...", "")
+	setError(header, joinErr)
+	features, _ := messages.VersionFeatures(version)
 	registration, err := proto.Marshal(&messages.RegisterResponse{
-		Header: header,
-		Id:     userID,
+		Header:   header,
+		Id:       id,
+		PoolId:   poolID,
+		Version:  version,
+		Features: features,
 	})
 
 	if checkError(err) {
 		return false
 	}
 
-	client.send <- registration
-
-	// map client with its userID
-	h.clients[client] = userID
-
-	// store client in waiting queue
-	h.waitingQueue = append(h.waitingQueue, &waitingClient{id: userID})
-	return true
+	return sendMessage(h, client, registration)
 }
 
-// initiates DiceMix-Light protocol
+// initiates DiceMix-Light protocol for peers waiting in pool
 // send all peers ID's
-func (h *hub) startDicemix() {
+func (h *hub) startDicemix(pool *pool) {
 	// generate session id for clients involved in current dicemix execution
 	sessionID := utils.RandUint64()
 
 	// create new run
 	run := newRun()
-	run.peers = make([]*messages.PeersInfo, utils.MinPeers)
 	run.sessionID = sessionID
 	run.run = 0
+	run.pool = pool
+	run.feeTerms = feeTerms(pool, h.config.CoordinatorFee, h.feeRate)
+	run.version, _ = pool.readyVersion()
 
 	// maintains list of clients which have registered
-	// but have not sent their long term public key yet
+	// but have not sent their long term public key yet,
+	// speak another version or could not fit in run
 	waitingClients := make([]*waitingClient, 0)
-	i := 0
 
 	// copy peersInfo from waiting queue to start dicemix run
-	for _, waitingClient := range h.waitingQueue {
-		if len(waitingClient.publicKey) == 0 {
+	for _, waitingClient := range pool.waitingQueue {
+		if len(waitingClient.publicKey) == 0 || waitingClient.version != run.version || len(run.peers) >= pool.MaxPeers {
 			// if client has not sent long term public key yet
 			// add him to waitingClients
 			waitingClients = append(waitingClients, waitingClient)
@@ -159,20 +347,43 @@ func (h *hub) startDicemix() {
 
 		// clients those have sent their long term public key
 		// add them to newly created dicemix run
-		run.peers[i] = &messages.PeersInfo{Id: waitingClient.id}
-		run.peers[i].LTPublicKey = waitingClient.publicKey
-		run.peers[i].MessageReceived = true
-		i++
+		run.peers = append(run.peers, &messages.PeersInfo{
+			Id:              waitingClient.id,
+			LTPublicKey:     waitingClient.publicKey,
+			MessageReceived: true,
+		})
 	}
 
 	// creates an association between sessionID and run
 	h.runs[sessionID] = run
+	emit(h, &events.Event{
+		Type:      events.RunStarted,
+		SessionID: sessionID,
+		Peers:     peerIDs(run.peers),
+	})
 
 	// replace waitingQueue with waitingClients
 	// i.e. store only those clients in waitingQueue which
 	// have not sent their long term public key yet
-	h.waitingQueue = waitingClients
+	pool.waitingQueue = waitingClients
+
+	// invalidate fill timer of pool
+	pool.epoch++
+	pool.fillStarted = time.Time{}
+
+	// pseudonyms of waiting peers and addresses change with every run
+	// so that they can not be followed through logs for long
+	h.redactor.Forget(0)
 
 	// broadcasts - initiates DiceMix-Light protocol
-	go broadcastDiceMixResponse(h, sessionID, messages.S_START_DICEMIX, "Initiate DiceMix Protocol", "")
+	// once caller has released hub
+	go func() {
+		h.Lock()
+		defer h.Unlock()
+		defer recoverSession(h, &sessionID)
+		broadcastDiceMixResponse(h, sessionID, messages.S_START_DICEMIX, "Initiate DiceMix Protocol", "")
+	}()
+
+	// remaining clients may already be enough for another run
+	checkPool(h, pool)
 }
@@ -0,0 +1,97 @@
+package server
+
+import (
+	"bytes"
+	"time"
+
+	"github.com/dev-appmonsters/dicemix-light-server/clock"
+	"github.com/dev-appmonsters/dicemix-light-server/evidence"
+	"github.com/dev-appmonsters/dicemix-light-server/messages"
+	"github.com/dev-appmonsters/dicemix-light-server/utils"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/golang/protobuf/proto"
+	"golang.org/x/crypto/curve25519"
+)
+
+// session of run used by tests
+const testSession = 1
+
+// number of peers in run used by tests
+const testPeers = 3
+
+// returns deterministic long term key of i-th peer
+func testLTSK(i int) *btcec.PrivateKey {
+	ltsk, _ := btcec.PrivKeyFromBytes(btcec.S256(), bytes.Repeat([]byte{byte(i + 1)}, 32))
+	return ltsk
+}
+
+// returns deterministic key exchange keypair of i-th peer
+func testKeypair(i int) ([]byte, []byte) {
+	var privateKey, publicKey [32]byte
+	copy(privateKey[:], bytes.Repeat([]byte{byte(i + 100)}, 32))
+	curve25519.ScalarBaseMult(&publicKey, &privateKey)
+	return privateKey[:], publicKey[:]
+}
+
+// creates hub with a single run of testPeers peers
+// waiting for nextState, first peer is connected over returned client
+func newTestHub(nextState int) (*hub, *client) {
+	config := DefaultConfig()
+	config.Network = "regtest"
+	config.Clock = clock.NewFakeClock(time.Unix(1500000000, 0))
+	h := newHub(config, evidence.NewMemoryStore())
+
+	r := newRun()
+	r.sessionID = testSession
+	r.run = 0
+	r.nextState = nextState
+	r.feeTerms = feeTerms(h.pools[h.poolOrder[0]], config.CoordinatorFee, utils.StaticFeeRate)
+
+	for i := 0; i < testPeers; i++ {
+		privateKey, publicKey := testKeypair(i)
+		r.peers = append(r.peers, &messages.PeersInfo{
+			Id:             int32(i + 1),
+			LTPublicKey:    testLTSK(i).PubKey().SerializeCompressed(),
+			PublicKey:      publicKey,
+			PrivateKey:     privateKey,
+			NumMsgs:        1,
+			DCVector:       make([]uint64, testPeers),
+			DCSimpleVector: [][]byte{make([]byte, 20), make([]byte, 20), make([]byte, 20)},
+			Confirmation:   true,
+			OK:             true,
+		})
+	}
+
+	// one other peer has already responded in current round
+	// so that request of first peer does not complete it
+	r.peers[1].MessageReceived = true
+	h.runs[testSession] = r
+
+	c := &client{hub: h, send: make(chan []byte, 256)}
+	h.clients[c] = r.peers[0].Id
+	return h, c
+}
+
+// signs request data with long term key of first peer
+func signTestRequest(data []byte) []byte {
+	signature, _ := testLTSK(0).Sign(chainhash.DoubleHashB(data))
+	message, _ := proto.Marshal(&messages.SignedRequest{
+		RequestData: data,
+		Signature:   signature.Serialize(),
+	})
+	return message
+}
+
+func testHeader(code uint32) *messages.RequestHeader {
+	return &messages.RequestHeader{Code: code, SessionId: testSession, Id: 1}
+}
+
+// returns request signed with long term key of first peer
+func signedTestRequest(request proto.Message) func() []byte {
+	return func() []byte {
+		data, _ := proto.Marshal(request)
+		return signTestRequest(data)
+	}
+}
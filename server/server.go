@@ -5,4 +5,10 @@ import "net/http"
 // Server - The main interface to enable connection.
 type Server interface {
 	Register(http.ResponseWriter, *http.Request)
+	PoolsInfo(http.ResponseWriter, *http.Request)
+	Info(http.ResponseWriter, *http.Request)
+	AdminHandler() http.Handler
+
+	// Close - stops background workers of server
+	Close()
 }
@@ -0,0 +1,95 @@
+package server
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io/ioutil"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Certificates - TLS credentials of server which can be
+// reloaded from disk without restarting server
+type Certificates interface {
+	TLSConfig() *tls.Config
+	Reload() error
+}
+
+type certificates struct {
+	config TLSConfig
+	Certificates
+
+	// guards certificate and clientCAs which are replaced on Reload
+	sync.RWMutex
+	certificate *tls.Certificate
+	clientCAs   *x509.CertPool
+}
+
+// NewCertificates loads certificate, key and client CAs
+// specified in config
+func NewCertificates(config TLSConfig) (Certificates, error) {
+	c := &certificates{config: config}
+	if err := c.Reload(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// TLSConfig returns configuration for http.Server
+// every handshake uses most recently loaded credentials
+func (c *certificates) TLSConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		GetConfigForClient: c.configForClient,
+	}
+}
+
+// Reload reads credentials from disk again
+// previous credentials are kept if they can not be loaded
+func (c *certificates) Reload() error {
+	certificate, err := tls.LoadX509KeyPair(c.config.CertFile, c.config.KeyFile)
+	if err != nil {
+		return err
+	}
+
+	var clientCAs *x509.CertPool
+	if c.config.ClientCAFile != "" {
+		data, err := ioutil.ReadFile(c.config.ClientCAFile)
+		if err != nil {
+			return err
+		}
+
+		clientCAs = x509.NewCertPool()
+		if !clientCAs.AppendCertsFromPEM(data) {
+			return errors.New("no certificates found in " + c.config.ClientCAFile)
+		}
+	}
+
+	c.Lock()
+	c.certificate = &certificate
+	c.clientCAs = clientCAs
+	c.Unlock()
+
+	log.Info("TLS - Loaded certificate ", c.config.CertFile)
+	return nil
+}
+
+// peers are required to present certificate signed by
+// client CAs if they are configured
+func (c *certificates) configForClient(*tls.ClientHelloInfo) (*tls.Config, error) {
+	c.RLock()
+	defer c.RUnlock()
+
+	config := &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{*c.certificate},
+	}
+
+	if c.clientCAs != nil {
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+		config.ClientCAs = c.clientCAs
+	}
+	return config, nil
+}
@@ -0,0 +1,223 @@
+package server
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writes self signed certificate with common name and its key to dir
+// returns parsed certificate
+func writeCertificate(t *testing.T, dir, name string) *x509.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: name},
+		DNSNames:              []string{"localhost"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer})
+	if err := ioutil.WriteFile(filepath.Join(dir, name+".crt"), certPEM, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, name+".key"), keyPEM, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	certificate, _ := x509.ParseCertificate(der)
+	return certificate
+}
+
+// starts TLS server using certificates
+func newTLSServer(certificates Certificates) *httptest.Server {
+	s := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	s.TLS = certificates.TLSConfig()
+	s.StartTLS()
+	return s
+}
+
+// returns serial number of certificate presented by server
+// every call performs a new handshake
+func serverSerial(url string, clientCertificates []tls.Certificate) (*big.Int, error) {
+	client := &http.Client{Transport: &http.Transport{
+		DisableKeepAlives: true,
+		TLSClientConfig: &tls.Config{
+			InsecureSkipVerify: true,
+			Certificates:       clientCertificates,
+		},
+	}}
+
+	response, err := client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	return response.TLS.PeerCertificates[0].SerialNumber, nil
+}
+
+func TestCertificatesReload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tls")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	initial := writeCertificate(t, dir, "server")
+	certificates, err := NewCertificates(TLSConfig{
+		CertFile: filepath.Join(dir, "server.crt"),
+		KeyFile:  filepath.Join(dir, "server.key"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := newTLSServer(certificates)
+	defer s.Close()
+
+	if serial, err := serverSerial(s.URL, nil); err != nil || serial.Cmp(initial.SerialNumber) != 0 {
+		t.Error("For", "initial", "expected", initial.SerialNumber, "got", serial, err)
+	}
+
+	// renewed certificate is used only after reload
+	renewed := writeCertificate(t, dir, "server")
+	if serial, err := serverSerial(s.URL, nil); err != nil || serial.Cmp(initial.SerialNumber) != 0 {
+		t.Error("For", "before reload", "expected", initial.SerialNumber, "got", serial, err)
+	}
+
+	if err := certificates.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if serial, err := serverSerial(s.URL, nil); err != nil || serial.Cmp(renewed.SerialNumber) != 0 {
+		t.Error("For", "after reload", "expected", renewed.SerialNumber, "got", serial, err)
+	}
+
+	// previous credentials are kept if reload fails
+	os.Remove(filepath.Join(dir, "server.key"))
+	if err := certificates.Reload(); err == nil {
+		t.Error("For", "missing key", "expected", "error", "got", err)
+	}
+	if serial, err := serverSerial(s.URL, nil); err != nil || serial.Cmp(renewed.SerialNumber) != 0 {
+		t.Error("For", "failed reload", "expected", renewed.SerialNumber, "got", serial, err)
+	}
+}
+
+func TestClientCertificates(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tls")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	writeCertificate(t, dir, "server")
+	writeCertificate(t, dir, "client")
+	writeCertificate(t, dir, "stranger")
+
+	certificates, err := NewCertificates(TLSConfig{
+		CertFile:     filepath.Join(dir, "server.crt"),
+		KeyFile:      filepath.Join(dir, "server.key"),
+		ClientCAFile: filepath.Join(dir, "client.crt"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := newTLSServer(certificates)
+	defer s.Close()
+
+	for _, name := range []string{"", "client", "stranger"} {
+		var clientCertificates []tls.Certificate
+		if name != "" {
+			certificate, err := tls.LoadX509KeyPair(filepath.Join(dir, name+".crt"), filepath.Join(dir, name+".key"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			clientCertificates = append(clientCertificates, certificate)
+		}
+
+		_, err := serverSerial(s.URL, clientCertificates)
+		if (err == nil) != (name == "client") {
+			t.Error("For", name, "expected accepted", name == "client", "got", err)
+		}
+	}
+}
+
+type originTestPair struct {
+	tls     bool
+	allowed []string
+	origin  string
+	host    string
+	valid   bool
+}
+
+var originTests = []originTestPair{
+	{false, nil, "", "example.com", true},
+	{false, nil, "http://example.com", "example.com", true},
+	{false, nil, "http://evil.com", "example.com", false},
+	{true, nil, "https://example.com", "example.com", true},
+	{true, nil, "http://example.com", "example.com", false},
+	{true, nil, "https://evil.com", "example.com", false},
+	{true, nil, "", "example.com", true},
+	{false, []string{"https://wallet.com"}, "https://wallet.com", "example.com", true},
+	{false, []string{"https://wallet.com"}, "HTTPS://Wallet.com", "example.com", true},
+	{false, []string{"https://wallet.com"}, "http://wallet.com", "example.com", false},
+	{false, []string{"https://wallet.com"}, "https://example.com", "example.com", false},
+	{false, []string{"*"}, "http://evil.com", "example.com", true},
+	{true, []string{"*"}, "http://evil.com", "example.com", false},
+}
+
+func TestCheckOrigin(t *testing.T) {
+	for _, pair := range originTests {
+		config := DefaultConfig()
+		config.Websocket.AllowedOrigins = pair.allowed
+		if pair.tls {
+			config.TLS = TLSConfig{CertFile: "server.crt", KeyFile: "server.key", HSTSMaxAge: 3600}
+		}
+
+		r := httptest.NewRequest(http.MethodGet, "/ws", nil)
+		r.Host = pair.host
+		if pair.origin != "" {
+			r.Header.Set("Origin", pair.origin)
+		}
+
+		if valid := checkOrigin(config)(r); valid != pair.valid {
+			t.Error("For", pair.origin, pair.tls, "expected", pair.valid, "got", valid)
+		}
+
+		hsts := upgradeHeader(config).Get("Strict-Transport-Security")
+		if (hsts == "max-age=3600") != pair.tls {
+			t.Error("For", pair.origin, pair.tls, "expected HSTS", pair.tls, "got", hsts)
+		}
+	}
+}
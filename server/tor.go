@@ -0,0 +1,65 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// prefix of TorConfig.Listen for unix sockets
+const unixPrefix = "unix:"
+
+// headers through which proxies reveal address of peer
+var forwardedHeaders = []string{"X-Forwarded-For", "X-Real-Ip", "Forwarded"}
+
+// key of context value marking requests received via Tor
+type torKey struct{}
+
+// ListenTor creates listener for connections forwarded
+// by local tor daemon from onion service
+func ListenTor(config TorConfig) (net.Listener, error) {
+	if strings.HasPrefix(config.Listen, unixPrefix) {
+		path := strings.TrimPrefix(config.Listen, unixPrefix)
+
+		// socket of previous execution
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		return net.Listen("unix", path)
+	}
+
+	if !loopback(config.Listen) {
+		return nil, errors.New("tor listener should be a unix socket or loopback address")
+	}
+	return net.Listen("tcp", config.Listen)
+}
+
+// TorHandler serves requests received via Tor
+// peers are anonymous i.e. their remote address is neither
+// logged nor used for bans and rate limits
+func TorHandler(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r = r.WithContext(context.WithValue(r.Context(), torKey{}, true))
+		for _, header := range forwardedHeaders {
+			r.Header.Del(header)
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
+
+// reports if address is a loopback host:port
+func loopback(address string) bool {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
@@ -0,0 +1,146 @@
+package server
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+type torListenTestPair struct {
+	listen string
+	valid  bool
+}
+
+var torListenTests = []torListenTestPair{
+	{"127.0.0.1:0", true},
+	{"localhost:0", true},
+	{"[::1]:0", true},
+	{"0.0.0.0:0", false},
+	{":0", false},
+	{"192.168.1.1:8084", false},
+}
+
+func TestListenTor(t *testing.T) {
+	for _, pair := range torListenTests {
+		config := DefaultConfig()
+		config.Tor = TorConfig{Listen: pair.listen}
+		if err := config.Validate(); (err == nil) != pair.valid {
+			t.Error("For", pair.listen, "expected valid", pair.valid, "got", err)
+		}
+
+		// loopback addresses may not be available in every environment
+		if !pair.valid {
+			if listener, err := ListenTor(config.Tor); err == nil {
+				listener.Close()
+				t.Error("For", pair.listen, "expected", "error", "got", nil)
+			}
+		}
+	}
+
+	dir, err := ioutil.TempDir("", "tor")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// stale socket of previous execution is replaced
+	path := filepath.Join(dir, "dicemix.sock")
+	ioutil.WriteFile(path, nil, 0600)
+	listener, err := ListenTor(TorConfig{Listen: unixPrefix + path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	if listener.Addr().Network() != "unix" {
+		t.Error("For", path, "expected", "unix", "got", listener.Addr().Network())
+	}
+}
+
+func TestTorHandler(t *testing.T) {
+	var ip string
+	var forwarded string
+	handler := TorHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ip = remoteIP(r)
+		forwarded = r.Header.Get("X-Forwarded-For")
+	}))
+
+	r := httptest.NewRequest(http.MethodGet, "/ws", nil)
+	r.RemoteAddr = "127.0.0.1:40000"
+	r.Header.Set("X-Forwarded-For", "1.2.3.4")
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+
+	if ip != "" || forwarded != "" {
+		t.Error("For", "tor", "expected", "anonymous", "got", ip, forwarded)
+	}
+
+	// forwarding headers are not trusted on clearnet either
+	if ip := remoteIP(r); ip != "127.0.0.1" {
+		t.Error("For", "clearnet", "expected", "127.0.0.1", "got", ip)
+	}
+}
+
+func TestTorConnections(t *testing.T) {
+	config := DefaultConfig()
+	config.Tor = TorConfig{Listen: "127.0.0.1:0", OnionAddress: "dicemixexampleaddress.onion"}
+	config.RateLimits.ConnectionBurst = 1
+
+	server := NewConnection(config)
+	defer server.Close()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ws", server.Register)
+	mux.HandleFunc("/info", server.Info)
+
+	// plain localhost listener stands in for local tor daemon
+	s := httptest.NewServer(TorHandler(mux))
+	defer s.Close()
+
+	// anonymous peers share loopback address but are not rate limited by it
+	for i := 0; i < 3; i++ {
+		conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(s.URL, "http")+"/ws", nil)
+		if err != nil {
+			t.Fatal("For", "connection", i, "expected", nil, "got", err)
+		}
+		defer conn.Close()
+	}
+
+	h := server.(*connection).hub
+	deadline := time.Now().Add(time.Second)
+	for {
+		h.Lock()
+		pending := len(h.pending)
+		for c := range h.pending {
+			if c.ip != "" {
+				t.Error("For", "pending client", "expected", "no address", "got", c.ip)
+			}
+		}
+		h.Unlock()
+
+		if pending == 3 || time.Now().After(deadline) {
+			if pending != 3 {
+				t.Error("For", "pending clients", "expected", 3, "got", pending)
+			}
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	response, err := http.Get(s.URL + "/info")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer response.Body.Close()
+
+	info := ServerInfo{}
+	if err := json.NewDecoder(response.Body).Decode(&info); err != nil || info.OnionAddress != config.Tor.OnionAddress {
+		t.Error("For", "/info", "expected", config.Tor.OnionAddress, "got", info, err)
+	}
+}
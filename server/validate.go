@@ -0,0 +1,134 @@
+package server
+
+import (
+	"crypto/subtle"
+
+	"github.com/dev-appmonsters/dicemix-light-server/messages"
+	"github.com/dev-appmonsters/dicemix-light-server/tx"
+	"github.com/dev-appmonsters/dicemix-light-server/utils"
+
+	"github.com/golang/protobuf/proto"
+)
+
+// error due to which request of peer has been rejected
+// code - one of error codes in messages/codes.go
+type requestError struct {
+	code    uint32
+	message string
+}
+
+func newRequestError(code uint32, message string) error {
+	return &requestError{code: code, message: message}
+}
+
+func (e *requestError) Error() string {
+	return e.message
+}
+
+// validates lengths of keys and vectors sent by peer
+// before they are stored in run
+func validateRequest(request proto.Message, run *run) error {
+	msgCount := int(totalMessageCount(run.peers))
+
+	switch r := request.(type) {
+	case *messages.KeyExchangeRequest:
+		if r.NumMsgs < 1 || r.NumMsgs > utils.MaxMsgs {
+			return newRequestError(messages.E_INVALID_MSG_COUNT, "invalid number of messages")
+		}
+		if len(r.PublicKey) != utils.KeySize {
+			return newRequestError(messages.E_INVALID_KEY, "invalid key exchange public key")
+		}
+
+		// inputs should pay for outputs, coordinator fee,
+		// share of miner fee and change
+		peer := &messages.PeersInfo{
+			Id:           r.GetHeader().GetId(),
+			NumMsgs:      r.NumMsgs,
+			Inputs:       r.Inputs,
+			ChangeScript: r.ChangeScript,
+			ChangeAmount: r.ChangeAmount,
+		}
+		if err := tx.ValidateFunds(peer, run.feeTerms, len(run.peers)); err != nil {
+			return newRequestError(messages.E_INSUFFICIENT_FUNDS, err.Error())
+		}
+
+		// an output can be spent only once in transaction
+		// which would otherwise fail to be assembled without a culprit
+		if err := tx.ValidateOutpoints(peer, run.peers); err != nil {
+			return newRequestError(messages.E_INSUFFICIENT_FUNDS, err.Error())
+		}
+
+	case *messages.DCExpRequest:
+		if len(r.DCExpVector) != msgCount {
+			return newRequestError(messages.E_INVALID_VECTOR, "invalid DC-EXP vector length")
+		}
+
+	case *messages.DCSimpleRequest:
+		if len(r.DCSimpleVector) != msgCount {
+			return newRequestError(messages.E_INVALID_VECTOR, "invalid DC-SIMPLE vector length")
+		}
+		for _, slot := range r.DCSimpleVector {
+			if len(slot) != utils.MessageSize {
+				return newRequestError(messages.E_INVALID_VECTOR, "invalid DC-SIMPLE slot size")
+			}
+		}
+		if len(r.NextPublicKey) != utils.KeySize {
+			return newRequestError(messages.E_INVALID_KEY, "invalid next key exchange public key")
+		}
+	}
+
+	return nil
+}
+
+// sets error code and description of err in header
+// errors other than requestError are reported as internal errors
+func setError(header *messages.ResponseHeader, err error) {
+	if err == nil {
+		return
+	}
+
+	header.Err = err.Error()
+	header.ErrorCode = messages.E_INTERNAL
+	if requestErr, ok := err.(*requestError); ok {
+		header.ErrorCode = requestErr.code
+	}
+}
+
+// informs peer that his request has been rejected
+// peer would be considered offline in current round
+func sendErrorResponse(h *hub, c *client, sessionID uint64, err error) {
+	emitRejected(h, c, 0, sessionID, err)
+
+	// peer may have already been removed
+	if _, ok := h.clients[c]; !ok && !h.pending[c] {
+		return
+	}
+
+	header := responseHeader(messages.S_REQUEST_REJECTED, sessionID, "Request rejected", "")
+	setError(header, err)
+	response, err := proto.Marshal(&messages.GenericResponse{
+		Header: header,
+	})
+
+	if checkError(err) {
+		return
+	}
+
+	sendMessage(h, c, response)
+}
+
+// checks if token is one of accepted tokens
+// every token is accepted if none are configured
+func validAuthToken(tokens []string, token string) bool {
+	if len(tokens) == 0 {
+		return true
+	}
+
+	valid := false
+	for _, accepted := range tokens {
+		if subtle.ConstantTimeCompare([]byte(accepted), []byte(token)) == 1 {
+			valid = true
+		}
+	}
+	return valid
+}
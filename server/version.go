@@ -0,0 +1,45 @@
+package server
+
+import (
+	"github.com/dev-appmonsters/dicemix-light-server/messages"
+)
+
+// selects most preferred protocol version supported by both
+// server and peer whose features satisfy those expected by peer
+// peers which do not announce versions speak legacy VERSION_1
+func negotiateVersion(request *messages.JoinRequest) (uint32, error) {
+	versions := request.Versions
+	if len(versions) == 0 {
+		versions = []uint32{messages.VERSION_1}
+	}
+
+	for _, supported := range messages.SupportedVersions {
+		features, _ := messages.VersionFeatures(supported)
+		if !features.Compatible(request.Features) {
+			continue
+		}
+
+		for _, version := range versions {
+			if version == supported {
+				return version, nil
+			}
+		}
+	}
+
+	return 0, newRequestError(messages.E_UNSUPPORTED_VERSION, "no supported protocol version")
+}
+
+// reports if peer speaks version
+// peers which do not announce versions speak legacy VERSION_1
+func announcesVersion(request *messages.JoinRequest, version uint32) bool {
+	if len(request.Versions) == 0 {
+		return version == messages.VERSION_1
+	}
+
+	for _, announced := range request.Versions {
+		if announced == version {
+			return true
+		}
+	}
+	return false
+}
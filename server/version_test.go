@@ -0,0 +1,85 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/dev-appmonsters/dicemix-light-server/messages"
+)
+
+type versionTestPair struct {
+	name     string
+	versions []uint32
+	features *messages.Features
+	version  uint32
+	err      bool
+}
+
+var versionTests = []versionTestPair{
+	{"legacy", nil, nil, messages.VERSION_1, false},
+	{"current", []uint32{messages.VERSION_1}, nil, messages.VERSION_1, false},
+	{"newer", []uint32{7, messages.VERSION_1}, nil, messages.VERSION_1, false},
+	{"unknown", []uint32{7}, nil, 0, true},
+	{"features", []uint32{messages.VERSION_1}, &messages.Features{
+		MessageLength: messages.MESSAGE_LENGTH_20,
+		HashFunction:  messages.HASH_FNV64_BASE58,
+		TxMode:        messages.TX_MODE_PSBT,
+	}, messages.VERSION_1, false},
+	{"partial features", nil, &messages.Features{TxMode: messages.TX_MODE_PSBT}, messages.VERSION_1, false},
+	{"message length", []uint32{messages.VERSION_1}, &messages.Features{MessageLength: 32}, 0, true},
+	{"hash function", []uint32{messages.VERSION_1}, &messages.Features{HashFunction: "sha256"}, 0, true},
+}
+
+func TestNegotiateVersion(t *testing.T) {
+	for _, pair := range versionTests {
+		version, err := negotiateVersion(&messages.JoinRequest{
+			Versions: pair.versions,
+			Features: pair.features,
+		})
+
+		if version != pair.version || (err != nil) != pair.err {
+			t.Error("For", pair.name, "expected", pair.version, pair.err, "got", version, err)
+		}
+
+		if err != nil && err.(*requestError).code != messages.E_UNSUPPORTED_VERSION {
+			t.Error("For", pair.name, "expected", messages.E_UNSUPPORTED_VERSION, "got", err.(*requestError).code)
+		}
+	}
+}
+
+func TestVersionGrouping(t *testing.T) {
+	supported := messages.SupportedVersions
+	messages.SupportedVersions = []uint32{2, messages.VERSION_1}
+	defer func() { messages.SupportedVersions = supported }()
+
+	h, _ := newTestHub(messages.C_KEY_EXCHANGE)
+	pool := h.pools[h.poolOrder[0]]
+	for id := int32(10); id < 15; id++ {
+		addWaitingPeer(h, id, true)
+		if id >= 12 {
+			pool.waitingQueue[len(pool.waitingQueue)-1].version = 2
+		}
+	}
+
+	h.Lock()
+	defer h.Unlock()
+
+	// run is started with peers speaking version of most ready peers
+	if version, counter := pool.readyVersion(); version != 2 || counter != 3 {
+		t.Error("For", "ready version", "expected", 2, 3, "got", version, counter)
+	}
+
+	h.startDicemix(pool)
+	for _, r := range h.runs {
+		if r.sessionID == testSession {
+			continue
+		}
+		if r.version != 2 || len(r.peers) != 3 || r.peers[0].Id != 12 {
+			t.Error("For", "run", "expected", 2, 3, "got", r.version, len(r.peers))
+		}
+	}
+
+	// peers speaking other version keep waiting
+	if len(pool.waitingQueue) != 2 || pool.readyCount(messages.VERSION_1) != 2 {
+		t.Error("For", "queue", "expected", 2, "got", len(pool.waitingQueue))
+	}
+}
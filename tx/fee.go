@@ -0,0 +1,142 @@
+package tx
+
+import (
+	"errors"
+
+	"github.com/dev-appmonsters/dicemix-light-server/messages"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+)
+
+// CoordinatorFee - returns fee charged by coordinator
+// from a participant mixing numMsgs outputs
+// fee := flat + (denomination * numMsgs * basisPoints / 10000)
+// saturates at maximum uint64 instead of overflowing
+func CoordinatorFee(terms *messages.FeeTerms, numMsgs uint32) uint64 {
+	amount, err := multiplyAmount(terms.Denomination, uint64(numMsgs))
+	if err != nil {
+		return ^uint64(0)
+	}
+
+	// amount * basisPoints / 10000 without overflowing intermediate product
+	basisPoints := uint64(terms.CoordinatorFeeBasisPoints)
+	fee := amount/10000*basisPoints + amount%10000*basisPoints/10000
+	if fee, err = sumAmounts(terms.CoordinatorFeeFlat, fee); err != nil {
+		return ^uint64(0)
+	}
+	return fee
+}
+
+// ValidateFunds - checks if inputs declared by peer are sufficient
+// to pay for his mixed outputs, coordinator fee, his share of miner fee and change
+func ValidateFunds(peer *messages.PeersInfo, terms *messages.FeeTerms, numPeers int) error {
+	if len(peer.Inputs) == 0 {
+		return errors.New("no inputs declared")
+	}
+
+	var total uint64
+	for _, input := range peer.Inputs {
+		if len(input.TxHash) != 32 {
+			return errors.New("invalid input transaction hash")
+		}
+		if len(input.PkScript) == 0 {
+			return errors.New("missing input script")
+		}
+
+		var err error
+		if total, err = sumAmounts(total, input.Amount); err != nil {
+			return errors.New("inputs exceed maximum amount")
+		}
+	}
+
+	if peer.ChangeAmount > 0 && len(peer.ChangeScript) == 0 {
+		return errors.New("missing change script")
+	}
+
+	mixed, err := multiplyAmount(terms.Denomination, uint64(peer.NumMsgs))
+	if err != nil {
+		return err
+	}
+	required, err := sumAmounts(mixed, CoordinatorFee(terms, peer.NumMsgs),
+		MinerFeeShare(peer, terms, numPeers), peer.ChangeAmount)
+	if err != nil {
+		return errors.New("outputs exceed maximum amount")
+	}
+	if total < required {
+		return errors.New("inputs do not cover outputs, coordinator fee, miner fee and change")
+	}
+	return nil
+}
+
+// ValidateOutpoints - checks that inputs of peer spend distinct outputs
+// none of which is declared by any other of peers
+func ValidateOutpoints(peer *messages.PeersInfo, peers []*messages.PeersInfo) error {
+	outpoints := make(map[wire.OutPoint]bool)
+	for _, other := range peers {
+		if other.Id == peer.Id {
+			continue
+		}
+		for _, input := range other.Inputs {
+			if outpoint, err := inputOutPoint(input); err == nil {
+				outpoints[*outpoint] = true
+			}
+		}
+	}
+
+	declared := make(map[wire.OutPoint]bool)
+	for _, input := range peer.Inputs {
+		outpoint, err := inputOutPoint(input)
+		if err != nil {
+			return err
+		}
+		if declared[*outpoint] {
+			return errors.New("duplicate input " + outpoint.String())
+		}
+		if outpoints[*outpoint] {
+			return errors.New("input declared by another peer " + outpoint.String())
+		}
+		declared[*outpoint] = true
+	}
+	return nil
+}
+
+// returns outpoint spent by input
+func inputOutPoint(input *messages.TxInput) (*wire.OutPoint, error) {
+	hash, err := chainhash.NewHash(input.TxHash)
+	if err != nil {
+		return nil, err
+	}
+	return wire.NewOutPoint(hash, input.Index), nil
+}
+
+// returns sum of amounts
+// or error if it exceeds maximum number of satoshis
+func sumAmounts(amounts ...uint64) (uint64, error) {
+	var total uint64
+	for _, amount := range amounts {
+		if amount > btcutil.MaxSatoshi-total {
+			return 0, errors.New("amount exceeds maximum number of satoshis")
+		}
+		total += amount
+	}
+	return total, nil
+}
+
+// returns amount * n
+// or error if it exceeds maximum number of satoshis
+func multiplyAmount(amount, n uint64) (uint64, error) {
+	if n > 0 && amount > btcutil.MaxSatoshi/n {
+		return 0, errors.New("amount exceeds maximum number of satoshis")
+	}
+	return amount * n, nil
+}
+
+// converts amount to value of transaction output
+func outputValue(amount uint64) (int64, error) {
+	if amount > btcutil.MaxSatoshi {
+		return 0, errors.New("amount exceeds maximum number of satoshis")
+	}
+	return int64(amount), nil
+}
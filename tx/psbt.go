@@ -0,0 +1,191 @@
+package tx
+
+import (
+	"bytes"
+	"errors"
+
+	"github.com/dev-appmonsters/dicemix-light-server/messages"
+	"github.com/dev-appmonsters/dicemix-light-server/utils"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	"github.com/btcsuite/btcutil/psbt"
+	"github.com/btcsuite/btcutil/txsort"
+)
+
+type psbtTX struct {
+	params *chaincfg.Params
+	TX
+}
+
+// NewPSBT creates a new TX instance which assembles
+// transaction as a Partially Signed Bitcoin Transaction
+func NewPSBT(params *chaincfg.Params) TX {
+	return &psbtTX{params: params}
+}
+
+// Assemble - creates transaction with inputs and change of every peer,
+// an output of denomination for every anonymous message and
+// an output paying coordinator fee
+// returns serialized PSBT
+func (t *psbtTX) Assemble(peers []*messages.PeersInfo, msgs [][]byte, terms *messages.FeeTerms) ([]byte, error) {
+	transaction, err := t.assemble(peers, msgs, terms)
+	if err != nil {
+		return nil, err
+	}
+
+	packet, err := psbt.NewFromUnsignedTx(transaction)
+	if err != nil {
+		return nil, err
+	}
+
+	// attach amount and script of every input
+	// required by peers to sign their inputs
+	var inputs, outputs uint64
+	for i, txIn := range transaction.TxIn {
+		input, ok := findInput(peers, &txIn.PreviousOutPoint)
+		if !ok {
+			return nil, errors.New("input not found")
+		}
+		value, err := outputValue(input.Amount)
+		if err != nil {
+			return nil, err
+		}
+		packet.Inputs[i].WitnessUtxo = wire.NewTxOut(value, input.PkScript)
+
+		if inputs, err = sumAmounts(inputs, input.Amount); err != nil {
+			return nil, err
+		}
+	}
+
+	// left over of inputs is paid to miners
+	// and should meet fee rate of run
+	for _, txOut := range transaction.TxOut {
+		if outputs, err = sumAmounts(outputs, uint64(txOut.Value)); err != nil {
+			return nil, err
+		}
+	}
+	if inputs < outputs || inputs-outputs < MinerFee(packet, terms.FeeRate) {
+		return nil, errors.New("miner fee below fee rate of run")
+	}
+
+	var buf bytes.Buffer
+	if err := packet.Serialize(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// creates unsigned transaction with BIP69 ordering
+// of inputs and outputs
+func (t *psbtTX) assemble(peers []*messages.PeersInfo, msgs [][]byte, terms *messages.FeeTerms) (*wire.MsgTx, error) {
+	transaction := wire.NewMsgTx(wire.TxVersion)
+	outpoints := make(map[wire.OutPoint]bool)
+	var coordinatorFee uint64
+
+	for _, peer := range peers {
+		for _, input := range peer.Inputs {
+			outpoint, err := inputOutPoint(input)
+			if err != nil {
+				return nil, err
+			}
+
+			// same output can not be spent twice
+			if outpoints[*outpoint] {
+				return nil, errors.New("duplicate input " + outpoint.String())
+			}
+			outpoints[*outpoint] = true
+
+			transaction.AddTxIn(wire.NewTxIn(outpoint, nil, nil))
+		}
+
+		if peer.ChangeAmount > 0 {
+			value, err := outputValue(peer.ChangeAmount)
+			if err != nil {
+				return nil, err
+			}
+			transaction.AddTxOut(wire.NewTxOut(value, peer.ChangeScript))
+		}
+
+		var err error
+		coordinatorFee, err = sumAmounts(coordinatorFee, CoordinatorFee(terms, peer.NumMsgs))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	denomination, err := outputValue(terms.Denomination)
+	if err != nil {
+		return nil, err
+	}
+
+	// every anonymous message is hash of public key of a fresh output
+	for _, msg := range msgs {
+		script, err := OutputScript(terms.ScriptType, msg)
+		if err != nil {
+			return nil, err
+		}
+		transaction.AddTxOut(wire.NewTxOut(denomination, script))
+	}
+
+	if coordinatorFee > 0 {
+		address, err := btcutil.DecodeAddress(terms.CoordinatorFeeAddress, t.params)
+		if err != nil {
+			return nil, err
+		}
+
+		script, err := txscript.PayToAddrScript(address)
+		if err != nil {
+			return nil, err
+		}
+		transaction.AddTxOut(wire.NewTxOut(int64(coordinatorFee), script))
+	}
+
+	return txsort.Sort(transaction), nil
+}
+
+// OutputScript - generates output script of specified type
+// paying to public key hash
+func OutputScript(scriptType string, pubKeyHash []byte) ([]byte, error) {
+	if len(pubKeyHash) != 20 {
+		return nil, errors.New("invalid public key hash")
+	}
+
+	switch scriptType {
+	case utils.P2WPKH:
+		return txscript.NewScriptBuilder().AddOp(txscript.OP_0).AddData(pubKeyHash).Script()
+	case utils.P2PKH:
+		return txscript.NewScriptBuilder().AddOp(txscript.OP_DUP).AddOp(txscript.OP_HASH160).
+			AddData(pubKeyHash).AddOp(txscript.OP_EQUALVERIFY).AddOp(txscript.OP_CHECKSIG).Script()
+	}
+	return nil, errors.New("unsupported script type " + scriptType)
+}
+
+// NetParams - returns parameters of bitcoin network with specified name
+func NetParams(network string) (*chaincfg.Params, error) {
+	switch network {
+	case "", chaincfg.MainNetParams.Name:
+		return &chaincfg.MainNetParams, nil
+	case chaincfg.TestNet3Params.Name:
+		return &chaincfg.TestNet3Params, nil
+	case chaincfg.RegressionNetParams.Name:
+		return &chaincfg.RegressionNetParams, nil
+	case chaincfg.SimNetParams.Name:
+		return &chaincfg.SimNetParams, nil
+	}
+	return nil, errors.New("unknown network " + network)
+}
+
+// returns input of peer which spends outpoint
+func findInput(peers []*messages.PeersInfo, outpoint *wire.OutPoint) (*messages.TxInput, bool) {
+	for _, peer := range peers {
+		for _, input := range peer.Inputs {
+			if input.Index == outpoint.Index && bytes.Equal(input.TxHash, outpoint.Hash[:]) {
+				return input, true
+			}
+		}
+	}
+	return nil, false
+}
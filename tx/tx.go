@@ -0,0 +1,10 @@
+package tx
+
+import (
+	"github.com/dev-appmonsters/dicemix-light-server/messages"
+)
+
+// TX - The main interface for assembling transaction of a DiceMix run.
+type TX interface {
+	Assemble([]*messages.PeersInfo, [][]byte, *messages.FeeTerms) ([]byte, error)
+}
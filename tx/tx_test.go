@@ -0,0 +1,240 @@
+package tx
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/dev-appmonsters/dicemix-light-server/messages"
+	"github.com/dev-appmonsters/dicemix-light-server/utils"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcutil"
+	"github.com/btcsuite/btcutil/psbt"
+)
+
+type coordinatorFeeTestPair struct {
+	terms   *messages.FeeTerms
+	numMsgs uint32
+	res     uint64
+}
+
+type fundsTestPair struct {
+	peer *messages.PeersInfo
+	res  bool
+}
+
+type feeShareTestPair struct {
+	peer     *messages.PeersInfo
+	feeRate  uint64
+	numPeers int
+	res      uint64
+}
+
+type outpointsTestPair struct {
+	peer  *messages.PeersInfo
+	peers []*messages.PeersInfo
+	res   bool
+}
+
+type scriptTestPair struct {
+	scriptType string
+	res        string
+}
+
+var terms = &messages.FeeTerms{
+	Denomination:              1000000,
+	ScriptType:                utils.P2WPKH,
+	CoordinatorFeeFlat:        1000,
+	CoordinatorFeeBasisPoints: 10,
+	CoordinatorFeeAddress:     "bcrt1qw508d6qejxtdg4y5r3zarvary0c5xw7kygt080",
+}
+
+var coordinatorFeeTests = []coordinatorFeeTestPair{
+	{&messages.FeeTerms{Denomination: 1000000}, 1, 0},
+	{&messages.FeeTerms{Denomination: 1000000, CoordinatorFeeFlat: 500}, 2, 500},
+	{&messages.FeeTerms{Denomination: 1000000, CoordinatorFeeBasisPoints: 25}, 1, 2500},
+	{terms, 2, 3000},
+	{&messages.FeeTerms{Denomination: 1 << 62, CoordinatorFeeBasisPoints: 25}, 1, ^uint64(0)},
+}
+
+var fundsTests = []fundsTestPair{
+	{peer(1, 1002000, 0), true},
+	{peer(1, 1020000, 18000), true},
+	{peer(1, 1001999, 0), false},
+	{peer(2, 2002999, 0), false},
+	{&messages.PeersInfo{NumMsgs: 1}, false},
+	{peer(1, ^uint64(0), 0), false},
+	{peer(1, 1002000, ^uint64(0)-1000000), false},
+	{&messages.PeersInfo{NumMsgs: 1, Inputs: append(peer(1, btcutil.MaxSatoshi, 0).Inputs, peer(2, btcutil.MaxSatoshi, 0).Inputs...)}, false},
+}
+
+var outpointsTests = []outpointsTestPair{
+	{withID(peer(1, 1002000, 0), 1), []*messages.PeersInfo{withID(peer(1, 1002000, 0), 1), withID(peer(2, 2002000, 0), 2)}, true},
+	{withID(peer(1, 1002000, 0), 1), []*messages.PeersInfo{withID(peer(1, 1002000, 0), 2)}, false},
+	{&messages.PeersInfo{Id: 1, Inputs: append(peer(1, 1002000, 0).Inputs, peer(1, 1002000, 0).Inputs...)}, nil, false},
+	{&messages.PeersInfo{Id: 1, Inputs: []*messages.TxInput{{TxHash: []byte{1}}}}, nil, false},
+}
+
+var p2wpkhScript = append([]byte{0, 20}, make([]byte, 20)...)
+
+var feeShareTests = []feeShareTestPair{
+	{peer(1, 1002000, 0), 0, 2, 0},
+	{peer(1, 1002000, 0), 2, 2, 412},
+	{&messages.PeersInfo{NumMsgs: 1, Inputs: []*messages.TxInput{{PkScript: p2wpkhScript}}}, 2, 2, 252},
+	{&messages.PeersInfo{NumMsgs: 1, Inputs: []*messages.TxInput{{PkScript: p2wpkhScript}}}, 2, 1, 305},
+	{&messages.PeersInfo{NumMsgs: 2, Inputs: []*messages.TxInput{{PkScript: p2wpkhScript}}, ChangeAmount: 1, ChangeScript: p2wpkhScript}, 1, 3, 179},
+}
+
+var scriptTests = []scriptTestPair{
+	{utils.P2WPKH, "00140102030405060708090a0b0c0d0e0f1011121314"},
+	{utils.P2PKH, "76a9140102030405060708090a0b0c0d0e0f101112131488ac"},
+	{"P2TR", ""},
+}
+
+func peer(numMsgs uint32, amount, change uint64) *messages.PeersInfo {
+	var info = &messages.PeersInfo{NumMsgs: numMsgs, ChangeAmount: change}
+	info.Inputs = []*messages.TxInput{{
+		TxHash:   bytes.Repeat([]byte{byte(amount)}, 32),
+		Index:    uint32(numMsgs),
+		Amount:   amount,
+		PkScript: []byte{0, 20},
+	}}
+	if change > 0 {
+		info.ChangeScript = []byte{0, 20}
+	}
+	return info
+}
+
+func withID(peer *messages.PeersInfo, id int32) *messages.PeersInfo {
+	peer.Id = id
+	return peer
+}
+
+func TestCoordinatorFee(t *testing.T) {
+	for _, pair := range coordinatorFeeTests {
+		if fee := CoordinatorFee(pair.terms, pair.numMsgs); fee != pair.res {
+			t.Error(
+				"For", pair.terms, pair.numMsgs,
+				"expected", pair.res,
+				"got", fee,
+			)
+		}
+	}
+}
+
+func TestValidateFunds(t *testing.T) {
+	for _, pair := range fundsTests {
+		if err := ValidateFunds(pair.peer, terms, 2); (err == nil) != pair.res {
+			t.Error(
+				"For", pair.peer,
+				"expected", pair.res,
+				"got", err,
+			)
+		}
+	}
+}
+
+func TestValidateOutpoints(t *testing.T) {
+	for _, pair := range outpointsTests {
+		if err := ValidateOutpoints(pair.peer, pair.peers); (err == nil) != pair.res {
+			t.Error(
+				"For", pair.peer, pair.peers,
+				"expected", pair.res,
+				"got", err,
+			)
+		}
+	}
+}
+
+func TestMinerFeeShare(t *testing.T) {
+	for _, pair := range feeShareTests {
+		feeTerms := *terms
+		feeTerms.FeeRate = pair.feeRate
+		if share := MinerFeeShare(pair.peer, &feeTerms, pair.numPeers); share != pair.res {
+			t.Error(
+				"For", pair.peer, pair.feeRate, pair.numPeers,
+				"expected", pair.res,
+				"got", share,
+			)
+		}
+	}
+
+	// inputs should also cover miner fee share
+	feeTerms := *terms
+	feeTerms.FeeRate = 2
+	if err := ValidateFunds(peer(1, 1002000, 0), &feeTerms, 2); err == nil {
+		t.Error("expected error for inputs not covering miner fee")
+	}
+	if err := ValidateFunds(peer(1, 1002412, 0), &feeTerms, 2); err != nil {
+		t.Error("expected inputs to cover miner fee got", err)
+	}
+}
+
+func TestOutputScript(t *testing.T) {
+	hash, _ := hex.DecodeString("0102030405060708090a0b0c0d0e0f1011121314")
+	for _, pair := range scriptTests {
+		script, err := OutputScript(pair.scriptType, hash)
+		if hex.EncodeToString(script) != pair.res || (err == nil) != (pair.res != "") {
+			t.Error(
+				"For", pair.scriptType,
+				"expected", pair.res,
+				"got", hex.EncodeToString(script), err,
+			)
+		}
+	}
+}
+
+func TestAssemble(t *testing.T) {
+	peers := []*messages.PeersInfo{peer(1, 1002000, 0), peer(2, 2050000, 26000)}
+	msgs := [][]byte{bytes.Repeat([]byte{1}, 20), bytes.Repeat([]byte{2}, 20), bytes.Repeat([]byte{3}, 20)}
+
+	transaction, err := NewPSBT(&chaincfg.RegressionNetParams).Assemble(peers, msgs, terms)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	packet, err := psbt.NewFromRawBytes(bytes.NewReader(transaction), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 3 mixed outputs, 1 change output and 1 coordinator fee output
+	if len(packet.UnsignedTx.TxIn) != 2 || len(packet.UnsignedTx.TxOut) != 5 {
+		t.Error("expected 2 inputs and 5 outputs got", len(packet.UnsignedTx.TxIn), len(packet.UnsignedTx.TxOut))
+	}
+
+	var total int64
+	for _, txOut := range packet.UnsignedTx.TxOut {
+		total += txOut.Value
+	}
+	if total != 3000000+26000+2000+3000 {
+		t.Error("unexpected total output value", total)
+	}
+
+	// vsize of 442 vB
+	if fee := MinerFee(packet, 1); fee != 442 {
+		t.Error("expected miner fee 442 got", fee)
+	}
+
+	// 21000 sat left over by peers covers up to 47 sat/vB
+	feeTerms := *terms
+	feeTerms.FeeRate = 47
+	if _, err := NewPSBT(&chaincfg.RegressionNetParams).Assemble(peers, msgs, &feeTerms); err != nil {
+		t.Error("expected miner fee to meet fee rate got", err)
+	}
+	feeTerms.FeeRate = 48
+	if _, err := NewPSBT(&chaincfg.RegressionNetParams).Assemble(peers, msgs, &feeTerms); err == nil {
+		t.Error("expected error for miner fee below fee rate")
+	}
+
+	// duplicate inputs are rejected
+	if _, err := NewPSBT(&chaincfg.RegressionNetParams).Assemble([]*messages.PeersInfo{peers[0], peers[0]}, msgs[:2], terms); err == nil {
+		t.Error("expected error for duplicate inputs")
+	}
+
+	// amounts which can not be represented in transaction are rejected
+	if _, err := NewPSBT(&chaincfg.RegressionNetParams).Assemble([]*messages.PeersInfo{peer(1, 1<<63, 0)}, msgs[:1], terms); err == nil {
+		t.Error("expected error for amount exceeding maximum number of satoshis")
+	}
+}
@@ -0,0 +1,98 @@
+package tx
+
+import (
+	"github.com/dev-appmonsters/dicemix-light-server/messages"
+
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil/psbt"
+)
+
+// sizes are in weight units
+// vsize := ceil(weight / 4)
+const (
+	// version, locktime, input count, output count and segwit marker, flag
+	txOverheadWeight = (4+4+1+1)*4 + 2
+
+	// outpoint, empty script sig, sequence
+	// and witness with 72 byte signature and 33 byte public key
+	p2wpkhInputWeight = (32+4+1+4)*4 + (1 + 1 + 72 + 1 + 33)
+
+	// outpoint, script sig with 72 byte signature and
+	// 33 byte public key, sequence and empty witness
+	p2pkhInputWeight = (32+4+1+107+4)*4 + 1
+
+	// upper bound of coordinator fee output (34 byte script)
+	coordinatorOutputWeight = (8 + 1 + 34) * 4
+)
+
+// MinerFee - returns miner fee required at feeRate sat/vB
+// by signed transaction assembled as PSBT
+// fee := ceil(feeRate * weight / 4)
+func MinerFee(packet *psbt.Packet, feeRate uint64) uint64 {
+	transaction := packet.UnsignedTx
+
+	// version, locktime and segwit marker, flag
+	weight := int64((4+4)*4 + 2)
+	weight += int64(wire.VarIntSerializeSize(uint64(len(transaction.TxIn)))) * 4
+	weight += int64(wire.VarIntSerializeSize(uint64(len(transaction.TxOut)))) * 4
+
+	for i := range transaction.TxIn {
+		var pkScript []byte
+		if packet.Inputs[i].WitnessUtxo != nil {
+			pkScript = packet.Inputs[i].WitnessUtxo.PkScript
+		}
+		weight += inputWeight(pkScript)
+	}
+
+	for _, txOut := range transaction.TxOut {
+		weight += outputWeight(len(txOut.PkScript))
+	}
+
+	return (uint64(weight)*feeRate + 3) / 4
+}
+
+// MinerFeeShare - returns miner fee to be paid by peer
+// share := ceil(feeRate * (overhead / numPeers + inputs + outputs) / 4)
+// where overhead includes coordinator fee output
+// computed only from values declared by peer in KE
+// so that every peer can deterministically compute his own share
+func MinerFeeShare(peer *messages.PeersInfo, terms *messages.FeeTerms, numPeers int) uint64 {
+	if numPeers < 1 {
+		numPeers = 1
+	}
+
+	overhead := int64(txOverheadWeight)
+	if terms.CoordinatorFeeFlat > 0 || terms.CoordinatorFeeBasisPoints > 0 {
+		overhead += coordinatorOutputWeight
+	}
+	weight := (overhead + int64(numPeers) - 1) / int64(numPeers)
+
+	for _, input := range peer.Inputs {
+		weight += inputWeight(input.PkScript)
+	}
+
+	if script, err := OutputScript(terms.ScriptType, make([]byte, 20)); err == nil {
+		weight += outputWeight(len(script)) * int64(peer.NumMsgs)
+	}
+
+	if peer.ChangeAmount > 0 {
+		weight += outputWeight(len(peer.ChangeScript))
+	}
+
+	return (uint64(weight)*terms.FeeRate + 3) / 4
+}
+
+// returns weight of input spending output with pkScript
+// inputs other than P2WPKH are considered as P2PKH
+func inputWeight(pkScript []byte) int64 {
+	if txscript.IsPayToWitnessPubKeyHash(pkScript) {
+		return p2wpkhInputWeight
+	}
+	return p2pkhInputWeight
+}
+
+// returns weight of output with script of scriptLen bytes
+func outputWeight(scriptLen int) int64 {
+	return int64(8+wire.VarIntSerializeSize(uint64(scriptLen))+scriptLen) * 4
+}
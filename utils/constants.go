@@ -20,14 +20,103 @@ const (
 	// MinPeers - number of peers required to start DiceMix protocol
 	MinPeers = 3
 
+	// MaxPeers - maximum number of peers in a single DiceMix run
+	MaxPeers = 50
+
+	// MaxMsgs - maximum number of messages of a peer in a single run
+	MaxMsgs = 10
+
+	// MessageSize - size of an anonymous message i.e. a DC-SIMPLE slot
+	MessageSize = 20
+
+	// KeySize - size of curve25519 key exchange keys
+	KeySize = 32
+
+	// BaseReadLimit - bytes allowed in a websocket message
+	// of a peer before number of messages of his run is known
+	BaseReadLimit = 16 * 1024
+
+	// SlotReadLimit - bytes allowed per message of run in addition
+	// to BaseReadLimit i.e. DC-SIMPLE slot along with its protobuf framing
+	SlotReadLimit = MessageSize + 12
+
+	// JoinWait - Time allowed to send C_JOIN_REQUEST after connecting.
+	JoinWait = 30 * time.Second
+
+	// PoolFillWait - Time to wait for more peers after MinPeers are ready.
+	PoolFillWait = 10 * time.Second
+
 	// ResponseWait - Time to wait for response from peers.
 	ResponseWait = 5
+
+	// ResumeWait - Time allowed to peers of runs restored after restart
+	// to reconnect before runs are aborted.
+	ResumeWait = 60 * time.Second
+
+	// SnapshotBuffer - Snapshots of runs queued to be written to disk
+	// before runs wait for run store.
+	SnapshotBuffer = 256
+
+	// EventBuffer - Events buffered for a subscriber of admin API
+	// before further events are dropped for him.
+	EventBuffer = 256
+
+	// ConfTarget - number of blocks within which transaction should confirm
+	ConfTarget = 6
+
+	// StaticFeeRate - fee rate (sat/vB) used when fee estimation is not available
+	StaticFeeRate = 10
+
+	// FeeRateRefresh - Time after which fee rate is estimated again.
+	FeeRateRefresh = time.Minute
+
+	// BanDuration - Time for which a peer is banned on first offence.
+	// doubled on every subsequent offence
+	BanDuration = 10 * time.Minute
+
+	// MaxBanDuration - Maximum time for which a peer is banned.
+	MaxBanDuration = 24 * time.Hour
+
+	// TimeoutsPerOffence - number of timeouts considered as an offence
+	TimeoutsPerOffence = 3
+
+	// ConnectionsPerMinute - connections allowed per remote address
+	// after ConnectionBurst connections
+	ConnectionsPerMinute = 30
+	ConnectionBurst      = 10
+
+	// KeysPerMinute - long term public keys allowed per remote address
+	// and per key after KeyBurst submissions
+	KeysPerMinute = 10
+	KeyBurst      = 5
+
+	// QueueStatusPerMinute - C_QUEUE_STATUS requests allowed per peer
+	// after QueueStatusBurst requests
+	QueueStatusPerMinute = 30
+	QueueStatusBurst     = 5
+
+	// MaxClients - maximum number of concurrently connected peers
+	MaxClients = 10000
+
+	// TicketWindow - Time for which proof of work tickets are valid
+	// before and after their timestamp.
+	TicketWindow = 10 * time.Minute
+
+	// TokenEpoch - Epoch stamped in blind tokens, tokens are valid
+	// in their epoch and in next one.
+	TokenEpoch = 24 * time.Hour
 )
 
-var (
-	// Newline - represents new line char
-	Newline = []byte{'\n'}
+// supported output script types
+const (
+	// P2PKH - pay to public key hash
+	P2PKH = "P2PKH"
 
+	// P2WPKH - pay to witness public key hash
+	P2WPKH = "P2WPKH"
+)
+
+var (
 	// Space - represents space char
 	Space = []byte{' '}
 )